@@ -0,0 +1,177 @@
+// Command migrate-files copies locally stored uploads into the S3 bucket
+// configured through the S3_* variables and points files.path at the
+// uploaded objects. It is safe to re-run: objects already in the bucket
+// with the expected size are not uploaded again.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/Anything-That-Works/GoPath/internal/database"
+	"github.com/Anything-That-Works/GoPath/internal/storage"
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+)
+
+const batchSize = 100
+
+func requireEnv(key string) string {
+	val := os.Getenv(key)
+	if val == "" {
+		log.Fatalf("%s value not found.", key)
+	}
+	return val
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report what would be migrated without changing anything")
+	deleteLocal := flag.Bool("delete-local", false, "remove local copies once they are in the bucket")
+	flag.Parse()
+
+	if err := godotenv.Load(".env"); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	dbURL := requireEnv("DB_URL")
+	uploadsPath := requireEnv("UPLOADS_PATH")
+
+	s3Config, err := storage.S3ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	bucket, err := storage.NewS3Storage(s3Config)
+	if err != nil {
+		log.Fatal("Cannot configure S3 storage: ", err)
+	}
+
+	con, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		log.Fatal("Cannot connect to database: ", err)
+	}
+	defer func() {
+		if err := con.Close(); err != nil {
+			log.Printf("Error closing database connection: %v", err)
+		}
+	}()
+	db := database.New(con)
+
+	ctx := context.Background()
+	var uploaded, skipped, failed int
+	after := uuid.Nil
+	for {
+		files, err := db.ListFilesAfter(ctx, database.ListFilesAfterParams{ID: after, Limit: batchSize})
+		if err != nil {
+			log.Fatal("Failed to list files: ", err)
+		}
+		if len(files) == 0 {
+			break
+		}
+		after = files[len(files)-1].ID
+
+		for _, file := range files {
+			done, err := migrateFile(ctx, db, bucket, uploadsPath, file, *dryRun, *deleteLocal)
+			switch {
+			case err != nil:
+				failed++
+				log.Printf("failed to migrate file %s (%s): %v", file.ID, file.Path, err)
+			case done:
+				uploaded++
+			default:
+				skipped++
+			}
+		}
+	}
+
+	log.Printf("migrated %d files, %d already in bucket, %d failed (dry run: %t)", uploaded, skipped, failed, *dryRun)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// migrateFile uploads one file, with its original and thumbnails, and
+// reports whether anything was copied.
+func migrateFile(ctx context.Context, db *database.Queries, bucket *storage.S3Storage, uploadsPath string, file database.File, dryRun bool, deleteLocal bool) (bool, error) {
+	// older rows may carry a directory prefix; objects are keyed by base name
+	key := filepath.Base(file.Path)
+	uploaded, err := copyObject(ctx, bucket, uploadsPath, key, file.MimeType, dryRun, deleteLocal)
+	if err != nil {
+		return false, err
+	}
+	if !dryRun && file.Path != key {
+		if err := db.UpdateFilePath(ctx, database.UpdateFilePathParams{NewPath: key, ID: file.ID}); err != nil {
+			return uploaded, err
+		}
+	}
+
+	if file.OriginalPath.Valid {
+		copied, err := copyObject(ctx, bucket, uploadsPath, file.OriginalPath.String, file.MimeType, dryRun, deleteLocal)
+		if err != nil {
+			return uploaded, err
+		}
+		uploaded = uploaded || copied
+	}
+
+	thumbnails, err := db.GetFileThumbnails(ctx, file.ID)
+	if err != nil {
+		return uploaded, err
+	}
+	for _, thumbnail := range thumbnails {
+		copied, err := copyObject(ctx, bucket, uploadsPath, thumbnail.Path, thumbnail.MimeType, dryRun, deleteLocal)
+		if err != nil {
+			return uploaded, err
+		}
+		uploaded = uploaded || copied
+	}
+	return uploaded, nil
+}
+
+// copyObject uploads the local file stored under key unless the bucket
+// already holds it, and reports whether it was copied.
+func copyObject(ctx context.Context, bucket *storage.S3Storage, uploadsPath string, key string, mimeType string, dryRun bool, deleteLocal bool) (bool, error) {
+	localPath := filepath.Join(uploadsPath, key)
+	f, err := os.Open(localPath)
+	if errors.Is(err, os.ErrNotExist) {
+		// already moved by an earlier run with -delete-local
+		_, statErr := bucket.Stat(ctx, key)
+		return false, statErr
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	size, err := bucket.Stat(ctx, key)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	copied := false
+	if err != nil || size != info.Size() {
+		if dryRun {
+			log.Printf("would upload %s -> %s", localPath, key)
+			return true, nil
+		}
+		if err := bucket.Put(ctx, key, f, info.Size(), mimeType); err != nil {
+			return false, err
+		}
+		copied = true
+	}
+
+	if deleteLocal && !dryRun {
+		if err := os.Remove(localPath); err != nil {
+			return copied, err
+		}
+	}
+	return copied, nil
+}
@@ -0,0 +1,141 @@
+// Command set-quota overrides the default storage quota of one user or
+// conversation, or removes the override again. Without a change flag it
+// prints the current quota and usage.
+//
+//	set-quota -user alice@example.com -bytes 5368709120
+//	set-quota -conversation <id> -unlimited
+//	set-quota -user <id> -reset
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/Anything-That-Works/GoPath/internal/database"
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+)
+
+func requireEnv(key string) string {
+	val := os.Getenv(key)
+	if val == "" {
+		log.Fatalf("%s value not found.", key)
+	}
+	return val
+}
+
+func main() {
+	user := flag.String("user", "", "email or ID of the user to change")
+	conversation := flag.String("conversation", "", "ID of the conversation to change")
+	bytes := flag.Int64("bytes", -1, "quota in bytes")
+	unlimited := flag.Bool("unlimited", false, "lift the quota entirely")
+	reset := flag.Bool("reset", false, "remove the override and fall back to the configured default")
+	flag.Parse()
+
+	if (*user == "") == (*conversation == "") {
+		log.Fatal("exactly one of -user and -conversation is required")
+	}
+	changes := 0
+	for _, set := range []bool{*bytes >= 0, *unlimited, *reset} {
+		if set {
+			changes++
+		}
+	}
+	if changes > 1 {
+		log.Fatal("-bytes, -unlimited and -reset are mutually exclusive")
+	}
+
+	if err := godotenv.Load(".env"); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	con, err := sql.Open("postgres", requireEnv("DB_URL"))
+	if err != nil {
+		log.Fatal("Cannot connect to database: ", err)
+	}
+	defer func() {
+		if err := con.Close(); err != nil {
+			log.Printf("Error closing database connection: %v", err)
+		}
+	}()
+	db := database.New(con)
+	ctx := context.Background()
+
+	// a NULL quota is an unlimited override
+	quota := sql.NullInt64{Int64: *bytes, Valid: !*unlimited}
+
+	if *user != "" {
+		userID, err := resolveUser(ctx, db, *user)
+		if err != nil {
+			log.Fatal("Failed to find user: ", err)
+		}
+		switch {
+		case *reset:
+			err = db.DeleteUserStorageQuota(ctx, userID)
+		case changes == 1:
+			_, err = db.SetUserStorageQuota(ctx, database.SetUserStorageQuotaParams{UserID: userID, QuotaBytes: quota})
+		}
+		if err != nil {
+			log.Fatal("Failed to update quota: ", err)
+		}
+
+		used, err := db.GetUserStorageUsage(ctx, userID)
+		if err != nil {
+			log.Fatal("Failed to fetch usage: ", err)
+		}
+		override, err := db.GetUserStorageQuota(ctx, userID)
+		printQuota("user "+userID.String(), used, override.QuotaBytes, err)
+		return
+	}
+
+	conversationID, err := uuid.Parse(*conversation)
+	if err != nil {
+		log.Fatalf("invalid conversation ID %q", *conversation)
+	}
+	switch {
+	case *reset:
+		err = db.DeleteConversationStorageQuota(ctx, conversationID)
+	case changes == 1:
+		_, err = db.SetConversationStorageQuota(ctx, database.SetConversationStorageQuotaParams{ConversationID: conversationID, QuotaBytes: quota})
+	}
+	if err != nil {
+		log.Fatal("Failed to update quota: ", err)
+	}
+
+	used, err := db.GetConversationStorageUsage(ctx, uuid.NullUUID{UUID: conversationID, Valid: true})
+	if err != nil {
+		log.Fatal("Failed to fetch usage: ", err)
+	}
+	override, err := db.GetConversationStorageQuota(ctx, conversationID)
+	printQuota("conversation "+conversationID.String(), used, override.QuotaBytes, err)
+}
+
+func resolveUser(ctx context.Context, db *database.Queries, value string) (uuid.UUID, error) {
+	if id, err := uuid.Parse(value); err == nil {
+		user, err := db.GetUserByID(ctx, id)
+		return user.ID, err
+	}
+	user, err := db.GetUserByEmail(ctx, value)
+	return user.ID, err
+}
+
+// printQuota reports usage against the override; err is the override
+// lookup's, where sql.ErrNoRows means the configured default applies.
+func printQuota(subject string, used int64, quota sql.NullInt64, err error) {
+	limit := "unlimited"
+	switch {
+	case err == sql.ErrNoRows:
+		limit = "default"
+	case err != nil:
+		log.Fatal("Failed to fetch quota: ", err)
+	case quota.Valid:
+		limit = strconv.FormatInt(quota.Int64, 10) + " bytes"
+	}
+	fmt.Printf("%s: %d bytes used, quota %s\n", subject, used, limit)
+}
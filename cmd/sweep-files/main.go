@@ -0,0 +1,86 @@
+// Command sweep-files runs one pass of the file sweeper the server runs in
+// the background, removing files no message carries and stored content no
+// row points at, then prints a summary. Use -dry-run to see what would go.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Anything-That-Works/GoPath/internal/database"
+	"github.com/Anything-That-Works/GoPath/internal/storage"
+	"github.com/Anything-That-Works/GoPath/internal/sweeper"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+)
+
+func requireEnv(key string) string {
+	val := os.Getenv(key)
+	if val == "" {
+		log.Fatalf("%s value not found.", key)
+	}
+	return val
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report what would be removed without changing anything")
+	grace := flag.Duration("grace", 24*time.Hour, "leave anything younger than this alone")
+	flag.Parse()
+
+	if *grace <= 0 {
+		log.Fatal("-grace must be positive")
+	}
+
+	if err := godotenv.Load(".env"); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	// URLs are never handed out, so local storage needs no signer
+	var fileStorage storage.FileStorage
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "local":
+		fileStorage = storage.NewLocalStorage(requireEnv("UPLOADS_PATH"), "", nil)
+	case "s3":
+		s3Config, err := storage.S3ConfigFromEnv()
+		if err != nil {
+			log.Fatal(err)
+		}
+		s3Storage, err := storage.NewS3Storage(s3Config)
+		if err != nil {
+			log.Fatal("Cannot configure S3 storage: ", err)
+		}
+		fileStorage = s3Storage
+	default:
+		log.Fatalf("STORAGE_BACKEND must be \"local\" or \"s3\", got %q", backend)
+	}
+
+	con, err := sql.Open("postgres", requireEnv("DB_URL"))
+	if err != nil {
+		log.Fatal("Cannot connect to database: ", err)
+	}
+	defer func() {
+		if err := con.Close(); err != nil {
+			log.Printf("Error closing database connection: %v", err)
+		}
+	}()
+
+	fileSweeper := &sweeper.Sweeper{
+		DB:          database.New(con),
+		Storage:     fileStorage,
+		GracePeriod: *grace,
+		DryRun:      *dryRun,
+	}
+	report, err := fileSweeper.Sweep(context.Background())
+	fmt.Println(report)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
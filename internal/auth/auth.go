@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
@@ -13,13 +14,17 @@ import (
 )
 
 type AuthData struct {
-	UserID uuid.UUID `json:"user_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	SessionID uuid.UUID `json:"session_id"` // uuid.Nil in tokens from before sessions
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID uuid.UUID, secretKey []byte) (model.TokenResponse, string, error) {
+// GenerateToken issues an access token for userID on session sessionID and
+// a refresh token for the same session.
+func GenerateToken(userID uuid.UUID, sessionID uuid.UUID, secretKey []byte) (model.TokenResponse, string, error) {
 	at, err := jwt.NewWithClaims(jwt.SigningMethodHS256, AuthData{
-		UserID: userID,
+		UserID:    userID,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -46,14 +51,47 @@ func GenerateToken(userID uuid.UUID, secretKey []byte) (model.TokenResponse, str
 }
 
 func GenerateRefreshToken() (rawToken string, hash string, err error) {
+	rawToken, hash, err = generateOpaqueToken()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	return rawToken, hash, nil
+}
+
+// GeneratePasswordResetToken returns a token to email and the hash to store
+// in its place.
+func GeneratePasswordResetToken() (rawToken string, hash string, err error) {
+	rawToken, hash, err = generateOpaqueToken()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate password reset token: %w", err)
+	}
+	return rawToken, hash, nil
+}
+
+// GenerateLoginChallengeToken returns a token for the second step of a
+// two-factor login and the hash to store in its place.
+func GenerateLoginChallengeToken() (rawToken string, hash string, err error) {
+	rawToken, hash, err = generateOpaqueToken()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate login challenge token: %w", err)
+	}
+	return rawToken, hash, nil
+}
+
+// HashToken returns the stored form of an opaque token such as a refresh,
+// password reset or login challenge token.
+func HashToken(rawToken string) string {
+	sum := sha256.Sum256([]byte(rawToken))
+	return fmt.Sprintf("%x", sum)
+}
+
+func generateOpaqueToken() (rawToken string, hash string, err error) {
 	b := make([]byte, 32)
 	if _, err = rand.Read(b); err != nil {
-		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
+		return "", "", err
 	}
 	rawToken = base64.URLEncoding.EncodeToString(b)
-	sum := sha256.Sum256([]byte(rawToken))
-	hash = fmt.Sprintf("%x", sum)
-	return rawToken, hash, nil
+	return rawToken, HashToken(rawToken), nil
 }
 
 func ValidateJWT(tokenString string, secretKey []byte) (*AuthData, error) {
@@ -74,3 +112,50 @@ func ValidateJWT(tokenString string, secretKey []byte) (*AuthData, error) {
 
 	return claims, nil
 }
+
+type emailVerificationClaims struct {
+	UserID uuid.UUID `json:"user_id"`
+	Email  string    `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// GenerateEmailVerificationToken signs a token for the link that proves
+// userID receives mail at email. It is signed with a key derived for this
+// purpose, so it can never pass as an access token.
+func GenerateEmailVerificationToken(userID uuid.UUID, email string, secretKey []byte, ttl time.Duration) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, emailVerificationClaims{
+		UserID: userID,
+		Email:  email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}).SignedString(PurposeKey(secretKey, "email-verification"))
+}
+
+// ValidateEmailVerificationToken returns the user and email a token was
+// issued for.
+func ValidateEmailVerificationToken(tokenString string, secretKey []byte) (uuid.UUID, string, error) {
+	claims := &emailVerificationClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return PurposeKey(secretKey, "email-verification"), nil
+	})
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	if !token.Valid || claims.UserID == uuid.Nil || claims.Email == "" {
+		return uuid.Nil, "", fmt.Errorf("invalid token")
+	}
+	return claims.UserID, claims.Email, nil
+}
+
+// PurposeKey derives a key for one purpose from secretKey, so what it signs
+// can't pass for anything signed with the key itself or for another purpose.
+func PurposeKey(secretKey []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secretKey)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
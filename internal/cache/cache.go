@@ -10,4 +10,8 @@ type Cache interface {
 	Set(ctx context.Context, key string, value string, ttl time.Duration) error
 	Delete(ctx context.Context, key string) error
 	DeleteByPattern(ctx context.Context, pattern string) error
+	// GetField and SetField cache several values under one key, so that
+	// they can all be invalidated with a single Delete
+	GetField(ctx context.Context, key string, field string) (string, error)
+	SetField(ctx context.Context, key string, field string, value string, ttl time.Duration) error
 }
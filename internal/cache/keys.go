@@ -15,8 +15,20 @@ func KeyUserProfile(userID string) string {
 	return fmt.Sprintf("user:profile:%s", userID)
 }
 
-func KeyConversationsList(userID string, page, limit int32) string {
-	return fmt.Sprintf("conversations:list:%s:%d:%d", userID, page, limit)
+// KeyConversationsList holds every cached page of a user's inbox, one field
+// per page, since previews and unread counts change with each message.
+func KeyConversationsList(userID string) string {
+	return fmt.Sprintf("conversations:list:%s", userID)
+}
+
+func FieldConversationsListPage(page, limit int32) string {
+	return fmt.Sprintf("%d:%d", page, limit)
+}
+
+// FieldConversationsListCursor identifies a keyset page; direction is
+// "before" or "after".
+func FieldConversationsListCursor(direction string, cursor string, limit int32) string {
+	return fmt.Sprintf("%s:%s:%d", direction, cursor, limit)
 }
 
 func KeyConversationMembers(conversationID string) string {
@@ -50,6 +50,26 @@ func (r *RedisCache) DeleteByPattern(ctx context.Context, pattern string) error
 	return r.client.Del(ctx, keys...).Err()
 }
 
+func (r *RedisCache) GetField(ctx context.Context, key string, field string) (string, error) {
+	return r.client.HGet(ctx, key, field).Result()
+}
+
+// SetField stores value under field and (re)sets the TTL of the whole key.
+func (r *RedisCache) SetField(ctx context.Context, key string, field string, value string, ttl time.Duration) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, field, value)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	return err
+}
+
+// Client exposes the underlying connection so other components, such as the
+// WebSocket hub's pub/sub, can share it.
+func (r *RedisCache) Client() *redis.Client {
+	return r.client
+}
+
 func (r *RedisCache) Close() error {
 	return r.client.Close()
 }
@@ -0,0 +1,166 @@
+// Package clamav scans content with a clamd daemon over its INSTREAM
+// protocol, on a TCP or Unix socket.
+package clamav
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// chunkSize stays well below clamd's default StreamMaxLength of 25MB.
+const chunkSize = 64 << 10
+
+// ErrSizeLimit means the content exceeded clamd's StreamMaxLength, so it
+// could not be scanned at all.
+var ErrSizeLimit = errors.New("clamd: stream size limit exceeded")
+
+type Result struct {
+	Infected  bool
+	Signature string // what was found, e.g. "Win.Test.EICAR_HDB-1"
+}
+
+type Scanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewScanner takes tcp://host:port, unix:///path/to/clamd.sock, or a bare
+// host:port. timeout bounds a whole scan, connection included.
+func NewScanner(address string, timeout time.Duration) (*Scanner, error) {
+	scanner := &Scanner{network: "tcp", address: address, timeout: timeout}
+	switch {
+	case strings.HasPrefix(address, "tcp://"):
+		scanner.address = strings.TrimPrefix(address, "tcp://")
+	case strings.HasPrefix(address, "unix://"):
+		scanner.network, scanner.address = "unix", strings.TrimPrefix(address, "unix://")
+	case strings.Contains(address, "://"):
+		return nil, fmt.Errorf("unsupported clamd address %q", address)
+	}
+	if scanner.address == "" {
+		return nil, fmt.Errorf("invalid clamd address %q", address)
+	}
+	return scanner, nil
+}
+
+// Ping checks that clamd is up.
+func (s *Scanner) Ping(ctx context.Context) error {
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("zPING\x00")); err != nil {
+		return err
+	}
+	reply, err := readReply(conn)
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("clamd: unexpected reply %q", reply)
+	}
+	return nil
+}
+
+// Scan streams r to clamd and returns its verdict.
+func (s *Scanner) Scan(ctx context.Context, r io.Reader) (Result, error) {
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	defer conn.Close()
+
+	if err := writeStream(conn, r); err != nil {
+		// clamd answers and hangs up as soon as the stream is too long
+		if reply, replyErr := readReply(conn); replyErr == nil {
+			return parseReply(reply)
+		}
+		return Result{}, err
+	}
+
+	reply, err := readReply(conn)
+	if err != nil {
+		return Result{}, err
+	}
+	return parseReply(reply)
+}
+
+func (s *Scanner) dial(ctx context.Context) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: s.timeout}
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(s.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// writeStream sends r as length-prefixed chunks, ended by an empty one.
+func writeStream(w io.Writer, r io.Reader) error {
+	if _, err := w.Write([]byte("zINSTREAM\x00")); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 4+chunkSize)
+	for {
+		n, err := r.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := w.Write(buf[:4+n]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := w.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+// readReply reads one null-terminated reply.
+func readReply(r io.Reader) (string, error) {
+	reply, err := bufio.NewReader(r).ReadString(0)
+	if err != nil && !(err == io.EOF && reply != "") {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimSuffix(reply, "\x00")), nil
+}
+
+// parseReply reads replies such as "stream: OK",
+// "stream: Win.Test.EICAR_HDB-1 FOUND" and
+// "INSTREAM size limit exceeded. ERROR".
+func parseReply(reply string) (Result, error) {
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return Result{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return Result{Infected: true, Signature: strings.TrimSuffix(reply, " FOUND")}, nil
+	case strings.Contains(reply, "size limit exceeded"):
+		return Result{}, ErrSizeLimit
+	default:
+		return Result{}, fmt.Errorf("clamd: %s", reply)
+	}
+}
@@ -0,0 +1,138 @@
+package clamav
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClamd accepts one connection, reads an INSTREAM scan and answers
+// with reply. It sends back the content it received.
+func fakeClamd(t *testing.T, reply string) (string, <-chan []byte) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		command, err := r.ReadString(0)
+		if err != nil || command != "zINSTREAM\x00" {
+			conn.Write([]byte("UNKNOWN COMMAND\x00"))
+			close(received)
+			return
+		}
+
+		var content bytes.Buffer
+		for {
+			var size uint32
+			if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+				close(received)
+				return
+			}
+			if size == 0 {
+				break
+			}
+			if _, err := io.CopyN(&content, r, int64(size)); err != nil {
+				close(received)
+				return
+			}
+		}
+		received <- content.Bytes()
+		conn.Write([]byte(reply + "\x00"))
+	}()
+
+	return listener.Addr().String(), received
+}
+
+func TestScan(t *testing.T) {
+	// spans several chunks
+	content := bytes.Repeat([]byte("GoPath"), chunkSize/3)
+
+	tests := []struct {
+		name    string
+		reply   string
+		want    Result
+		wantErr string
+	}{
+		{
+			name:  "clean",
+			reply: "stream: OK",
+			want:  Result{},
+		},
+		{
+			name:  "infected",
+			reply: "stream: Win.Test.EICAR_HDB-1 FOUND",
+			want:  Result{Infected: true, Signature: "Win.Test.EICAR_HDB-1"},
+		},
+		{
+			name:    "size limit",
+			reply:   "INSTREAM size limit exceeded. ERROR",
+			wantErr: ErrSizeLimit.Error(),
+		},
+		{
+			name:    "error",
+			reply:   "stream: Can't allocate memory ERROR",
+			wantErr: "clamd: Can't allocate memory ERROR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address, received := fakeClamd(t, tt.reply)
+			scanner, err := NewScanner("tcp://"+address, 5*time.Second)
+			if err != nil {
+				t.Fatalf("NewScanner: %v", err)
+			}
+
+			result, err := scanner.Scan(context.Background(), bytes.NewReader(content))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Scan error = %v, want %s", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Scan: %v", err)
+			}
+			if result != tt.want {
+				t.Errorf("Scan = %+v, want %+v", result, tt.want)
+			}
+
+			if got := <-received; !bytes.Equal(got, content) {
+				t.Errorf("clamd received %d bytes, want %d", len(got), len(content))
+			}
+		})
+	}
+}
+
+func TestScanUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	scanner, err := NewScanner(address, time.Second)
+	if err != nil {
+		t.Fatalf("NewScanner: %v", err)
+	}
+	if _, err := scanner.Scan(context.Background(), strings.NewReader("content")); err == nil {
+		t.Fatal("Scan succeeded without clamd")
+	}
+}
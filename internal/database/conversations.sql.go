@@ -33,7 +33,7 @@ func (q *Queries) AddConversationMember(ctx context.Context, arg AddConversation
 const createConversation = `-- name: CreateConversation :one
 INSERT INTO conversations (created_by, is_group, name)
 VALUES ($1, $2, $3)
-RETURNING id, is_group, name, created_by, created_at, updated_at, deleted_at
+RETURNING id, is_group, name, created_by, created_at, updated_at, deleted_at, last_seq
 `
 
 type CreateConversationParams struct {
@@ -53,6 +53,7 @@ func (q *Queries) CreateConversation(ctx context.Context, arg CreateConversation
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.LastSeq,
 	)
 	return i, err
 }
@@ -67,7 +68,7 @@ func (q *Queries) DeleteConversation(ctx context.Context, id uuid.UUID) error {
 }
 
 const getConversationByID = `-- name: GetConversationByID :one
-SELECT id, is_group, name, created_by, created_at, updated_at, deleted_at FROM conversations WHERE id = $1 AND deleted_at IS NULL
+SELECT id, is_group, name, created_by, created_at, updated_at, deleted_at, last_seq FROM conversations WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) GetConversationByID(ctx context.Context, id uuid.UUID) (Conversation, error) {
@@ -81,6 +82,7 @@ func (q *Queries) GetConversationByID(ctx context.Context, id uuid.UUID) (Conver
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.LastSeq,
 	)
 	return i, err
 }
@@ -151,7 +153,7 @@ func (q *Queries) GetConversationMembers(ctx context.Context, conversationID uui
 }
 
 const getDirectConversation = `-- name: GetDirectConversation :one
-SELECT c.id, c.is_group, c.name, c.created_by, c.created_at, c.updated_at, c.deleted_at FROM conversations c
+SELECT c.id, c.is_group, c.name, c.created_by, c.created_at, c.updated_at, c.deleted_at, c.last_seq FROM conversations c
 JOIN conversation_members cm1 ON cm1.conversation_id = c.id AND cm1.user_id = $1
 JOIN conversation_members cm2 ON cm2.conversation_id = c.id AND cm2.user_id = $2
 WHERE c.is_group = FALSE
@@ -175,6 +177,7 @@ func (q *Queries) GetDirectConversation(ctx context.Context, arg GetDirectConver
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.LastSeq,
 	)
 	return i, err
 }
@@ -206,12 +209,52 @@ func (q *Queries) GetFirstAdminOrMember(ctx context.Context, arg GetFirstAdminOr
 }
 
 const getUserConversations = `-- name: GetUserConversations :many
-SELECT c.id, c.is_group, c.name, c.created_by, c.created_at, c.updated_at, c.deleted_at FROM conversations c
-JOIN conversation_members cm ON cm.conversation_id = c.id
-WHERE cm.user_id = $1
-AND c.deleted_at IS NULL
-ORDER BY c.updated_at DESC
-LIMIT $2 OFFSET $3
+WITH page AS (
+    SELECT c.id FROM conversations c
+    JOIN conversation_members cm ON cm.conversation_id = c.id
+    WHERE cm.user_id = $1
+    AND c.deleted_at IS NULL
+    ORDER BY c.updated_at DESC, c.id DESC
+    LIMIT $2 OFFSET $3
+)
+SELECT
+    c.id, c.is_group, c.name, c.created_by, c.created_at, c.updated_at, c.deleted_at, c.last_seq,
+    lm.id AS last_message_id,
+    lm.sender_id AS last_message_sender_id,
+    LEFT(lm.content, 120)::text AS last_message_snippet,
+    f.mime_type AS last_message_file_type,
+    lm.created_at AS last_message_at,
+    COALESCE(uc.unread_count, 0)::bigint AS unread_count,
+    ou.id AS other_user_id,
+    ou.name AS other_user_name,
+    ou.email AS other_user_email,
+    ou.email_verified_at AS other_user_email_verified_at
+FROM page
+JOIN conversations c ON c.id = page.id
+JOIN conversation_members cm ON cm.conversation_id = c.id AND cm.user_id = $1
+LEFT JOIN LATERAL (
+    SELECT m.id, m.sender_id, m.content, m.file_id, m.created_at
+    FROM messages m
+    WHERE m.conversation_id = c.id AND m.deleted_at IS NULL AND m.thread_id IS NULL
+    ORDER BY m.seq DESC
+    LIMIT 1
+) lm ON TRUE
+LEFT JOIN files f ON f.id = lm.file_id
+LEFT JOIN LATERAL (
+    SELECT COUNT(*) AS unread_count
+    FROM messages m
+    WHERE m.conversation_id = c.id AND m.deleted_at IS NULL AND m.thread_id IS NULL
+    AND m.sender_id != cm.user_id
+    AND (cm.last_read_at IS NULL OR m.created_at > cm.last_read_at)
+) uc ON TRUE
+LEFT JOIN LATERAL (
+    SELECT u.id, u.name, u.email, u.email_verified_at
+    FROM conversation_members om
+    JOIN users u ON u.id = om.user_id
+    WHERE om.conversation_id = c.id AND om.user_id != cm.user_id
+    LIMIT 1
+) ou ON NOT c.is_group
+ORDER BY c.updated_at DESC, c.id DESC
 `
 
 type GetUserConversationsParams struct {
@@ -220,23 +263,288 @@ type GetUserConversationsParams struct {
 	Offset int32     `db:"offset" json:"offset"`
 }
 
-func (q *Queries) GetUserConversations(ctx context.Context, arg GetUserConversationsParams) ([]Conversation, error) {
+type GetUserConversationsRow struct {
+	Conversation             Conversation   `db:"conversation" json:"conversation"`
+	LastMessageID            uuid.NullUUID  `db:"last_message_id" json:"last_message_id"`
+	LastMessageSenderID      uuid.NullUUID  `db:"last_message_sender_id" json:"last_message_sender_id"`
+	LastMessageSnippet       sql.NullString `db:"last_message_snippet" json:"last_message_snippet"`
+	LastMessageFileType      sql.NullString `db:"last_message_file_type" json:"last_message_file_type"`
+	LastMessageAt            sql.NullTime   `db:"last_message_at" json:"last_message_at"`
+	UnreadCount              int64          `db:"unread_count" json:"unread_count"`
+	OtherUserID              uuid.NullUUID  `db:"other_user_id" json:"other_user_id"`
+	OtherUserName            sql.NullString `db:"other_user_name" json:"other_user_name"`
+	OtherUserEmail           sql.NullString `db:"other_user_email" json:"other_user_email"`
+	OtherUserEmailVerifiedAt sql.NullTime   `db:"other_user_email_verified_at" json:"other_user_email_verified_at"`
+}
+
+// one row per conversation with everything the inbox renders: the latest
+// timeline message, the caller's unread count and, for direct chats, the
+// other participant. The page is picked first, so the summaries are only
+// worked out for the conversations on it.
+func (q *Queries) GetUserConversations(ctx context.Context, arg GetUserConversationsParams) ([]GetUserConversationsRow, error) {
 	rows, err := q.query(ctx, q.getUserConversationsStmt, getUserConversations, arg.UserID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Conversation
+	var items []GetUserConversationsRow
+	for rows.Next() {
+		var i GetUserConversationsRow
+		if err := rows.Scan(
+			&i.Conversation.ID,
+			&i.Conversation.IsGroup,
+			&i.Conversation.Name,
+			&i.Conversation.CreatedBy,
+			&i.Conversation.CreatedAt,
+			&i.Conversation.UpdatedAt,
+			&i.Conversation.DeletedAt,
+			&i.Conversation.LastSeq,
+			&i.LastMessageID,
+			&i.LastMessageSenderID,
+			&i.LastMessageSnippet,
+			&i.LastMessageFileType,
+			&i.LastMessageAt,
+			&i.UnreadCount,
+			&i.OtherUserID,
+			&i.OtherUserName,
+			&i.OtherUserEmail,
+			&i.OtherUserEmailVerifiedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserConversationsAfter = `-- name: GetUserConversationsAfter :many
+WITH page AS (
+    SELECT c.id FROM conversations c
+    JOIN conversation_members cm ON cm.conversation_id = c.id
+    WHERE cm.user_id = $1
+    AND c.deleted_at IS NULL
+    AND (c.updated_at, c.id) > ($3::timestamptz, $4::uuid)
+    ORDER BY c.updated_at ASC, c.id ASC
+    LIMIT $2
+)
+SELECT
+    c.id, c.is_group, c.name, c.created_by, c.created_at, c.updated_at, c.deleted_at, c.last_seq,
+    lm.id AS last_message_id,
+    lm.sender_id AS last_message_sender_id,
+    LEFT(lm.content, 120)::text AS last_message_snippet,
+    f.mime_type AS last_message_file_type,
+    lm.created_at AS last_message_at,
+    COALESCE(uc.unread_count, 0)::bigint AS unread_count,
+    ou.id AS other_user_id,
+    ou.name AS other_user_name,
+    ou.email AS other_user_email,
+    ou.email_verified_at AS other_user_email_verified_at
+FROM page
+JOIN conversations c ON c.id = page.id
+JOIN conversation_members cm ON cm.conversation_id = c.id AND cm.user_id = $1
+LEFT JOIN LATERAL (
+    SELECT m.id, m.sender_id, m.content, m.file_id, m.created_at
+    FROM messages m
+    WHERE m.conversation_id = c.id AND m.deleted_at IS NULL AND m.thread_id IS NULL
+    ORDER BY m.seq DESC
+    LIMIT 1
+) lm ON TRUE
+LEFT JOIN files f ON f.id = lm.file_id
+LEFT JOIN LATERAL (
+    SELECT COUNT(*) AS unread_count
+    FROM messages m
+    WHERE m.conversation_id = c.id AND m.deleted_at IS NULL AND m.thread_id IS NULL
+    AND m.sender_id != cm.user_id
+    AND (cm.last_read_at IS NULL OR m.created_at > cm.last_read_at)
+) uc ON TRUE
+LEFT JOIN LATERAL (
+    SELECT u.id, u.name, u.email, u.email_verified_at
+    FROM conversation_members om
+    JOIN users u ON u.id = om.user_id
+    WHERE om.conversation_id = c.id AND om.user_id != cm.user_id
+    LIMIT 1
+) ou ON NOT c.is_group
+ORDER BY c.updated_at ASC, c.id ASC
+`
+
+type GetUserConversationsAfterParams struct {
+	UserID          uuid.UUID `db:"user_id" json:"user_id"`
+	Limit           int32     `db:"limit" json:"limit"`
+	CursorUpdatedAt time.Time `db:"cursor_updated_at" json:"cursor_updated_at"`
+	CursorID        uuid.UUID `db:"cursor_id" json:"cursor_id"`
+}
+
+type GetUserConversationsAfterRow struct {
+	Conversation             Conversation   `db:"conversation" json:"conversation"`
+	LastMessageID            uuid.NullUUID  `db:"last_message_id" json:"last_message_id"`
+	LastMessageSenderID      uuid.NullUUID  `db:"last_message_sender_id" json:"last_message_sender_id"`
+	LastMessageSnippet       sql.NullString `db:"last_message_snippet" json:"last_message_snippet"`
+	LastMessageFileType      sql.NullString `db:"last_message_file_type" json:"last_message_file_type"`
+	LastMessageAt            sql.NullTime   `db:"last_message_at" json:"last_message_at"`
+	UnreadCount              int64          `db:"unread_count" json:"unread_count"`
+	OtherUserID              uuid.NullUUID  `db:"other_user_id" json:"other_user_id"`
+	OtherUserName            sql.NullString `db:"other_user_name" json:"other_user_name"`
+	OtherUserEmail           sql.NullString `db:"other_user_email" json:"other_user_email"`
+	OtherUserEmailVerifiedAt sql.NullTime   `db:"other_user_email_verified_at" json:"other_user_email_verified_at"`
+}
+
+func (q *Queries) GetUserConversationsAfter(ctx context.Context, arg GetUserConversationsAfterParams) ([]GetUserConversationsAfterRow, error) {
+	rows, err := q.query(ctx, q.getUserConversationsAfterStmt, getUserConversationsAfter,
+		arg.UserID,
+		arg.Limit,
+		arg.CursorUpdatedAt,
+		arg.CursorID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserConversationsAfterRow
+	for rows.Next() {
+		var i GetUserConversationsAfterRow
+		if err := rows.Scan(
+			&i.Conversation.ID,
+			&i.Conversation.IsGroup,
+			&i.Conversation.Name,
+			&i.Conversation.CreatedBy,
+			&i.Conversation.CreatedAt,
+			&i.Conversation.UpdatedAt,
+			&i.Conversation.DeletedAt,
+			&i.Conversation.LastSeq,
+			&i.LastMessageID,
+			&i.LastMessageSenderID,
+			&i.LastMessageSnippet,
+			&i.LastMessageFileType,
+			&i.LastMessageAt,
+			&i.UnreadCount,
+			&i.OtherUserID,
+			&i.OtherUserName,
+			&i.OtherUserEmail,
+			&i.OtherUserEmailVerifiedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserConversationsBefore = `-- name: GetUserConversationsBefore :many
+WITH page AS (
+    SELECT c.id FROM conversations c
+    JOIN conversation_members cm ON cm.conversation_id = c.id
+    WHERE cm.user_id = $1
+    AND c.deleted_at IS NULL
+    AND (c.updated_at, c.id) < ($3::timestamptz, $4::uuid)
+    ORDER BY c.updated_at DESC, c.id DESC
+    LIMIT $2
+)
+SELECT
+    c.id, c.is_group, c.name, c.created_by, c.created_at, c.updated_at, c.deleted_at, c.last_seq,
+    lm.id AS last_message_id,
+    lm.sender_id AS last_message_sender_id,
+    LEFT(lm.content, 120)::text AS last_message_snippet,
+    f.mime_type AS last_message_file_type,
+    lm.created_at AS last_message_at,
+    COALESCE(uc.unread_count, 0)::bigint AS unread_count,
+    ou.id AS other_user_id,
+    ou.name AS other_user_name,
+    ou.email AS other_user_email,
+    ou.email_verified_at AS other_user_email_verified_at
+FROM page
+JOIN conversations c ON c.id = page.id
+JOIN conversation_members cm ON cm.conversation_id = c.id AND cm.user_id = $1
+LEFT JOIN LATERAL (
+    SELECT m.id, m.sender_id, m.content, m.file_id, m.created_at
+    FROM messages m
+    WHERE m.conversation_id = c.id AND m.deleted_at IS NULL AND m.thread_id IS NULL
+    ORDER BY m.seq DESC
+    LIMIT 1
+) lm ON TRUE
+LEFT JOIN files f ON f.id = lm.file_id
+LEFT JOIN LATERAL (
+    SELECT COUNT(*) AS unread_count
+    FROM messages m
+    WHERE m.conversation_id = c.id AND m.deleted_at IS NULL AND m.thread_id IS NULL
+    AND m.sender_id != cm.user_id
+    AND (cm.last_read_at IS NULL OR m.created_at > cm.last_read_at)
+) uc ON TRUE
+LEFT JOIN LATERAL (
+    SELECT u.id, u.name, u.email, u.email_verified_at
+    FROM conversation_members om
+    JOIN users u ON u.id = om.user_id
+    WHERE om.conversation_id = c.id AND om.user_id != cm.user_id
+    LIMIT 1
+) ou ON NOT c.is_group
+ORDER BY c.updated_at DESC, c.id DESC
+`
+
+type GetUserConversationsBeforeParams struct {
+	UserID          uuid.UUID `db:"user_id" json:"user_id"`
+	Limit           int32     `db:"limit" json:"limit"`
+	CursorUpdatedAt time.Time `db:"cursor_updated_at" json:"cursor_updated_at"`
+	CursorID        uuid.UUID `db:"cursor_id" json:"cursor_id"`
+}
+
+type GetUserConversationsBeforeRow struct {
+	Conversation             Conversation   `db:"conversation" json:"conversation"`
+	LastMessageID            uuid.NullUUID  `db:"last_message_id" json:"last_message_id"`
+	LastMessageSenderID      uuid.NullUUID  `db:"last_message_sender_id" json:"last_message_sender_id"`
+	LastMessageSnippet       sql.NullString `db:"last_message_snippet" json:"last_message_snippet"`
+	LastMessageFileType      sql.NullString `db:"last_message_file_type" json:"last_message_file_type"`
+	LastMessageAt            sql.NullTime   `db:"last_message_at" json:"last_message_at"`
+	UnreadCount              int64          `db:"unread_count" json:"unread_count"`
+	OtherUserID              uuid.NullUUID  `db:"other_user_id" json:"other_user_id"`
+	OtherUserName            sql.NullString `db:"other_user_name" json:"other_user_name"`
+	OtherUserEmail           sql.NullString `db:"other_user_email" json:"other_user_email"`
+	OtherUserEmailVerifiedAt sql.NullTime   `db:"other_user_email_verified_at" json:"other_user_email_verified_at"`
+}
+
+func (q *Queries) GetUserConversationsBefore(ctx context.Context, arg GetUserConversationsBeforeParams) ([]GetUserConversationsBeforeRow, error) {
+	rows, err := q.query(ctx, q.getUserConversationsBeforeStmt, getUserConversationsBefore,
+		arg.UserID,
+		arg.Limit,
+		arg.CursorUpdatedAt,
+		arg.CursorID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserConversationsBeforeRow
 	for rows.Next() {
-		var i Conversation
+		var i GetUserConversationsBeforeRow
 		if err := rows.Scan(
-			&i.ID,
-			&i.IsGroup,
-			&i.Name,
-			&i.CreatedBy,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.DeletedAt,
+			&i.Conversation.ID,
+			&i.Conversation.IsGroup,
+			&i.Conversation.Name,
+			&i.Conversation.CreatedBy,
+			&i.Conversation.CreatedAt,
+			&i.Conversation.UpdatedAt,
+			&i.Conversation.DeletedAt,
+			&i.Conversation.LastSeq,
+			&i.LastMessageID,
+			&i.LastMessageSenderID,
+			&i.LastMessageSnippet,
+			&i.LastMessageFileType,
+			&i.LastMessageAt,
+			&i.UnreadCount,
+			&i.OtherUserID,
+			&i.OtherUserName,
+			&i.OtherUserEmail,
+			&i.OtherUserEmailVerifiedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -287,7 +595,7 @@ const updateConversationName = `-- name: UpdateConversationName :one
 UPDATE conversations
 SET name = $2, updated_at = NOW()
 WHERE id = $1
-RETURNING id, is_group, name, created_by, created_at, updated_at, deleted_at
+RETURNING id, is_group, name, created_by, created_at, updated_at, deleted_at, last_seq
 `
 
 type UpdateConversationNameParams struct {
@@ -306,6 +614,7 @@ func (q *Queries) UpdateConversationName(ctx context.Context, arg UpdateConversa
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.LastSeq,
 	)
 	return i, err
 }
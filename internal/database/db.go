@@ -24,36 +24,132 @@ func New(db DBTX) *Queries {
 func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	q := Queries{db: db}
 	var err error
+	if q.acquireBlobStmt, err = db.PrepareContext(ctx, acquireBlob); err != nil {
+		return nil, fmt.Errorf("error preparing query AcquireBlob: %w", err)
+	}
+	if q.addBlobRefStmt, err = db.PrepareContext(ctx, addBlobRef); err != nil {
+		return nil, fmt.Errorf("error preparing query AddBlobRef: %w", err)
+	}
 	if q.addConversationMemberStmt, err = db.PrepareContext(ctx, addConversationMember); err != nil {
 		return nil, fmt.Errorf("error preparing query AddConversationMember: %w", err)
 	}
+	if q.addMessageReactionStmt, err = db.PrepareContext(ctx, addMessageReaction); err != nil {
+		return nil, fmt.Errorf("error preparing query AddMessageReaction: %w", err)
+	}
+	if q.appendUploadPartStmt, err = db.PrepareContext(ctx, appendUploadPart); err != nil {
+		return nil, fmt.Errorf("error preparing query AppendUploadPart: %w", err)
+	}
+	if q.attachFileToConversationStmt, err = db.PrepareContext(ctx, attachFileToConversation); err != nil {
+		return nil, fmt.Errorf("error preparing query AttachFileToConversation: %w", err)
+	}
+	if q.attemptLoginChallengeStmt, err = db.PrepareContext(ctx, attemptLoginChallenge); err != nil {
+		return nil, fmt.Errorf("error preparing query AttemptLoginChallenge: %w", err)
+	}
+	if q.canAccessContentStmt, err = db.PrepareContext(ctx, canAccessContent); err != nil {
+		return nil, fmt.Errorf("error preparing query CanAccessContent: %w", err)
+	}
+	if q.canAccessFileStmt, err = db.PrepareContext(ctx, canAccessFile); err != nil {
+		return nil, fmt.Errorf("error preparing query CanAccessFile: %w", err)
+	}
+	if q.claimUploadStmt, err = db.PrepareContext(ctx, claimUpload); err != nil {
+		return nil, fmt.Errorf("error preparing query ClaimUpload: %w", err)
+	}
+	if q.copyFileThumbnailsStmt, err = db.PrepareContext(ctx, copyFileThumbnails); err != nil {
+		return nil, fmt.Errorf("error preparing query CopyFileThumbnails: %w", err)
+	}
+	if q.countRecentPasswordResetTokensStmt, err = db.PrepareContext(ctx, countRecentPasswordResetTokens); err != nil {
+		return nil, fmt.Errorf("error preparing query CountRecentPasswordResetTokens: %w", err)
+	}
+	if q.countUnusedRecoveryCodesStmt, err = db.PrepareContext(ctx, countUnusedRecoveryCodes); err != nil {
+		return nil, fmt.Errorf("error preparing query CountUnusedRecoveryCodes: %w", err)
+	}
 	if q.createConversationStmt, err = db.PrepareContext(ctx, createConversation); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateConversation: %w", err)
 	}
 	if q.createFileStmt, err = db.PrepareContext(ctx, createFile); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateFile: %w", err)
 	}
+	if q.createFileThumbnailStmt, err = db.PrepareContext(ctx, createFileThumbnail); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateFileThumbnail: %w", err)
+	}
+	if q.createLoginChallengeStmt, err = db.PrepareContext(ctx, createLoginChallenge); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateLoginChallenge: %w", err)
+	}
 	if q.createMessageStmt, err = db.PrepareContext(ctx, createMessage); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateMessage: %w", err)
 	}
+	if q.createPasswordResetTokenStmt, err = db.PrepareContext(ctx, createPasswordResetToken); err != nil {
+		return nil, fmt.Errorf("error preparing query CreatePasswordResetToken: %w", err)
+	}
+	if q.createRecoveryCodeStmt, err = db.PrepareContext(ctx, createRecoveryCode); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateRecoveryCode: %w", err)
+	}
 	if q.createRefreshTokenStmt, err = db.PrepareContext(ctx, createRefreshToken); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateRefreshToken: %w", err)
 	}
+	if q.createSecurityEventStmt, err = db.PrepareContext(ctx, createSecurityEvent); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateSecurityEvent: %w", err)
+	}
+	if q.createUploadStmt, err = db.PrepareContext(ctx, createUpload); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateUpload: %w", err)
+	}
 	if q.createUserStmt, err = db.PrepareContext(ctx, createUser); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateUser: %w", err)
 	}
 	if q.deleteConversationStmt, err = db.PrepareContext(ctx, deleteConversation); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteConversation: %w", err)
 	}
+	if q.deleteConversationStorageQuotaStmt, err = db.PrepareContext(ctx, deleteConversationStorageQuota); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteConversationStorageQuota: %w", err)
+	}
+	if q.deleteExpiredLoginChallengesStmt, err = db.PrepareContext(ctx, deleteExpiredLoginChallenges); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteExpiredLoginChallenges: %w", err)
+	}
+	if q.deleteExpiredUploadsStmt, err = db.PrepareContext(ctx, deleteExpiredUploads); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteExpiredUploads: %w", err)
+	}
 	if q.deleteFileStmt, err = db.PrepareContext(ctx, deleteFile); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteFile: %w", err)
 	}
+	if q.deleteOrphanedFileStmt, err = db.PrepareContext(ctx, deleteOrphanedFile); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteOrphanedFile: %w", err)
+	}
+	if q.deleteRecoveryCodesStmt, err = db.PrepareContext(ctx, deleteRecoveryCodes); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteRecoveryCodes: %w", err)
+	}
+	if q.deleteUnreferencedBlobStmt, err = db.PrepareContext(ctx, deleteUnreferencedBlob); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteUnreferencedBlob: %w", err)
+	}
+	if q.deleteUploadStmt, err = db.PrepareContext(ctx, deleteUpload); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteUpload: %w", err)
+	}
 	if q.deleteUserStmt, err = db.PrepareContext(ctx, deleteUser); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteUser: %w", err)
 	}
+	if q.deleteUserStorageQuotaStmt, err = db.PrepareContext(ctx, deleteUserStorageQuota); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteUserStorageQuota: %w", err)
+	}
+	if q.deleteUserTOTPStmt, err = db.PrepareContext(ctx, deleteUserTOTP); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteUserTOTP: %w", err)
+	}
 	if q.editMessageStmt, err = db.PrepareContext(ctx, editMessage); err != nil {
 		return nil, fmt.Errorf("error preparing query EditMessage: %w", err)
 	}
+	if q.enableUserTOTPStmt, err = db.PrepareContext(ctx, enableUserTOTP); err != nil {
+		return nil, fmt.Errorf("error preparing query EnableUserTOTP: %w", err)
+	}
+	if q.followThreadStmt, err = db.PrepareContext(ctx, followThread); err != nil {
+		return nil, fmt.Errorf("error preparing query FollowThread: %w", err)
+	}
+	if q.getAccessibleFileByPathStmt, err = db.PrepareContext(ctx, getAccessibleFileByPath); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAccessibleFileByPath: %w", err)
+	}
+	if q.getAccessibleFileBySHA256Stmt, err = db.PrepareContext(ctx, getAccessibleFileBySHA256); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAccessibleFileBySHA256: %w", err)
+	}
+	if q.getBlobStmt, err = db.PrepareContext(ctx, getBlob); err != nil {
+		return nil, fmt.Errorf("error preparing query GetBlob: %w", err)
+	}
 	if q.getConversationByIDStmt, err = db.PrepareContext(ctx, getConversationByID); err != nil {
 		return nil, fmt.Errorf("error preparing query GetConversationByID: %w", err)
 	}
@@ -63,27 +159,75 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.getConversationMembersStmt, err = db.PrepareContext(ctx, getConversationMembers); err != nil {
 		return nil, fmt.Errorf("error preparing query GetConversationMembers: %w", err)
 	}
+	if q.getConversationStorageQuotaStmt, err = db.PrepareContext(ctx, getConversationStorageQuota); err != nil {
+		return nil, fmt.Errorf("error preparing query GetConversationStorageQuota: %w", err)
+	}
+	if q.getConversationStorageUsageStmt, err = db.PrepareContext(ctx, getConversationStorageUsage); err != nil {
+		return nil, fmt.Errorf("error preparing query GetConversationStorageUsage: %w", err)
+	}
 	if q.getDirectConversationStmt, err = db.PrepareContext(ctx, getDirectConversation); err != nil {
 		return nil, fmt.Errorf("error preparing query GetDirectConversation: %w", err)
 	}
 	if q.getFileByIDStmt, err = db.PrepareContext(ctx, getFileByID); err != nil {
 		return nil, fmt.Errorf("error preparing query GetFileByID: %w", err)
 	}
+	if q.getFileThumbnailsStmt, err = db.PrepareContext(ctx, getFileThumbnails); err != nil {
+		return nil, fmt.Errorf("error preparing query GetFileThumbnails: %w", err)
+	}
 	if q.getFirstAdminOrMemberStmt, err = db.PrepareContext(ctx, getFirstAdminOrMember); err != nil {
 		return nil, fmt.Errorf("error preparing query GetFirstAdminOrMember: %w", err)
 	}
+	if q.getFollowedThreadsStmt, err = db.PrepareContext(ctx, getFollowedThreads); err != nil {
+		return nil, fmt.Errorf("error preparing query GetFollowedThreads: %w", err)
+	}
+	if q.getMessageByClientMsgIDStmt, err = db.PrepareContext(ctx, getMessageByClientMsgID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMessageByClientMsgID: %w", err)
+	}
 	if q.getMessageByIDStmt, err = db.PrepareContext(ctx, getMessageByID); err != nil {
 		return nil, fmt.Errorf("error preparing query GetMessageByID: %w", err)
 	}
+	if q.getMessageReactionsStmt, err = db.PrepareContext(ctx, getMessageReactions); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMessageReactions: %w", err)
+	}
 	if q.getMessageReceiptsStmt, err = db.PrepareContext(ctx, getMessageReceipts); err != nil {
 		return nil, fmt.Errorf("error preparing query GetMessageReceipts: %w", err)
 	}
 	if q.getMessagesByConversationStmt, err = db.PrepareContext(ctx, getMessagesByConversation); err != nil {
 		return nil, fmt.Errorf("error preparing query GetMessagesByConversation: %w", err)
 	}
+	if q.getMessagesByConversationAfterStmt, err = db.PrepareContext(ctx, getMessagesByConversationAfter); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMessagesByConversationAfter: %w", err)
+	}
+	if q.getMessagesByConversationBeforeStmt, err = db.PrepareContext(ctx, getMessagesByConversationBefore); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMessagesByConversationBefore: %w", err)
+	}
+	if q.getMessagesForReplayStmt, err = db.PrepareContext(ctx, getMessagesForReplay); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMessagesForReplay: %w", err)
+	}
+	if q.getPinnedMessageStmt, err = db.PrepareContext(ctx, getPinnedMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query GetPinnedMessage: %w", err)
+	}
+	if q.getPinnedMessagesStmt, err = db.PrepareContext(ctx, getPinnedMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query GetPinnedMessages: %w", err)
+	}
+	if q.getProcessedFileBySHA256Stmt, err = db.PrepareContext(ctx, getProcessedFileBySHA256); err != nil {
+		return nil, fmt.Errorf("error preparing query GetProcessedFileBySHA256: %w", err)
+	}
 	if q.getRefreshTokenByHashStmt, err = db.PrepareContext(ctx, getRefreshTokenByHash); err != nil {
 		return nil, fmt.Errorf("error preparing query GetRefreshTokenByHash: %w", err)
 	}
+	if q.getScanVerdictBySHA256Stmt, err = db.PrepareContext(ctx, getScanVerdictBySHA256); err != nil {
+		return nil, fmt.Errorf("error preparing query GetScanVerdictBySHA256: %w", err)
+	}
+	if q.getThreadFollowersStmt, err = db.PrepareContext(ctx, getThreadFollowers); err != nil {
+		return nil, fmt.Errorf("error preparing query GetThreadFollowers: %w", err)
+	}
+	if q.getThreadRepliesStmt, err = db.PrepareContext(ctx, getThreadReplies); err != nil {
+		return nil, fmt.Errorf("error preparing query GetThreadReplies: %w", err)
+	}
+	if q.getUploadStmt, err = db.PrepareContext(ctx, getUpload); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUpload: %w", err)
+	}
 	if q.getUserByEmailStmt, err = db.PrepareContext(ctx, getUserByEmail); err != nil {
 		return nil, fmt.Errorf("error preparing query GetUserByEmail: %w", err)
 	}
@@ -93,36 +237,138 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.getUserConversationsStmt, err = db.PrepareContext(ctx, getUserConversations); err != nil {
 		return nil, fmt.Errorf("error preparing query GetUserConversations: %w", err)
 	}
+	if q.getUserConversationsAfterStmt, err = db.PrepareContext(ctx, getUserConversationsAfter); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserConversationsAfter: %w", err)
+	}
+	if q.getUserConversationsBeforeStmt, err = db.PrepareContext(ctx, getUserConversationsBefore); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserConversationsBefore: %w", err)
+	}
+	if q.getUserStorageQuotaStmt, err = db.PrepareContext(ctx, getUserStorageQuota); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserStorageQuota: %w", err)
+	}
+	if q.getUserStorageUsageStmt, err = db.PrepareContext(ctx, getUserStorageUsage); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserStorageUsage: %w", err)
+	}
+	if q.getUserTOTPStmt, err = db.PrepareContext(ctx, getUserTOTP); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserTOTP: %w", err)
+	}
+	if q.invalidatePasswordResetTokensStmt, err = db.PrepareContext(ctx, invalidatePasswordResetTokens); err != nil {
+		return nil, fmt.Errorf("error preparing query InvalidatePasswordResetTokens: %w", err)
+	}
+	if q.isSessionActiveStmt, err = db.PrepareContext(ctx, isSessionActive); err != nil {
+		return nil, fmt.Errorf("error preparing query IsSessionActive: %w", err)
+	}
+	if q.isStoragePathReferencedStmt, err = db.PrepareContext(ctx, isStoragePathReferenced); err != nil {
+		return nil, fmt.Errorf("error preparing query IsStoragePathReferenced: %w", err)
+	}
+	if q.listActiveSessionsStmt, err = db.PrepareContext(ctx, listActiveSessions); err != nil {
+		return nil, fmt.Errorf("error preparing query ListActiveSessions: %w", err)
+	}
+	if q.listBlobRefCountDriftStmt, err = db.PrepareContext(ctx, listBlobRefCountDrift); err != nil {
+		return nil, fmt.Errorf("error preparing query ListBlobRefCountDrift: %w", err)
+	}
+	if q.listFilesAfterStmt, err = db.PrepareContext(ctx, listFilesAfter); err != nil {
+		return nil, fmt.Errorf("error preparing query ListFilesAfter: %w", err)
+	}
+	if q.listOrphanedFilesStmt, err = db.PrepareContext(ctx, listOrphanedFiles); err != nil {
+		return nil, fmt.Errorf("error preparing query ListOrphanedFiles: %w", err)
+	}
+	if q.listPendingScansStmt, err = db.PrepareContext(ctx, listPendingScans); err != nil {
+		return nil, fmt.Errorf("error preparing query ListPendingScans: %w", err)
+	}
+	if q.listUnreferencedBlobsStmt, err = db.PrepareContext(ctx, listUnreferencedBlobs); err != nil {
+		return nil, fmt.Errorf("error preparing query ListUnreferencedBlobs: %w", err)
+	}
+	if q.lockConversationPinsStmt, err = db.PrepareContext(ctx, lockConversationPins); err != nil {
+		return nil, fmt.Errorf("error preparing query LockConversationPins: %w", err)
+	}
+	if q.lockConversationStorageStmt, err = db.PrepareContext(ctx, lockConversationStorage); err != nil {
+		return nil, fmt.Errorf("error preparing query LockConversationStorage: %w", err)
+	}
+	if q.lockUserStorageStmt, err = db.PrepareContext(ctx, lockUserStorage); err != nil {
+		return nil, fmt.Errorf("error preparing query LockUserStorage: %w", err)
+	}
+	if q.markEmailVerifiedStmt, err = db.PrepareContext(ctx, markEmailVerified); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkEmailVerified: %w", err)
+	}
 	if q.markMessageReadStmt, err = db.PrepareContext(ctx, markMessageRead); err != nil {
 		return nil, fmt.Errorf("error preparing query MarkMessageRead: %w", err)
 	}
+	if q.pinMessageStmt, err = db.PrepareContext(ctx, pinMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query PinMessage: %w", err)
+	}
+	if q.releaseBlobStmt, err = db.PrepareContext(ctx, releaseBlob); err != nil {
+		return nil, fmt.Errorf("error preparing query ReleaseBlob: %w", err)
+	}
+	if q.releaseUploadStmt, err = db.PrepareContext(ctx, releaseUpload); err != nil {
+		return nil, fmt.Errorf("error preparing query ReleaseUpload: %w", err)
+	}
 	if q.removeConversationMemberStmt, err = db.PrepareContext(ctx, removeConversationMember); err != nil {
 		return nil, fmt.Errorf("error preparing query RemoveConversationMember: %w", err)
 	}
+	if q.removeMessageReactionStmt, err = db.PrepareContext(ctx, removeMessageReaction); err != nil {
+		return nil, fmt.Errorf("error preparing query RemoveMessageReaction: %w", err)
+	}
 	if q.revokeAllUserRefreshTokensStmt, err = db.PrepareContext(ctx, revokeAllUserRefreshTokens); err != nil {
 		return nil, fmt.Errorf("error preparing query RevokeAllUserRefreshTokens: %w", err)
 	}
+	if q.revokeOtherSessionsStmt, err = db.PrepareContext(ctx, revokeOtherSessions); err != nil {
+		return nil, fmt.Errorf("error preparing query RevokeOtherSessions: %w", err)
+	}
 	if q.revokeRefreshTokenStmt, err = db.PrepareContext(ctx, revokeRefreshToken); err != nil {
 		return nil, fmt.Errorf("error preparing query RevokeRefreshToken: %w", err)
 	}
+	if q.revokeSessionStmt, err = db.PrepareContext(ctx, revokeSession); err != nil {
+		return nil, fmt.Errorf("error preparing query RevokeSession: %w", err)
+	}
 	if q.rotateRefreshTokenStmt, err = db.PrepareContext(ctx, rotateRefreshToken); err != nil {
 		return nil, fmt.Errorf("error preparing query RotateRefreshToken: %w", err)
 	}
 	if q.searchMessagesStmt, err = db.PrepareContext(ctx, searchMessages); err != nil {
 		return nil, fmt.Errorf("error preparing query SearchMessages: %w", err)
 	}
+	if q.searchMessagesAfterStmt, err = db.PrepareContext(ctx, searchMessagesAfter); err != nil {
+		return nil, fmt.Errorf("error preparing query SearchMessagesAfter: %w", err)
+	}
+	if q.setBlobRefCountStmt, err = db.PrepareContext(ctx, setBlobRefCount); err != nil {
+		return nil, fmt.Errorf("error preparing query SetBlobRefCount: %w", err)
+	}
+	if q.setConversationStorageQuotaStmt, err = db.PrepareContext(ctx, setConversationStorageQuota); err != nil {
+		return nil, fmt.Errorf("error preparing query SetConversationStorageQuota: %w", err)
+	}
+	if q.setFileImageInfoStmt, err = db.PrepareContext(ctx, setFileImageInfo); err != nil {
+		return nil, fmt.Errorf("error preparing query SetFileImageInfo: %w", err)
+	}
+	if q.setFileScanResultStmt, err = db.PrepareContext(ctx, setFileScanResult); err != nil {
+		return nil, fmt.Errorf("error preparing query SetFileScanResult: %w", err)
+	}
 	if q.setMemberRoleStmt, err = db.PrepareContext(ctx, setMemberRole); err != nil {
 		return nil, fmt.Errorf("error preparing query SetMemberRole: %w", err)
 	}
+	if q.setPendingUserTOTPStmt, err = db.PrepareContext(ctx, setPendingUserTOTP); err != nil {
+		return nil, fmt.Errorf("error preparing query SetPendingUserTOTP: %w", err)
+	}
+	if q.setUserStorageQuotaStmt, err = db.PrepareContext(ctx, setUserStorageQuota); err != nil {
+		return nil, fmt.Errorf("error preparing query SetUserStorageQuota: %w", err)
+	}
 	if q.softDeleteMessageStmt, err = db.PrepareContext(ctx, softDeleteMessage); err != nil {
 		return nil, fmt.Errorf("error preparing query SoftDeleteMessage: %w", err)
 	}
+	if q.unfollowThreadStmt, err = db.PrepareContext(ctx, unfollowThread); err != nil {
+		return nil, fmt.Errorf("error preparing query UnfollowThread: %w", err)
+	}
+	if q.unpinMessageStmt, err = db.PrepareContext(ctx, unpinMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query UnpinMessage: %w", err)
+	}
 	if q.updateConversationNameStmt, err = db.PrepareContext(ctx, updateConversationName); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateConversationName: %w", err)
 	}
 	if q.updateConversationTimestampStmt, err = db.PrepareContext(ctx, updateConversationTimestamp); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateConversationTimestamp: %w", err)
 	}
+	if q.updateFilePathStmt, err = db.PrepareContext(ctx, updateFilePath); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateFilePath: %w", err)
+	}
 	if q.updateLastReadStmt, err = db.PrepareContext(ctx, updateLastRead); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateLastRead: %w", err)
 	}
@@ -135,6 +381,18 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.upsertMessageReceiptStmt, err = db.PrepareContext(ctx, upsertMessageReceipt); err != nil {
 		return nil, fmt.Errorf("error preparing query UpsertMessageReceipt: %w", err)
 	}
+	if q.useLoginChallengeStmt, err = db.PrepareContext(ctx, useLoginChallenge); err != nil {
+		return nil, fmt.Errorf("error preparing query UseLoginChallenge: %w", err)
+	}
+	if q.usePasswordResetTokenStmt, err = db.PrepareContext(ctx, usePasswordResetToken); err != nil {
+		return nil, fmt.Errorf("error preparing query UsePasswordResetToken: %w", err)
+	}
+	if q.useRecoveryCodeStmt, err = db.PrepareContext(ctx, useRecoveryCode); err != nil {
+		return nil, fmt.Errorf("error preparing query UseRecoveryCode: %w", err)
+	}
+	if q.useTOTPStepStmt, err = db.PrepareContext(ctx, useTOTPStep); err != nil {
+		return nil, fmt.Errorf("error preparing query UseTOTPStep: %w", err)
+	}
 	if q.userExistsByEmailStmt, err = db.PrepareContext(ctx, userExistsByEmail); err != nil {
 		return nil, fmt.Errorf("error preparing query UserExistsByEmail: %w", err)
 	}
@@ -143,11 +401,71 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 
 func (q *Queries) Close() error {
 	var err error
+	if q.acquireBlobStmt != nil {
+		if cerr := q.acquireBlobStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing acquireBlobStmt: %w", cerr)
+		}
+	}
+	if q.addBlobRefStmt != nil {
+		if cerr := q.addBlobRefStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addBlobRefStmt: %w", cerr)
+		}
+	}
 	if q.addConversationMemberStmt != nil {
 		if cerr := q.addConversationMemberStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing addConversationMemberStmt: %w", cerr)
 		}
 	}
+	if q.addMessageReactionStmt != nil {
+		if cerr := q.addMessageReactionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addMessageReactionStmt: %w", cerr)
+		}
+	}
+	if q.appendUploadPartStmt != nil {
+		if cerr := q.appendUploadPartStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing appendUploadPartStmt: %w", cerr)
+		}
+	}
+	if q.attachFileToConversationStmt != nil {
+		if cerr := q.attachFileToConversationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing attachFileToConversationStmt: %w", cerr)
+		}
+	}
+	if q.attemptLoginChallengeStmt != nil {
+		if cerr := q.attemptLoginChallengeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing attemptLoginChallengeStmt: %w", cerr)
+		}
+	}
+	if q.canAccessContentStmt != nil {
+		if cerr := q.canAccessContentStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing canAccessContentStmt: %w", cerr)
+		}
+	}
+	if q.canAccessFileStmt != nil {
+		if cerr := q.canAccessFileStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing canAccessFileStmt: %w", cerr)
+		}
+	}
+	if q.claimUploadStmt != nil {
+		if cerr := q.claimUploadStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing claimUploadStmt: %w", cerr)
+		}
+	}
+	if q.copyFileThumbnailsStmt != nil {
+		if cerr := q.copyFileThumbnailsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing copyFileThumbnailsStmt: %w", cerr)
+		}
+	}
+	if q.countRecentPasswordResetTokensStmt != nil {
+		if cerr := q.countRecentPasswordResetTokensStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countRecentPasswordResetTokensStmt: %w", cerr)
+		}
+	}
+	if q.countUnusedRecoveryCodesStmt != nil {
+		if cerr := q.countUnusedRecoveryCodesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countUnusedRecoveryCodesStmt: %w", cerr)
+		}
+	}
 	if q.createConversationStmt != nil {
 		if cerr := q.createConversationStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createConversationStmt: %w", cerr)
@@ -158,16 +476,46 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing createFileStmt: %w", cerr)
 		}
 	}
+	if q.createFileThumbnailStmt != nil {
+		if cerr := q.createFileThumbnailStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createFileThumbnailStmt: %w", cerr)
+		}
+	}
+	if q.createLoginChallengeStmt != nil {
+		if cerr := q.createLoginChallengeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createLoginChallengeStmt: %w", cerr)
+		}
+	}
 	if q.createMessageStmt != nil {
 		if cerr := q.createMessageStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createMessageStmt: %w", cerr)
 		}
 	}
+	if q.createPasswordResetTokenStmt != nil {
+		if cerr := q.createPasswordResetTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createPasswordResetTokenStmt: %w", cerr)
+		}
+	}
+	if q.createRecoveryCodeStmt != nil {
+		if cerr := q.createRecoveryCodeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createRecoveryCodeStmt: %w", cerr)
+		}
+	}
 	if q.createRefreshTokenStmt != nil {
 		if cerr := q.createRefreshTokenStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createRefreshTokenStmt: %w", cerr)
 		}
 	}
+	if q.createSecurityEventStmt != nil {
+		if cerr := q.createSecurityEventStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createSecurityEventStmt: %w", cerr)
+		}
+	}
+	if q.createUploadStmt != nil {
+		if cerr := q.createUploadStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createUploadStmt: %w", cerr)
+		}
+	}
 	if q.createUserStmt != nil {
 		if cerr := q.createUserStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createUserStmt: %w", cerr)
@@ -178,21 +526,91 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing deleteConversationStmt: %w", cerr)
 		}
 	}
+	if q.deleteConversationStorageQuotaStmt != nil {
+		if cerr := q.deleteConversationStorageQuotaStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteConversationStorageQuotaStmt: %w", cerr)
+		}
+	}
+	if q.deleteExpiredLoginChallengesStmt != nil {
+		if cerr := q.deleteExpiredLoginChallengesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteExpiredLoginChallengesStmt: %w", cerr)
+		}
+	}
+	if q.deleteExpiredUploadsStmt != nil {
+		if cerr := q.deleteExpiredUploadsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteExpiredUploadsStmt: %w", cerr)
+		}
+	}
 	if q.deleteFileStmt != nil {
 		if cerr := q.deleteFileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteFileStmt: %w", cerr)
 		}
 	}
+	if q.deleteOrphanedFileStmt != nil {
+		if cerr := q.deleteOrphanedFileStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteOrphanedFileStmt: %w", cerr)
+		}
+	}
+	if q.deleteRecoveryCodesStmt != nil {
+		if cerr := q.deleteRecoveryCodesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteRecoveryCodesStmt: %w", cerr)
+		}
+	}
+	if q.deleteUnreferencedBlobStmt != nil {
+		if cerr := q.deleteUnreferencedBlobStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteUnreferencedBlobStmt: %w", cerr)
+		}
+	}
+	if q.deleteUploadStmt != nil {
+		if cerr := q.deleteUploadStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteUploadStmt: %w", cerr)
+		}
+	}
 	if q.deleteUserStmt != nil {
 		if cerr := q.deleteUserStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteUserStmt: %w", cerr)
 		}
 	}
+	if q.deleteUserStorageQuotaStmt != nil {
+		if cerr := q.deleteUserStorageQuotaStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteUserStorageQuotaStmt: %w", cerr)
+		}
+	}
+	if q.deleteUserTOTPStmt != nil {
+		if cerr := q.deleteUserTOTPStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteUserTOTPStmt: %w", cerr)
+		}
+	}
 	if q.editMessageStmt != nil {
 		if cerr := q.editMessageStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing editMessageStmt: %w", cerr)
 		}
 	}
+	if q.enableUserTOTPStmt != nil {
+		if cerr := q.enableUserTOTPStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing enableUserTOTPStmt: %w", cerr)
+		}
+	}
+	if q.followThreadStmt != nil {
+		if cerr := q.followThreadStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing followThreadStmt: %w", cerr)
+		}
+	}
+	if q.getAccessibleFileByPathStmt != nil {
+		if cerr := q.getAccessibleFileByPathStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAccessibleFileByPathStmt: %w", cerr)
+		}
+	}
+	if q.getAccessibleFileBySHA256Stmt != nil {
+		if cerr := q.getAccessibleFileBySHA256Stmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAccessibleFileBySHA256Stmt: %w", cerr)
+		}
+	}
+	if q.getBlobStmt != nil {
+		if cerr := q.getBlobStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getBlobStmt: %w", cerr)
+		}
+	}
 	if q.getConversationByIDStmt != nil {
 		if cerr := q.getConversationByIDStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getConversationByIDStmt: %w", cerr)
@@ -208,6 +626,16 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getConversationMembersStmt: %w", cerr)
 		}
 	}
+	if q.getConversationStorageQuotaStmt != nil {
+		if cerr := q.getConversationStorageQuotaStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getConversationStorageQuotaStmt: %w", cerr)
+		}
+	}
+	if q.getConversationStorageUsageStmt != nil {
+		if cerr := q.getConversationStorageUsageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getConversationStorageUsageStmt: %w", cerr)
+		}
+	}
 	if q.getDirectConversationStmt != nil {
 		if cerr := q.getDirectConversationStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getDirectConversationStmt: %w", cerr)
@@ -218,16 +646,36 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getFileByIDStmt: %w", cerr)
 		}
 	}
+	if q.getFileThumbnailsStmt != nil {
+		if cerr := q.getFileThumbnailsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getFileThumbnailsStmt: %w", cerr)
+		}
+	}
 	if q.getFirstAdminOrMemberStmt != nil {
 		if cerr := q.getFirstAdminOrMemberStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getFirstAdminOrMemberStmt: %w", cerr)
 		}
 	}
+	if q.getFollowedThreadsStmt != nil {
+		if cerr := q.getFollowedThreadsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getFollowedThreadsStmt: %w", cerr)
+		}
+	}
+	if q.getMessageByClientMsgIDStmt != nil {
+		if cerr := q.getMessageByClientMsgIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMessageByClientMsgIDStmt: %w", cerr)
+		}
+	}
 	if q.getMessageByIDStmt != nil {
 		if cerr := q.getMessageByIDStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getMessageByIDStmt: %w", cerr)
 		}
 	}
+	if q.getMessageReactionsStmt != nil {
+		if cerr := q.getMessageReactionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMessageReactionsStmt: %w", cerr)
+		}
+	}
 	if q.getMessageReceiptsStmt != nil {
 		if cerr := q.getMessageReceiptsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getMessageReceiptsStmt: %w", cerr)
@@ -238,11 +686,61 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getMessagesByConversationStmt: %w", cerr)
 		}
 	}
+	if q.getMessagesByConversationAfterStmt != nil {
+		if cerr := q.getMessagesByConversationAfterStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMessagesByConversationAfterStmt: %w", cerr)
+		}
+	}
+	if q.getMessagesByConversationBeforeStmt != nil {
+		if cerr := q.getMessagesByConversationBeforeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMessagesByConversationBeforeStmt: %w", cerr)
+		}
+	}
+	if q.getMessagesForReplayStmt != nil {
+		if cerr := q.getMessagesForReplayStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMessagesForReplayStmt: %w", cerr)
+		}
+	}
+	if q.getPinnedMessageStmt != nil {
+		if cerr := q.getPinnedMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getPinnedMessageStmt: %w", cerr)
+		}
+	}
+	if q.getPinnedMessagesStmt != nil {
+		if cerr := q.getPinnedMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getPinnedMessagesStmt: %w", cerr)
+		}
+	}
+	if q.getProcessedFileBySHA256Stmt != nil {
+		if cerr := q.getProcessedFileBySHA256Stmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getProcessedFileBySHA256Stmt: %w", cerr)
+		}
+	}
 	if q.getRefreshTokenByHashStmt != nil {
 		if cerr := q.getRefreshTokenByHashStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getRefreshTokenByHashStmt: %w", cerr)
 		}
 	}
+	if q.getScanVerdictBySHA256Stmt != nil {
+		if cerr := q.getScanVerdictBySHA256Stmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getScanVerdictBySHA256Stmt: %w", cerr)
+		}
+	}
+	if q.getThreadFollowersStmt != nil {
+		if cerr := q.getThreadFollowersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getThreadFollowersStmt: %w", cerr)
+		}
+	}
+	if q.getThreadRepliesStmt != nil {
+		if cerr := q.getThreadRepliesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getThreadRepliesStmt: %w", cerr)
+		}
+	}
+	if q.getUploadStmt != nil {
+		if cerr := q.getUploadStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUploadStmt: %w", cerr)
+		}
+	}
 	if q.getUserByEmailStmt != nil {
 		if cerr := q.getUserByEmailStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getUserByEmailStmt: %w", cerr)
@@ -258,26 +756,146 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getUserConversationsStmt: %w", cerr)
 		}
 	}
+	if q.getUserConversationsAfterStmt != nil {
+		if cerr := q.getUserConversationsAfterStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserConversationsAfterStmt: %w", cerr)
+		}
+	}
+	if q.getUserConversationsBeforeStmt != nil {
+		if cerr := q.getUserConversationsBeforeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserConversationsBeforeStmt: %w", cerr)
+		}
+	}
+	if q.getUserStorageQuotaStmt != nil {
+		if cerr := q.getUserStorageQuotaStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserStorageQuotaStmt: %w", cerr)
+		}
+	}
+	if q.getUserStorageUsageStmt != nil {
+		if cerr := q.getUserStorageUsageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserStorageUsageStmt: %w", cerr)
+		}
+	}
+	if q.getUserTOTPStmt != nil {
+		if cerr := q.getUserTOTPStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserTOTPStmt: %w", cerr)
+		}
+	}
+	if q.invalidatePasswordResetTokensStmt != nil {
+		if cerr := q.invalidatePasswordResetTokensStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing invalidatePasswordResetTokensStmt: %w", cerr)
+		}
+	}
+	if q.isSessionActiveStmt != nil {
+		if cerr := q.isSessionActiveStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing isSessionActiveStmt: %w", cerr)
+		}
+	}
+	if q.isStoragePathReferencedStmt != nil {
+		if cerr := q.isStoragePathReferencedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing isStoragePathReferencedStmt: %w", cerr)
+		}
+	}
+	if q.listActiveSessionsStmt != nil {
+		if cerr := q.listActiveSessionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listActiveSessionsStmt: %w", cerr)
+		}
+	}
+	if q.listBlobRefCountDriftStmt != nil {
+		if cerr := q.listBlobRefCountDriftStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listBlobRefCountDriftStmt: %w", cerr)
+		}
+	}
+	if q.listFilesAfterStmt != nil {
+		if cerr := q.listFilesAfterStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listFilesAfterStmt: %w", cerr)
+		}
+	}
+	if q.listOrphanedFilesStmt != nil {
+		if cerr := q.listOrphanedFilesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listOrphanedFilesStmt: %w", cerr)
+		}
+	}
+	if q.listPendingScansStmt != nil {
+		if cerr := q.listPendingScansStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listPendingScansStmt: %w", cerr)
+		}
+	}
+	if q.listUnreferencedBlobsStmt != nil {
+		if cerr := q.listUnreferencedBlobsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listUnreferencedBlobsStmt: %w", cerr)
+		}
+	}
+	if q.lockConversationPinsStmt != nil {
+		if cerr := q.lockConversationPinsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing lockConversationPinsStmt: %w", cerr)
+		}
+	}
+	if q.lockConversationStorageStmt != nil {
+		if cerr := q.lockConversationStorageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing lockConversationStorageStmt: %w", cerr)
+		}
+	}
+	if q.lockUserStorageStmt != nil {
+		if cerr := q.lockUserStorageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing lockUserStorageStmt: %w", cerr)
+		}
+	}
+	if q.markEmailVerifiedStmt != nil {
+		if cerr := q.markEmailVerifiedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markEmailVerifiedStmt: %w", cerr)
+		}
+	}
 	if q.markMessageReadStmt != nil {
 		if cerr := q.markMessageReadStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing markMessageReadStmt: %w", cerr)
 		}
 	}
+	if q.pinMessageStmt != nil {
+		if cerr := q.pinMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing pinMessageStmt: %w", cerr)
+		}
+	}
+	if q.releaseBlobStmt != nil {
+		if cerr := q.releaseBlobStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing releaseBlobStmt: %w", cerr)
+		}
+	}
+	if q.releaseUploadStmt != nil {
+		if cerr := q.releaseUploadStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing releaseUploadStmt: %w", cerr)
+		}
+	}
 	if q.removeConversationMemberStmt != nil {
 		if cerr := q.removeConversationMemberStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing removeConversationMemberStmt: %w", cerr)
 		}
 	}
+	if q.removeMessageReactionStmt != nil {
+		if cerr := q.removeMessageReactionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing removeMessageReactionStmt: %w", cerr)
+		}
+	}
 	if q.revokeAllUserRefreshTokensStmt != nil {
 		if cerr := q.revokeAllUserRefreshTokensStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing revokeAllUserRefreshTokensStmt: %w", cerr)
 		}
 	}
+	if q.revokeOtherSessionsStmt != nil {
+		if cerr := q.revokeOtherSessionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing revokeOtherSessionsStmt: %w", cerr)
+		}
+	}
 	if q.revokeRefreshTokenStmt != nil {
 		if cerr := q.revokeRefreshTokenStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing revokeRefreshTokenStmt: %w", cerr)
 		}
 	}
+	if q.revokeSessionStmt != nil {
+		if cerr := q.revokeSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing revokeSessionStmt: %w", cerr)
+		}
+	}
 	if q.rotateRefreshTokenStmt != nil {
 		if cerr := q.rotateRefreshTokenStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing rotateRefreshTokenStmt: %w", cerr)
@@ -288,16 +906,61 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing searchMessagesStmt: %w", cerr)
 		}
 	}
+	if q.searchMessagesAfterStmt != nil {
+		if cerr := q.searchMessagesAfterStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing searchMessagesAfterStmt: %w", cerr)
+		}
+	}
+	if q.setBlobRefCountStmt != nil {
+		if cerr := q.setBlobRefCountStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setBlobRefCountStmt: %w", cerr)
+		}
+	}
+	if q.setConversationStorageQuotaStmt != nil {
+		if cerr := q.setConversationStorageQuotaStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setConversationStorageQuotaStmt: %w", cerr)
+		}
+	}
+	if q.setFileImageInfoStmt != nil {
+		if cerr := q.setFileImageInfoStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setFileImageInfoStmt: %w", cerr)
+		}
+	}
+	if q.setFileScanResultStmt != nil {
+		if cerr := q.setFileScanResultStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setFileScanResultStmt: %w", cerr)
+		}
+	}
 	if q.setMemberRoleStmt != nil {
 		if cerr := q.setMemberRoleStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing setMemberRoleStmt: %w", cerr)
 		}
 	}
+	if q.setPendingUserTOTPStmt != nil {
+		if cerr := q.setPendingUserTOTPStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setPendingUserTOTPStmt: %w", cerr)
+		}
+	}
+	if q.setUserStorageQuotaStmt != nil {
+		if cerr := q.setUserStorageQuotaStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setUserStorageQuotaStmt: %w", cerr)
+		}
+	}
 	if q.softDeleteMessageStmt != nil {
 		if cerr := q.softDeleteMessageStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing softDeleteMessageStmt: %w", cerr)
 		}
 	}
+	if q.unfollowThreadStmt != nil {
+		if cerr := q.unfollowThreadStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing unfollowThreadStmt: %w", cerr)
+		}
+	}
+	if q.unpinMessageStmt != nil {
+		if cerr := q.unpinMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing unpinMessageStmt: %w", cerr)
+		}
+	}
 	if q.updateConversationNameStmt != nil {
 		if cerr := q.updateConversationNameStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateConversationNameStmt: %w", cerr)
@@ -308,6 +971,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing updateConversationTimestampStmt: %w", cerr)
 		}
 	}
+	if q.updateFilePathStmt != nil {
+		if cerr := q.updateFilePathStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateFilePathStmt: %w", cerr)
+		}
+	}
 	if q.updateLastReadStmt != nil {
 		if cerr := q.updateLastReadStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateLastReadStmt: %w", cerr)
@@ -328,6 +996,26 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing upsertMessageReceiptStmt: %w", cerr)
 		}
 	}
+	if q.useLoginChallengeStmt != nil {
+		if cerr := q.useLoginChallengeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing useLoginChallengeStmt: %w", cerr)
+		}
+	}
+	if q.usePasswordResetTokenStmt != nil {
+		if cerr := q.usePasswordResetTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing usePasswordResetTokenStmt: %w", cerr)
+		}
+	}
+	if q.useRecoveryCodeStmt != nil {
+		if cerr := q.useRecoveryCodeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing useRecoveryCodeStmt: %w", cerr)
+		}
+	}
+	if q.useTOTPStepStmt != nil {
+		if cerr := q.useTOTPStepStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing useTOTPStepStmt: %w", cerr)
+		}
+	}
 	if q.userExistsByEmailStmt != nil {
 		if cerr := q.userExistsByEmailStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing userExistsByEmailStmt: %w", cerr)
@@ -370,89 +1058,261 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db                              DBTX
-	tx                              *sql.Tx
-	addConversationMemberStmt       *sql.Stmt
-	createConversationStmt          *sql.Stmt
-	createFileStmt                  *sql.Stmt
-	createMessageStmt               *sql.Stmt
-	createRefreshTokenStmt          *sql.Stmt
-	createUserStmt                  *sql.Stmt
-	deleteConversationStmt          *sql.Stmt
-	deleteFileStmt                  *sql.Stmt
-	deleteUserStmt                  *sql.Stmt
-	editMessageStmt                 *sql.Stmt
-	getConversationByIDStmt         *sql.Stmt
-	getConversationMemberStmt       *sql.Stmt
-	getConversationMembersStmt      *sql.Stmt
-	getDirectConversationStmt       *sql.Stmt
-	getFileByIDStmt                 *sql.Stmt
-	getFirstAdminOrMemberStmt       *sql.Stmt
-	getMessageByIDStmt              *sql.Stmt
-	getMessageReceiptsStmt          *sql.Stmt
-	getMessagesByConversationStmt   *sql.Stmt
-	getRefreshTokenByHashStmt       *sql.Stmt
-	getUserByEmailStmt              *sql.Stmt
-	getUserByIDStmt                 *sql.Stmt
-	getUserConversationsStmt        *sql.Stmt
-	markMessageReadStmt             *sql.Stmt
-	removeConversationMemberStmt    *sql.Stmt
-	revokeAllUserRefreshTokensStmt  *sql.Stmt
-	revokeRefreshTokenStmt          *sql.Stmt
-	rotateRefreshTokenStmt          *sql.Stmt
-	searchMessagesStmt              *sql.Stmt
-	setMemberRoleStmt               *sql.Stmt
-	softDeleteMessageStmt           *sql.Stmt
-	updateConversationNameStmt      *sql.Stmt
-	updateConversationTimestampStmt *sql.Stmt
-	updateLastReadStmt              *sql.Stmt
-	updateUserStmt                  *sql.Stmt
-	updateUserPasswordStmt          *sql.Stmt
-	upsertMessageReceiptStmt        *sql.Stmt
-	userExistsByEmailStmt           *sql.Stmt
+	db                                  DBTX
+	tx                                  *sql.Tx
+	acquireBlobStmt                     *sql.Stmt
+	addBlobRefStmt                      *sql.Stmt
+	addConversationMemberStmt           *sql.Stmt
+	addMessageReactionStmt              *sql.Stmt
+	appendUploadPartStmt                *sql.Stmt
+	attachFileToConversationStmt        *sql.Stmt
+	attemptLoginChallengeStmt           *sql.Stmt
+	canAccessContentStmt                *sql.Stmt
+	canAccessFileStmt                   *sql.Stmt
+	claimUploadStmt                     *sql.Stmt
+	copyFileThumbnailsStmt              *sql.Stmt
+	countRecentPasswordResetTokensStmt  *sql.Stmt
+	countUnusedRecoveryCodesStmt        *sql.Stmt
+	createConversationStmt              *sql.Stmt
+	createFileStmt                      *sql.Stmt
+	createFileThumbnailStmt             *sql.Stmt
+	createLoginChallengeStmt            *sql.Stmt
+	createMessageStmt                   *sql.Stmt
+	createPasswordResetTokenStmt        *sql.Stmt
+	createRecoveryCodeStmt              *sql.Stmt
+	createRefreshTokenStmt              *sql.Stmt
+	createSecurityEventStmt             *sql.Stmt
+	createUploadStmt                    *sql.Stmt
+	createUserStmt                      *sql.Stmt
+	deleteConversationStmt              *sql.Stmt
+	deleteConversationStorageQuotaStmt  *sql.Stmt
+	deleteExpiredLoginChallengesStmt    *sql.Stmt
+	deleteExpiredUploadsStmt            *sql.Stmt
+	deleteFileStmt                      *sql.Stmt
+	deleteOrphanedFileStmt              *sql.Stmt
+	deleteRecoveryCodesStmt             *sql.Stmt
+	deleteUnreferencedBlobStmt          *sql.Stmt
+	deleteUploadStmt                    *sql.Stmt
+	deleteUserStmt                      *sql.Stmt
+	deleteUserStorageQuotaStmt          *sql.Stmt
+	deleteUserTOTPStmt                  *sql.Stmt
+	editMessageStmt                     *sql.Stmt
+	enableUserTOTPStmt                  *sql.Stmt
+	followThreadStmt                    *sql.Stmt
+	getAccessibleFileByPathStmt         *sql.Stmt
+	getAccessibleFileBySHA256Stmt       *sql.Stmt
+	getBlobStmt                         *sql.Stmt
+	getConversationByIDStmt             *sql.Stmt
+	getConversationMemberStmt           *sql.Stmt
+	getConversationMembersStmt          *sql.Stmt
+	getConversationStorageQuotaStmt     *sql.Stmt
+	getConversationStorageUsageStmt     *sql.Stmt
+	getDirectConversationStmt           *sql.Stmt
+	getFileByIDStmt                     *sql.Stmt
+	getFileThumbnailsStmt               *sql.Stmt
+	getFirstAdminOrMemberStmt           *sql.Stmt
+	getFollowedThreadsStmt              *sql.Stmt
+	getMessageByClientMsgIDStmt         *sql.Stmt
+	getMessageByIDStmt                  *sql.Stmt
+	getMessageReactionsStmt             *sql.Stmt
+	getMessageReceiptsStmt              *sql.Stmt
+	getMessagesByConversationStmt       *sql.Stmt
+	getMessagesByConversationAfterStmt  *sql.Stmt
+	getMessagesByConversationBeforeStmt *sql.Stmt
+	getMessagesForReplayStmt            *sql.Stmt
+	getPinnedMessageStmt                *sql.Stmt
+	getPinnedMessagesStmt               *sql.Stmt
+	getProcessedFileBySHA256Stmt        *sql.Stmt
+	getRefreshTokenByHashStmt           *sql.Stmt
+	getScanVerdictBySHA256Stmt          *sql.Stmt
+	getThreadFollowersStmt              *sql.Stmt
+	getThreadRepliesStmt                *sql.Stmt
+	getUploadStmt                       *sql.Stmt
+	getUserByEmailStmt                  *sql.Stmt
+	getUserByIDStmt                     *sql.Stmt
+	getUserConversationsStmt            *sql.Stmt
+	getUserConversationsAfterStmt       *sql.Stmt
+	getUserConversationsBeforeStmt      *sql.Stmt
+	getUserStorageQuotaStmt             *sql.Stmt
+	getUserStorageUsageStmt             *sql.Stmt
+	getUserTOTPStmt                     *sql.Stmt
+	invalidatePasswordResetTokensStmt   *sql.Stmt
+	isSessionActiveStmt                 *sql.Stmt
+	isStoragePathReferencedStmt         *sql.Stmt
+	listActiveSessionsStmt              *sql.Stmt
+	listBlobRefCountDriftStmt           *sql.Stmt
+	listFilesAfterStmt                  *sql.Stmt
+	listOrphanedFilesStmt               *sql.Stmt
+	listPendingScansStmt                *sql.Stmt
+	listUnreferencedBlobsStmt           *sql.Stmt
+	lockConversationPinsStmt            *sql.Stmt
+	lockConversationStorageStmt         *sql.Stmt
+	lockUserStorageStmt                 *sql.Stmt
+	markEmailVerifiedStmt               *sql.Stmt
+	markMessageReadStmt                 *sql.Stmt
+	pinMessageStmt                      *sql.Stmt
+	releaseBlobStmt                     *sql.Stmt
+	releaseUploadStmt                   *sql.Stmt
+	removeConversationMemberStmt        *sql.Stmt
+	removeMessageReactionStmt           *sql.Stmt
+	revokeAllUserRefreshTokensStmt      *sql.Stmt
+	revokeOtherSessionsStmt             *sql.Stmt
+	revokeRefreshTokenStmt              *sql.Stmt
+	revokeSessionStmt                   *sql.Stmt
+	rotateRefreshTokenStmt              *sql.Stmt
+	searchMessagesStmt                  *sql.Stmt
+	searchMessagesAfterStmt             *sql.Stmt
+	setBlobRefCountStmt                 *sql.Stmt
+	setConversationStorageQuotaStmt     *sql.Stmt
+	setFileImageInfoStmt                *sql.Stmt
+	setFileScanResultStmt               *sql.Stmt
+	setMemberRoleStmt                   *sql.Stmt
+	setPendingUserTOTPStmt              *sql.Stmt
+	setUserStorageQuotaStmt             *sql.Stmt
+	softDeleteMessageStmt               *sql.Stmt
+	unfollowThreadStmt                  *sql.Stmt
+	unpinMessageStmt                    *sql.Stmt
+	updateConversationNameStmt          *sql.Stmt
+	updateConversationTimestampStmt     *sql.Stmt
+	updateFilePathStmt                  *sql.Stmt
+	updateLastReadStmt                  *sql.Stmt
+	updateUserStmt                      *sql.Stmt
+	updateUserPasswordStmt              *sql.Stmt
+	upsertMessageReceiptStmt            *sql.Stmt
+	useLoginChallengeStmt               *sql.Stmt
+	usePasswordResetTokenStmt           *sql.Stmt
+	useRecoveryCodeStmt                 *sql.Stmt
+	useTOTPStepStmt                     *sql.Stmt
+	userExistsByEmailStmt               *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:                              tx,
-		tx:                              tx,
-		addConversationMemberStmt:       q.addConversationMemberStmt,
-		createConversationStmt:          q.createConversationStmt,
-		createFileStmt:                  q.createFileStmt,
-		createMessageStmt:               q.createMessageStmt,
-		createRefreshTokenStmt:          q.createRefreshTokenStmt,
-		createUserStmt:                  q.createUserStmt,
-		deleteConversationStmt:          q.deleteConversationStmt,
-		deleteFileStmt:                  q.deleteFileStmt,
-		deleteUserStmt:                  q.deleteUserStmt,
-		editMessageStmt:                 q.editMessageStmt,
-		getConversationByIDStmt:         q.getConversationByIDStmt,
-		getConversationMemberStmt:       q.getConversationMemberStmt,
-		getConversationMembersStmt:      q.getConversationMembersStmt,
-		getDirectConversationStmt:       q.getDirectConversationStmt,
-		getFileByIDStmt:                 q.getFileByIDStmt,
-		getFirstAdminOrMemberStmt:       q.getFirstAdminOrMemberStmt,
-		getMessageByIDStmt:              q.getMessageByIDStmt,
-		getMessageReceiptsStmt:          q.getMessageReceiptsStmt,
-		getMessagesByConversationStmt:   q.getMessagesByConversationStmt,
-		getRefreshTokenByHashStmt:       q.getRefreshTokenByHashStmt,
-		getUserByEmailStmt:              q.getUserByEmailStmt,
-		getUserByIDStmt:                 q.getUserByIDStmt,
-		getUserConversationsStmt:        q.getUserConversationsStmt,
-		markMessageReadStmt:             q.markMessageReadStmt,
-		removeConversationMemberStmt:    q.removeConversationMemberStmt,
-		revokeAllUserRefreshTokensStmt:  q.revokeAllUserRefreshTokensStmt,
-		revokeRefreshTokenStmt:          q.revokeRefreshTokenStmt,
-		rotateRefreshTokenStmt:          q.rotateRefreshTokenStmt,
-		searchMessagesStmt:              q.searchMessagesStmt,
-		setMemberRoleStmt:               q.setMemberRoleStmt,
-		softDeleteMessageStmt:           q.softDeleteMessageStmt,
-		updateConversationNameStmt:      q.updateConversationNameStmt,
-		updateConversationTimestampStmt: q.updateConversationTimestampStmt,
-		updateLastReadStmt:              q.updateLastReadStmt,
-		updateUserStmt:                  q.updateUserStmt,
-		updateUserPasswordStmt:          q.updateUserPasswordStmt,
-		upsertMessageReceiptStmt:        q.upsertMessageReceiptStmt,
-		userExistsByEmailStmt:           q.userExistsByEmailStmt,
+		db:                                  tx,
+		tx:                                  tx,
+		acquireBlobStmt:                     q.acquireBlobStmt,
+		addBlobRefStmt:                      q.addBlobRefStmt,
+		addConversationMemberStmt:           q.addConversationMemberStmt,
+		addMessageReactionStmt:              q.addMessageReactionStmt,
+		appendUploadPartStmt:                q.appendUploadPartStmt,
+		attachFileToConversationStmt:        q.attachFileToConversationStmt,
+		attemptLoginChallengeStmt:           q.attemptLoginChallengeStmt,
+		canAccessContentStmt:                q.canAccessContentStmt,
+		canAccessFileStmt:                   q.canAccessFileStmt,
+		claimUploadStmt:                     q.claimUploadStmt,
+		copyFileThumbnailsStmt:              q.copyFileThumbnailsStmt,
+		countRecentPasswordResetTokensStmt:  q.countRecentPasswordResetTokensStmt,
+		countUnusedRecoveryCodesStmt:        q.countUnusedRecoveryCodesStmt,
+		createConversationStmt:              q.createConversationStmt,
+		createFileStmt:                      q.createFileStmt,
+		createFileThumbnailStmt:             q.createFileThumbnailStmt,
+		createLoginChallengeStmt:            q.createLoginChallengeStmt,
+		createMessageStmt:                   q.createMessageStmt,
+		createPasswordResetTokenStmt:        q.createPasswordResetTokenStmt,
+		createRecoveryCodeStmt:              q.createRecoveryCodeStmt,
+		createRefreshTokenStmt:              q.createRefreshTokenStmt,
+		createSecurityEventStmt:             q.createSecurityEventStmt,
+		createUploadStmt:                    q.createUploadStmt,
+		createUserStmt:                      q.createUserStmt,
+		deleteConversationStmt:              q.deleteConversationStmt,
+		deleteConversationStorageQuotaStmt:  q.deleteConversationStorageQuotaStmt,
+		deleteExpiredLoginChallengesStmt:    q.deleteExpiredLoginChallengesStmt,
+		deleteExpiredUploadsStmt:            q.deleteExpiredUploadsStmt,
+		deleteFileStmt:                      q.deleteFileStmt,
+		deleteOrphanedFileStmt:              q.deleteOrphanedFileStmt,
+		deleteRecoveryCodesStmt:             q.deleteRecoveryCodesStmt,
+		deleteUnreferencedBlobStmt:          q.deleteUnreferencedBlobStmt,
+		deleteUploadStmt:                    q.deleteUploadStmt,
+		deleteUserStmt:                      q.deleteUserStmt,
+		deleteUserStorageQuotaStmt:          q.deleteUserStorageQuotaStmt,
+		deleteUserTOTPStmt:                  q.deleteUserTOTPStmt,
+		editMessageStmt:                     q.editMessageStmt,
+		enableUserTOTPStmt:                  q.enableUserTOTPStmt,
+		followThreadStmt:                    q.followThreadStmt,
+		getAccessibleFileByPathStmt:         q.getAccessibleFileByPathStmt,
+		getAccessibleFileBySHA256Stmt:       q.getAccessibleFileBySHA256Stmt,
+		getBlobStmt:                         q.getBlobStmt,
+		getConversationByIDStmt:             q.getConversationByIDStmt,
+		getConversationMemberStmt:           q.getConversationMemberStmt,
+		getConversationMembersStmt:          q.getConversationMembersStmt,
+		getConversationStorageQuotaStmt:     q.getConversationStorageQuotaStmt,
+		getConversationStorageUsageStmt:     q.getConversationStorageUsageStmt,
+		getDirectConversationStmt:           q.getDirectConversationStmt,
+		getFileByIDStmt:                     q.getFileByIDStmt,
+		getFileThumbnailsStmt:               q.getFileThumbnailsStmt,
+		getFirstAdminOrMemberStmt:           q.getFirstAdminOrMemberStmt,
+		getFollowedThreadsStmt:              q.getFollowedThreadsStmt,
+		getMessageByClientMsgIDStmt:         q.getMessageByClientMsgIDStmt,
+		getMessageByIDStmt:                  q.getMessageByIDStmt,
+		getMessageReactionsStmt:             q.getMessageReactionsStmt,
+		getMessageReceiptsStmt:              q.getMessageReceiptsStmt,
+		getMessagesByConversationStmt:       q.getMessagesByConversationStmt,
+		getMessagesByConversationAfterStmt:  q.getMessagesByConversationAfterStmt,
+		getMessagesByConversationBeforeStmt: q.getMessagesByConversationBeforeStmt,
+		getMessagesForReplayStmt:            q.getMessagesForReplayStmt,
+		getPinnedMessageStmt:                q.getPinnedMessageStmt,
+		getPinnedMessagesStmt:               q.getPinnedMessagesStmt,
+		getProcessedFileBySHA256Stmt:        q.getProcessedFileBySHA256Stmt,
+		getRefreshTokenByHashStmt:           q.getRefreshTokenByHashStmt,
+		getScanVerdictBySHA256Stmt:          q.getScanVerdictBySHA256Stmt,
+		getThreadFollowersStmt:              q.getThreadFollowersStmt,
+		getThreadRepliesStmt:                q.getThreadRepliesStmt,
+		getUploadStmt:                       q.getUploadStmt,
+		getUserByEmailStmt:                  q.getUserByEmailStmt,
+		getUserByIDStmt:                     q.getUserByIDStmt,
+		getUserConversationsStmt:            q.getUserConversationsStmt,
+		getUserConversationsAfterStmt:       q.getUserConversationsAfterStmt,
+		getUserConversationsBeforeStmt:      q.getUserConversationsBeforeStmt,
+		getUserStorageQuotaStmt:             q.getUserStorageQuotaStmt,
+		getUserStorageUsageStmt:             q.getUserStorageUsageStmt,
+		getUserTOTPStmt:                     q.getUserTOTPStmt,
+		invalidatePasswordResetTokensStmt:   q.invalidatePasswordResetTokensStmt,
+		isSessionActiveStmt:                 q.isSessionActiveStmt,
+		isStoragePathReferencedStmt:         q.isStoragePathReferencedStmt,
+		listActiveSessionsStmt:              q.listActiveSessionsStmt,
+		listBlobRefCountDriftStmt:           q.listBlobRefCountDriftStmt,
+		listFilesAfterStmt:                  q.listFilesAfterStmt,
+		listOrphanedFilesStmt:               q.listOrphanedFilesStmt,
+		listPendingScansStmt:                q.listPendingScansStmt,
+		listUnreferencedBlobsStmt:           q.listUnreferencedBlobsStmt,
+		lockConversationPinsStmt:            q.lockConversationPinsStmt,
+		lockConversationStorageStmt:         q.lockConversationStorageStmt,
+		lockUserStorageStmt:                 q.lockUserStorageStmt,
+		markEmailVerifiedStmt:               q.markEmailVerifiedStmt,
+		markMessageReadStmt:                 q.markMessageReadStmt,
+		pinMessageStmt:                      q.pinMessageStmt,
+		releaseBlobStmt:                     q.releaseBlobStmt,
+		releaseUploadStmt:                   q.releaseUploadStmt,
+		removeConversationMemberStmt:        q.removeConversationMemberStmt,
+		removeMessageReactionStmt:           q.removeMessageReactionStmt,
+		revokeAllUserRefreshTokensStmt:      q.revokeAllUserRefreshTokensStmt,
+		revokeOtherSessionsStmt:             q.revokeOtherSessionsStmt,
+		revokeRefreshTokenStmt:              q.revokeRefreshTokenStmt,
+		revokeSessionStmt:                   q.revokeSessionStmt,
+		rotateRefreshTokenStmt:              q.rotateRefreshTokenStmt,
+		searchMessagesStmt:                  q.searchMessagesStmt,
+		searchMessagesAfterStmt:             q.searchMessagesAfterStmt,
+		setBlobRefCountStmt:                 q.setBlobRefCountStmt,
+		setConversationStorageQuotaStmt:     q.setConversationStorageQuotaStmt,
+		setFileImageInfoStmt:                q.setFileImageInfoStmt,
+		setFileScanResultStmt:               q.setFileScanResultStmt,
+		setMemberRoleStmt:                   q.setMemberRoleStmt,
+		setPendingUserTOTPStmt:              q.setPendingUserTOTPStmt,
+		setUserStorageQuotaStmt:             q.setUserStorageQuotaStmt,
+		softDeleteMessageStmt:               q.softDeleteMessageStmt,
+		unfollowThreadStmt:                  q.unfollowThreadStmt,
+		unpinMessageStmt:                    q.unpinMessageStmt,
+		updateConversationNameStmt:          q.updateConversationNameStmt,
+		updateConversationTimestampStmt:     q.updateConversationTimestampStmt,
+		updateFilePathStmt:                  q.updateFilePathStmt,
+		updateLastReadStmt:                  q.updateLastReadStmt,
+		updateUserStmt:                      q.updateUserStmt,
+		updateUserPasswordStmt:              q.updateUserPasswordStmt,
+		upsertMessageReceiptStmt:            q.upsertMessageReceiptStmt,
+		useLoginChallengeStmt:               q.useLoginChallengeStmt,
+		usePasswordResetTokenStmt:           q.usePasswordResetTokenStmt,
+		useRecoveryCodeStmt:                 q.useRecoveryCodeStmt,
+		useTOTPStepStmt:                     q.useTOTPStepStmt,
+		userExistsByEmailStmt:               q.userExistsByEmailStmt,
 	}
 }
@@ -7,22 +7,124 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const canAccessContent = `-- name: CanAccessContent :one
+SELECT EXISTS (
+    SELECT 1 FROM files f
+    WHERE f.sha256 = $1 AND (
+        f.uploader_id = $2 OR EXISTS (
+            SELECT 1 FROM messages m
+            JOIN conversation_members cm ON cm.conversation_id = m.conversation_id
+            WHERE m.file_id = f.id AND cm.user_id = $2 AND m.deleted_at IS NULL
+        )
+    )
+)::boolean AS can_access
+`
+
+type CanAccessContentParams struct {
+	Sha256 sql.NullString `db:"sha256" json:"sha256"`
+	UserID uuid.UUID      `db:"user_id" json:"user_id"`
+}
+
+// like CanAccessFile, but through any file holding the same bytes
+func (q *Queries) CanAccessContent(ctx context.Context, arg CanAccessContentParams) (bool, error) {
+	row := q.queryRow(ctx, q.canAccessContentStmt, canAccessContent, arg.Sha256, arg.UserID)
+	var can_access bool
+	err := row.Scan(&can_access)
+	return can_access, err
+}
+
+const canAccessFile = `-- name: CanAccessFile :one
+SELECT (
+    EXISTS (
+        SELECT 1 FROM files f
+        WHERE f.id = $1 AND f.uploader_id = $2
+    ) OR EXISTS (
+        SELECT 1 FROM messages m
+        JOIN conversation_members cm ON cm.conversation_id = m.conversation_id
+        WHERE m.file_id = $1 AND cm.user_id = $2 AND m.deleted_at IS NULL
+    )
+)::boolean AS can_access
+`
+
+type CanAccessFileParams struct {
+	FileID uuid.UUID `db:"file_id" json:"file_id"`
+	UserID uuid.UUID `db:"user_id" json:"user_id"`
+}
+
+// the uploader, and members of any conversation with a message carrying the file
+func (q *Queries) CanAccessFile(ctx context.Context, arg CanAccessFileParams) (bool, error) {
+	row := q.queryRow(ctx, q.canAccessFileStmt, canAccessFile, arg.FileID, arg.UserID)
+	var can_access bool
+	err := row.Scan(&can_access)
+	return can_access, err
+}
+
+const copyFileThumbnails = `-- name: CopyFileThumbnails :many
+INSERT INTO file_thumbnails (file_id, size, width, height, mime_type, path)
+SELECT $1, size, width, height, mime_type, path
+FROM file_thumbnails
+WHERE file_id = $2
+RETURNING file_id, size, width, height, mime_type, path
+`
+
+type CopyFileThumbnailsParams struct {
+	ToFileID   uuid.UUID `db:"to_file_id" json:"to_file_id"`
+	FromFileID uuid.UUID `db:"from_file_id" json:"from_file_id"`
+}
+
+func (q *Queries) CopyFileThumbnails(ctx context.Context, arg CopyFileThumbnailsParams) ([]FileThumbnail, error) {
+	rows, err := q.query(ctx, q.copyFileThumbnailsStmt, copyFileThumbnails, arg.ToFileID, arg.FromFileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FileThumbnail
+	for rows.Next() {
+		var i FileThumbnail
+		if err := rows.Scan(
+			&i.FileID,
+			&i.Size,
+			&i.Width,
+			&i.Height,
+			&i.MimeType,
+			&i.Path,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createFile = `-- name: CreateFile :one
-INSERT INTO files (uploader_id, name, mime_type, size, path)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING id, uploader_id, name, mime_type, size, path, created_at
+INSERT INTO files (uploader_id, name, mime_type, size, path, original_path, sha256, conversation_id, scan_status, upload_sha256)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id, uploader_id, name, mime_type, size, path, created_at, width, height, blurhash, original_path, sha256, upload_sha256, conversation_id, scan_status, scan_signature, scanned_at
 `
 
 type CreateFileParams struct {
-	UploaderID uuid.UUID `db:"uploader_id" json:"uploader_id"`
-	Name       string    `db:"name" json:"name"`
-	MimeType   string    `db:"mime_type" json:"mime_type"`
-	Size       int64     `db:"size" json:"size"`
-	Path       string    `db:"path" json:"path"`
+	UploaderID     uuid.UUID      `db:"uploader_id" json:"uploader_id"`
+	Name           string         `db:"name" json:"name"`
+	MimeType       string         `db:"mime_type" json:"mime_type"`
+	Size           int64          `db:"size" json:"size"`
+	Path           string         `db:"path" json:"path"`
+	OriginalPath   sql.NullString `db:"original_path" json:"original_path"`
+	Sha256         sql.NullString `db:"sha256" json:"sha256"`
+	ConversationID uuid.NullUUID  `db:"conversation_id" json:"conversation_id"`
+	ScanStatus     FileScanStatus `db:"scan_status" json:"scan_status"`
+	UploadSha256   sql.NullString `db:"upload_sha256" json:"upload_sha256"`
 }
 
 func (q *Queries) CreateFile(ctx context.Context, arg CreateFileParams) (File, error) {
@@ -32,6 +134,11 @@ func (q *Queries) CreateFile(ctx context.Context, arg CreateFileParams) (File, e
 		arg.MimeType,
 		arg.Size,
 		arg.Path,
+		arg.OriginalPath,
+		arg.Sha256,
+		arg.ConversationID,
+		arg.ScanStatus,
+		arg.UploadSha256,
 	)
 	var i File
 	err := row.Scan(
@@ -42,6 +149,52 @@ func (q *Queries) CreateFile(ctx context.Context, arg CreateFileParams) (File, e
 		&i.Size,
 		&i.Path,
 		&i.CreatedAt,
+		&i.Width,
+		&i.Height,
+		&i.Blurhash,
+		&i.OriginalPath,
+		&i.Sha256,
+		&i.UploadSha256,
+		&i.ConversationID,
+		&i.ScanStatus,
+		&i.ScanSignature,
+		&i.ScannedAt,
+	)
+	return i, err
+}
+
+const createFileThumbnail = `-- name: CreateFileThumbnail :one
+INSERT INTO file_thumbnails (file_id, size, width, height, mime_type, path)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING file_id, size, width, height, mime_type, path
+`
+
+type CreateFileThumbnailParams struct {
+	FileID   uuid.UUID `db:"file_id" json:"file_id"`
+	Size     int32     `db:"size" json:"size"`
+	Width    int32     `db:"width" json:"width"`
+	Height   int32     `db:"height" json:"height"`
+	MimeType string    `db:"mime_type" json:"mime_type"`
+	Path     string    `db:"path" json:"path"`
+}
+
+func (q *Queries) CreateFileThumbnail(ctx context.Context, arg CreateFileThumbnailParams) (FileThumbnail, error) {
+	row := q.queryRow(ctx, q.createFileThumbnailStmt, createFileThumbnail,
+		arg.FileID,
+		arg.Size,
+		arg.Width,
+		arg.Height,
+		arg.MimeType,
+		arg.Path,
+	)
+	var i FileThumbnail
+	err := row.Scan(
+		&i.FileID,
+		&i.Size,
+		&i.Width,
+		&i.Height,
+		&i.MimeType,
+		&i.Path,
 	)
 	return i, err
 }
@@ -60,8 +213,144 @@ func (q *Queries) DeleteFile(ctx context.Context, arg DeleteFileParams) error {
 	return err
 }
 
+const deleteOrphanedFile = `-- name: DeleteOrphanedFile :one
+DELETE FROM files f
+WHERE f.id = $1
+  AND NOT EXISTS (
+      SELECT 1 FROM messages m
+      WHERE m.file_id = f.id AND (m.deleted_at IS NULL OR m.deleted_at >= $2)
+  )
+RETURNING id, uploader_id, name, mime_type, size, path, created_at, width, height, blurhash, original_path, sha256, upload_sha256, conversation_id, scan_status, scan_signature, scanned_at
+`
+
+type DeleteOrphanedFileParams struct {
+	ID     uuid.UUID `db:"id" json:"id"`
+	Cutoff time.Time `db:"cutoff" json:"cutoff"`
+}
+
+// rechecks, as the file may have been attached since it was listed
+func (q *Queries) DeleteOrphanedFile(ctx context.Context, arg DeleteOrphanedFileParams) (File, error) {
+	row := q.queryRow(ctx, q.deleteOrphanedFileStmt, deleteOrphanedFile, arg.ID, arg.Cutoff)
+	var i File
+	err := row.Scan(
+		&i.ID,
+		&i.UploaderID,
+		&i.Name,
+		&i.MimeType,
+		&i.Size,
+		&i.Path,
+		&i.CreatedAt,
+		&i.Width,
+		&i.Height,
+		&i.Blurhash,
+		&i.OriginalPath,
+		&i.Sha256,
+		&i.UploadSha256,
+		&i.ConversationID,
+		&i.ScanStatus,
+		&i.ScanSignature,
+		&i.ScannedAt,
+	)
+	return i, err
+}
+
+const getAccessibleFileByPath = `-- name: GetAccessibleFileByPath :one
+SELECT f.id, f.uploader_id, f.name, f.mime_type, f.size, f.path, f.created_at, f.width, f.height, f.blurhash, f.original_path, f.sha256, f.upload_sha256, f.conversation_id, f.scan_status, f.scan_signature, f.scanned_at FROM files f
+WHERE (
+    f.path = $1 OR f.original_path = $1 OR EXISTS (
+        SELECT 1 FROM file_thumbnails t
+        WHERE t.file_id = f.id AND t.path = $1
+    )
+) AND (
+    f.uploader_id = $2 OR EXISTS (
+        SELECT 1 FROM messages m
+        JOIN conversation_members cm ON cm.conversation_id = m.conversation_id
+        WHERE m.file_id = f.id AND cm.user_id = $2 AND m.deleted_at IS NULL
+    )
+)
+ORDER BY f.uploader_id = $2 DESC, f.created_at
+LIMIT 1
+`
+
+type GetAccessibleFileByPathParams struct {
+	Path   string    `db:"path" json:"path"`
+	UserID uuid.UUID `db:"user_id" json:"user_id"`
+}
+
+// deduplicated files share paths, so of the files holding path as their
+// content, original or a thumbnail, the caller's own comes first, then the
+// earliest shared with them
+func (q *Queries) GetAccessibleFileByPath(ctx context.Context, arg GetAccessibleFileByPathParams) (File, error) {
+	row := q.queryRow(ctx, q.getAccessibleFileByPathStmt, getAccessibleFileByPath, arg.Path, arg.UserID)
+	var i File
+	err := row.Scan(
+		&i.ID,
+		&i.UploaderID,
+		&i.Name,
+		&i.MimeType,
+		&i.Size,
+		&i.Path,
+		&i.CreatedAt,
+		&i.Width,
+		&i.Height,
+		&i.Blurhash,
+		&i.OriginalPath,
+		&i.Sha256,
+		&i.UploadSha256,
+		&i.ConversationID,
+		&i.ScanStatus,
+		&i.ScanSignature,
+		&i.ScannedAt,
+	)
+	return i, err
+}
+
+const getAccessibleFileBySHA256 = `-- name: GetAccessibleFileBySHA256 :one
+SELECT f.id, f.uploader_id, f.name, f.mime_type, f.size, f.path, f.created_at, f.width, f.height, f.blurhash, f.original_path, f.sha256, f.upload_sha256, f.conversation_id, f.scan_status, f.scan_signature, f.scanned_at FROM files f
+WHERE (f.sha256 = $1 OR f.upload_sha256 = $1) AND (
+    f.uploader_id = $2 OR EXISTS (
+        SELECT 1 FROM messages m
+        JOIN conversation_members cm ON cm.conversation_id = m.conversation_id
+        WHERE m.file_id = f.id AND cm.user_id = $2 AND m.deleted_at IS NULL
+    )
+)
+ORDER BY f.created_at
+LIMIT 1
+`
+
+type GetAccessibleFileBySHA256Params struct {
+	Sha256 sql.NullString `db:"sha256" json:"sha256"`
+	UserID uuid.UUID      `db:"user_id" json:"user_id"`
+}
+
+// an image matches the hash of its stored content or of the bytes uploaded
+func (q *Queries) GetAccessibleFileBySHA256(ctx context.Context, arg GetAccessibleFileBySHA256Params) (File, error) {
+	row := q.queryRow(ctx, q.getAccessibleFileBySHA256Stmt, getAccessibleFileBySHA256, arg.Sha256, arg.UserID)
+	var i File
+	err := row.Scan(
+		&i.ID,
+		&i.UploaderID,
+		&i.Name,
+		&i.MimeType,
+		&i.Size,
+		&i.Path,
+		&i.CreatedAt,
+		&i.Width,
+		&i.Height,
+		&i.Blurhash,
+		&i.OriginalPath,
+		&i.Sha256,
+		&i.UploadSha256,
+		&i.ConversationID,
+		&i.ScanStatus,
+		&i.ScanSignature,
+		&i.ScannedAt,
+	)
+	return i, err
+}
+
 const getFileByID = `-- name: GetFileByID :one
-SELECT id, uploader_id, name, mime_type, size, path, created_at FROM files WHERE id = $1
+SELECT id, uploader_id, name, mime_type, size, path, created_at, width, height, blurhash, original_path, sha256, upload_sha256, conversation_id, scan_status, scan_signature, scanned_at FROM files WHERE id = $1
 `
 
 func (q *Queries) GetFileByID(ctx context.Context, id uuid.UUID) (File, error) {
@@ -75,6 +364,394 @@ func (q *Queries) GetFileByID(ctx context.Context, id uuid.UUID) (File, error) {
 		&i.Size,
 		&i.Path,
 		&i.CreatedAt,
+		&i.Width,
+		&i.Height,
+		&i.Blurhash,
+		&i.OriginalPath,
+		&i.Sha256,
+		&i.UploadSha256,
+		&i.ConversationID,
+		&i.ScanStatus,
+		&i.ScanSignature,
+		&i.ScannedAt,
+	)
+	return i, err
+}
+
+const getFileThumbnails = `-- name: GetFileThumbnails :many
+SELECT file_id, size, width, height, mime_type, path FROM file_thumbnails
+WHERE file_id = $1
+ORDER BY size
+`
+
+func (q *Queries) GetFileThumbnails(ctx context.Context, fileID uuid.UUID) ([]FileThumbnail, error) {
+	rows, err := q.query(ctx, q.getFileThumbnailsStmt, getFileThumbnails, fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FileThumbnail
+	for rows.Next() {
+		var i FileThumbnail
+		if err := rows.Scan(
+			&i.FileID,
+			&i.Size,
+			&i.Width,
+			&i.Height,
+			&i.MimeType,
+			&i.Path,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getProcessedFileBySHA256 = `-- name: GetProcessedFileBySHA256 :one
+SELECT id, uploader_id, name, mime_type, size, path, created_at, width, height, blurhash, original_path, sha256, upload_sha256, conversation_id, scan_status, scan_signature, scanned_at FROM files
+WHERE sha256 = $1 AND id <> $2 AND width IS NOT NULL
+ORDER BY created_at
+LIMIT 1
+`
+
+type GetProcessedFileBySHA256Params struct {
+	Sha256 sql.NullString `db:"sha256" json:"sha256"`
+	ID     uuid.UUID      `db:"id" json:"id"`
+}
+
+// another file with the same content whose image info is already worked out
+func (q *Queries) GetProcessedFileBySHA256(ctx context.Context, arg GetProcessedFileBySHA256Params) (File, error) {
+	row := q.queryRow(ctx, q.getProcessedFileBySHA256Stmt, getProcessedFileBySHA256, arg.Sha256, arg.ID)
+	var i File
+	err := row.Scan(
+		&i.ID,
+		&i.UploaderID,
+		&i.Name,
+		&i.MimeType,
+		&i.Size,
+		&i.Path,
+		&i.CreatedAt,
+		&i.Width,
+		&i.Height,
+		&i.Blurhash,
+		&i.OriginalPath,
+		&i.Sha256,
+		&i.UploadSha256,
+		&i.ConversationID,
+		&i.ScanStatus,
+		&i.ScanSignature,
+		&i.ScannedAt,
 	)
 	return i, err
 }
+
+const getScanVerdictBySHA256 = `-- name: GetScanVerdictBySHA256 :one
+SELECT scan_status, scan_signature FROM files
+WHERE sha256 = $1 AND id <> $2 AND scan_status IN ('clean', 'infected')
+ORDER BY scanned_at DESC
+LIMIT 1
+`
+
+type GetScanVerdictBySHA256Params struct {
+	Sha256 sql.NullString `db:"sha256" json:"sha256"`
+	ID     uuid.UUID      `db:"id" json:"id"`
+}
+
+type GetScanVerdictBySHA256Row struct {
+	ScanStatus    FileScanStatus `db:"scan_status" json:"scan_status"`
+	ScanSignature sql.NullString `db:"scan_signature" json:"scan_signature"`
+}
+
+// identical content already scanned under another file
+func (q *Queries) GetScanVerdictBySHA256(ctx context.Context, arg GetScanVerdictBySHA256Params) (GetScanVerdictBySHA256Row, error) {
+	row := q.queryRow(ctx, q.getScanVerdictBySHA256Stmt, getScanVerdictBySHA256, arg.Sha256, arg.ID)
+	var i GetScanVerdictBySHA256Row
+	err := row.Scan(&i.ScanStatus, &i.ScanSignature)
+	return i, err
+}
+
+const isStoragePathReferenced = `-- name: IsStoragePathReferenced :one
+SELECT (
+    EXISTS (SELECT 1 FROM files WHERE path = $1 OR original_path = $1)
+    OR EXISTS (SELECT 1 FROM file_thumbnails WHERE path = $1)
+    OR EXISTS (SELECT 1 FROM blobs WHERE path = $1)
+    OR EXISTS (SELECT 1 FROM uploads WHERE $1 = ANY(part_paths))
+)::boolean AS referenced
+`
+
+// whether any row still points at a stored object
+func (q *Queries) IsStoragePathReferenced(ctx context.Context, path string) (bool, error) {
+	row := q.queryRow(ctx, q.isStoragePathReferencedStmt, isStoragePathReferenced, path)
+	var referenced bool
+	err := row.Scan(&referenced)
+	return referenced, err
+}
+
+const listFilesAfter = `-- name: ListFilesAfter :many
+SELECT id, uploader_id, name, mime_type, size, path, created_at, width, height, blurhash, original_path, sha256, upload_sha256, conversation_id, scan_status, scan_signature, scanned_at FROM files
+WHERE id > $1
+ORDER BY id
+LIMIT $2
+`
+
+type ListFilesAfterParams struct {
+	ID    uuid.UUID `db:"id" json:"id"`
+	Limit int32     `db:"limit" json:"limit"`
+}
+
+func (q *Queries) ListFilesAfter(ctx context.Context, arg ListFilesAfterParams) ([]File, error) {
+	rows, err := q.query(ctx, q.listFilesAfterStmt, listFilesAfter, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []File
+	for rows.Next() {
+		var i File
+		if err := rows.Scan(
+			&i.ID,
+			&i.UploaderID,
+			&i.Name,
+			&i.MimeType,
+			&i.Size,
+			&i.Path,
+			&i.CreatedAt,
+			&i.Width,
+			&i.Height,
+			&i.Blurhash,
+			&i.OriginalPath,
+			&i.Sha256,
+			&i.UploadSha256,
+			&i.ConversationID,
+			&i.ScanStatus,
+			&i.ScanSignature,
+			&i.ScannedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrphanedFiles = `-- name: ListOrphanedFiles :many
+SELECT f.id, f.uploader_id, f.name, f.mime_type, f.size, f.path, f.created_at, f.width, f.height, f.blurhash, f.original_path, f.sha256, f.upload_sha256, f.conversation_id, f.scan_status, f.scan_signature, f.scanned_at FROM files f
+WHERE f.created_at < $1 AND f.id > $2
+  AND NOT EXISTS (
+      SELECT 1 FROM messages m
+      WHERE m.file_id = f.id AND (m.deleted_at IS NULL OR m.deleted_at >= $1)
+  )
+ORDER BY f.id
+LIMIT $3
+`
+
+type ListOrphanedFilesParams struct {
+	Cutoff  time.Time `db:"cutoff" json:"cutoff"`
+	AfterID uuid.UUID `db:"after_id" json:"after_id"`
+	MaxRows int32     `db:"max_rows" json:"max_rows"`
+}
+
+// files older than cutoff that no message carries, apart from messages
+// deleted before cutoff
+func (q *Queries) ListOrphanedFiles(ctx context.Context, arg ListOrphanedFilesParams) ([]File, error) {
+	rows, err := q.query(ctx, q.listOrphanedFilesStmt, listOrphanedFiles, arg.Cutoff, arg.AfterID, arg.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []File
+	for rows.Next() {
+		var i File
+		if err := rows.Scan(
+			&i.ID,
+			&i.UploaderID,
+			&i.Name,
+			&i.MimeType,
+			&i.Size,
+			&i.Path,
+			&i.CreatedAt,
+			&i.Width,
+			&i.Height,
+			&i.Blurhash,
+			&i.OriginalPath,
+			&i.Sha256,
+			&i.UploadSha256,
+			&i.ConversationID,
+			&i.ScanStatus,
+			&i.ScanSignature,
+			&i.ScannedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPendingScans = `-- name: ListPendingScans :many
+SELECT id, uploader_id, name, mime_type, size, path, created_at, width, height, blurhash, original_path, sha256, upload_sha256, conversation_id, scan_status, scan_signature, scanned_at FROM files
+WHERE scan_status = 'pending' AND created_at < $1
+ORDER BY created_at
+LIMIT $2
+`
+
+type ListPendingScansParams struct {
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	Limit     int32     `db:"limit" json:"limit"`
+}
+
+func (q *Queries) ListPendingScans(ctx context.Context, arg ListPendingScansParams) ([]File, error) {
+	rows, err := q.query(ctx, q.listPendingScansStmt, listPendingScans, arg.CreatedAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []File
+	for rows.Next() {
+		var i File
+		if err := rows.Scan(
+			&i.ID,
+			&i.UploaderID,
+			&i.Name,
+			&i.MimeType,
+			&i.Size,
+			&i.Path,
+			&i.CreatedAt,
+			&i.Width,
+			&i.Height,
+			&i.Blurhash,
+			&i.OriginalPath,
+			&i.Sha256,
+			&i.UploadSha256,
+			&i.ConversationID,
+			&i.ScanStatus,
+			&i.ScanSignature,
+			&i.ScannedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setFileImageInfo = `-- name: SetFileImageInfo :one
+UPDATE files
+SET width = $2, height = $3, blurhash = $4
+WHERE id = $1
+RETURNING id, uploader_id, name, mime_type, size, path, created_at, width, height, blurhash, original_path, sha256, upload_sha256, conversation_id, scan_status, scan_signature, scanned_at
+`
+
+type SetFileImageInfoParams struct {
+	ID       uuid.UUID      `db:"id" json:"id"`
+	Width    sql.NullInt32  `db:"width" json:"width"`
+	Height   sql.NullInt32  `db:"height" json:"height"`
+	Blurhash sql.NullString `db:"blurhash" json:"blurhash"`
+}
+
+func (q *Queries) SetFileImageInfo(ctx context.Context, arg SetFileImageInfoParams) (File, error) {
+	row := q.queryRow(ctx, q.setFileImageInfoStmt, setFileImageInfo,
+		arg.ID,
+		arg.Width,
+		arg.Height,
+		arg.Blurhash,
+	)
+	var i File
+	err := row.Scan(
+		&i.ID,
+		&i.UploaderID,
+		&i.Name,
+		&i.MimeType,
+		&i.Size,
+		&i.Path,
+		&i.CreatedAt,
+		&i.Width,
+		&i.Height,
+		&i.Blurhash,
+		&i.OriginalPath,
+		&i.Sha256,
+		&i.UploadSha256,
+		&i.ConversationID,
+		&i.ScanStatus,
+		&i.ScanSignature,
+		&i.ScannedAt,
+	)
+	return i, err
+}
+
+const setFileScanResult = `-- name: SetFileScanResult :one
+UPDATE files
+SET scan_status = $2, scan_signature = $3, scanned_at = NOW()
+WHERE id = $1
+RETURNING id, uploader_id, name, mime_type, size, path, created_at, width, height, blurhash, original_path, sha256, upload_sha256, conversation_id, scan_status, scan_signature, scanned_at
+`
+
+type SetFileScanResultParams struct {
+	ID            uuid.UUID      `db:"id" json:"id"`
+	ScanStatus    FileScanStatus `db:"scan_status" json:"scan_status"`
+	ScanSignature sql.NullString `db:"scan_signature" json:"scan_signature"`
+}
+
+func (q *Queries) SetFileScanResult(ctx context.Context, arg SetFileScanResultParams) (File, error) {
+	row := q.queryRow(ctx, q.setFileScanResultStmt, setFileScanResult, arg.ID, arg.ScanStatus, arg.ScanSignature)
+	var i File
+	err := row.Scan(
+		&i.ID,
+		&i.UploaderID,
+		&i.Name,
+		&i.MimeType,
+		&i.Size,
+		&i.Path,
+		&i.CreatedAt,
+		&i.Width,
+		&i.Height,
+		&i.Blurhash,
+		&i.OriginalPath,
+		&i.Sha256,
+		&i.UploadSha256,
+		&i.ConversationID,
+		&i.ScanStatus,
+		&i.ScanSignature,
+		&i.ScannedAt,
+	)
+	return i, err
+}
+
+const updateFilePath = `-- name: UpdateFilePath :exec
+UPDATE files SET path = $1 WHERE id = $2
+`
+
+type UpdateFilePathParams struct {
+	NewPath string    `db:"new_path" json:"new_path"`
+	ID      uuid.UUID `db:"id" json:"id"`
+}
+
+func (q *Queries) UpdateFilePath(ctx context.Context, arg UpdateFilePathParams) error {
+	_, err := q.exec(ctx, q.updateFilePathStmt, updateFilePath, arg.NewPath, arg.ID)
+	return err
+}
@@ -8,14 +8,25 @@ package database
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createMessage = `-- name: CreateMessage :one
-INSERT INTO messages (conversation_id, sender_id, content, file_id, reply_to_id)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING id, conversation_id, sender_id, content, file_id, reply_to_id, status, is_edited, deleted_at, created_at, updated_at
+WITH next_seq AS (
+    UPDATE conversations
+    SET last_seq = last_seq + 1
+    WHERE id = $1
+    RETURNING last_seq
+)
+INSERT INTO messages (conversation_id, sender_id, content, file_id, reply_to_id, client_msg_id, thread_id, seq)
+SELECT $1::uuid, $2::uuid, $3::text,
+       $4::uuid, $5::uuid, $6::uuid,
+       $7::uuid, next_seq.last_seq
+FROM next_seq
+RETURNING id, conversation_id, sender_id, content, file_id, reply_to_id, status, is_edited, deleted_at, created_at, updated_at, seq, modified_seq, client_msg_id, thread_id
 `
 
 type CreateMessageParams struct {
@@ -24,6 +35,8 @@ type CreateMessageParams struct {
 	Content        sql.NullString `db:"content" json:"content"`
 	FileID         uuid.NullUUID  `db:"file_id" json:"file_id"`
 	ReplyToID      uuid.NullUUID  `db:"reply_to_id" json:"reply_to_id"`
+	ClientMsgID    uuid.NullUUID  `db:"client_msg_id" json:"client_msg_id"`
+	ThreadID       uuid.NullUUID  `db:"thread_id" json:"thread_id"`
 }
 
 func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error) {
@@ -33,6 +46,8 @@ func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (M
 		arg.Content,
 		arg.FileID,
 		arg.ReplyToID,
+		arg.ClientMsgID,
+		arg.ThreadID,
 	)
 	var i Message
 	err := row.Scan(
@@ -47,25 +62,36 @@ func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (M
 		&i.DeletedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Seq,
+		&i.ModifiedSeq,
+		&i.ClientMsgID,
+		&i.ThreadID,
 	)
 	return i, err
 }
 
 const editMessage = `-- name: EditMessage :one
 UPDATE messages
-SET content = $2, is_edited = TRUE, updated_at = NOW()
-WHERE id = $1 AND sender_id = $3 AND deleted_at IS NULL
-RETURNING id, conversation_id, sender_id, content, file_id, reply_to_id, status, is_edited, deleted_at, created_at, updated_at
+SET content = $2, is_edited = TRUE, updated_at = NOW(),
+    modified_seq = (SELECT last_seq FROM conversations WHERE id = messages.conversation_id)
+WHERE id = $1 AND sender_id = $3 AND conversation_id = $4 AND deleted_at IS NULL
+RETURNING id, conversation_id, sender_id, content, file_id, reply_to_id, status, is_edited, deleted_at, created_at, updated_at, seq, modified_seq, client_msg_id, thread_id
 `
 
 type EditMessageParams struct {
-	ID       uuid.UUID      `db:"id" json:"id"`
-	Content  sql.NullString `db:"content" json:"content"`
-	SenderID uuid.UUID      `db:"sender_id" json:"sender_id"`
+	ID             uuid.UUID      `db:"id" json:"id"`
+	Content        sql.NullString `db:"content" json:"content"`
+	SenderID       uuid.UUID      `db:"sender_id" json:"sender_id"`
+	ConversationID uuid.UUID      `db:"conversation_id" json:"conversation_id"`
 }
 
 func (q *Queries) EditMessage(ctx context.Context, arg EditMessageParams) (Message, error) {
-	row := q.queryRow(ctx, q.editMessageStmt, editMessage, arg.ID, arg.Content, arg.SenderID)
+	row := q.queryRow(ctx, q.editMessageStmt, editMessage,
+		arg.ID,
+		arg.Content,
+		arg.SenderID,
+		arg.ConversationID,
+	)
 	var i Message
 	err := row.Scan(
 		&i.ID,
@@ -79,12 +105,50 @@ func (q *Queries) EditMessage(ctx context.Context, arg EditMessageParams) (Messa
 		&i.DeletedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Seq,
+		&i.ModifiedSeq,
+		&i.ClientMsgID,
+		&i.ThreadID,
+	)
+	return i, err
+}
+
+const getMessageByClientMsgID = `-- name: GetMessageByClientMsgID :one
+SELECT id, conversation_id, sender_id, content, file_id, reply_to_id, status, is_edited, deleted_at, created_at, updated_at, seq, modified_seq, client_msg_id, thread_id FROM messages
+WHERE conversation_id = $1 AND sender_id = $2 AND client_msg_id = $3
+`
+
+type GetMessageByClientMsgIDParams struct {
+	ConversationID uuid.UUID     `db:"conversation_id" json:"conversation_id"`
+	SenderID       uuid.UUID     `db:"sender_id" json:"sender_id"`
+	ClientMsgID    uuid.NullUUID `db:"client_msg_id" json:"client_msg_id"`
+}
+
+func (q *Queries) GetMessageByClientMsgID(ctx context.Context, arg GetMessageByClientMsgIDParams) (Message, error) {
+	row := q.queryRow(ctx, q.getMessageByClientMsgIDStmt, getMessageByClientMsgID, arg.ConversationID, arg.SenderID, arg.ClientMsgID)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.ConversationID,
+		&i.SenderID,
+		&i.Content,
+		&i.FileID,
+		&i.ReplyToID,
+		&i.Status,
+		&i.IsEdited,
+		&i.DeletedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Seq,
+		&i.ModifiedSeq,
+		&i.ClientMsgID,
+		&i.ThreadID,
 	)
 	return i, err
 }
 
 const getMessageByID = `-- name: GetMessageByID :one
-SELECT id, conversation_id, sender_id, content, file_id, reply_to_id, status, is_edited, deleted_at, created_at, updated_at FROM messages WHERE id = $1
+SELECT id, conversation_id, sender_id, content, file_id, reply_to_id, status, is_edited, deleted_at, created_at, updated_at, seq, modified_seq, client_msg_id, thread_id FROM messages WHERE id = $1
 `
 
 func (q *Queries) GetMessageByID(ctx context.Context, id uuid.UUID) (Message, error) {
@@ -102,6 +166,10 @@ func (q *Queries) GetMessageByID(ctx context.Context, id uuid.UUID) (Message, er
 		&i.DeletedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Seq,
+		&i.ModifiedSeq,
+		&i.ClientMsgID,
+		&i.ThreadID,
 	)
 	return i, err
 }
@@ -139,10 +207,28 @@ func (q *Queries) GetMessageReceipts(ctx context.Context, messageID uuid.UUID) (
 }
 
 const getMessagesByConversation = `-- name: GetMessagesByConversation :many
-SELECT id, conversation_id, sender_id, content, file_id, reply_to_id, status, is_edited, deleted_at, created_at, updated_at FROM messages
-WHERE conversation_id = $1 AND deleted_at IS NULL
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3
+WITH page AS (
+    SELECT id FROM messages
+    WHERE conversation_id = $1 AND deleted_at IS NULL AND thread_id IS NULL
+    ORDER BY created_at DESC, id DESC
+    LIMIT $2 OFFSET $3
+)
+SELECT
+    m.id, m.conversation_id, m.sender_id, m.content, m.file_id, m.reply_to_id, m.status, m.is_edited, m.deleted_at, m.created_at, m.updated_at, m.seq, m.modified_seq, m.client_msg_id, m.thread_id,
+    COALESCE(t.reply_count, 0)::bigint AS reply_count,
+    t.last_reply_at,
+    COALESCE(t.participant_ids, '{}')::uuid[] AS thread_participant_ids
+FROM page
+JOIN messages m ON m.id = page.id
+LEFT JOIN LATERAL (
+    SELECT
+        COUNT(*) AS reply_count,
+        MAX(r.created_at) AS last_reply_at,
+        ARRAY_AGG(DISTINCT r.sender_id) AS participant_ids
+    FROM messages r
+    WHERE r.thread_id = m.id AND r.deleted_at IS NULL
+) t ON TRUE
+ORDER BY m.created_at DESC, m.id DESC
 `
 
 type GetMessagesByConversationParams struct {
@@ -151,12 +237,251 @@ type GetMessagesByConversationParams struct {
 	Offset         int32     `db:"offset" json:"offset"`
 }
 
-func (q *Queries) GetMessagesByConversation(ctx context.Context, arg GetMessagesByConversationParams) ([]Message, error) {
+type GetMessagesByConversationRow struct {
+	Message              Message      `db:"message" json:"message"`
+	ReplyCount           int64        `db:"reply_count" json:"reply_count"`
+	LastReplyAt          sql.NullTime `db:"last_reply_at" json:"last_reply_at"`
+	ThreadParticipantIds []uuid.UUID  `db:"thread_participant_ids" json:"thread_participant_ids"`
+}
+
+// thread replies are left out of the timeline and summarised on their root;
+// the page is picked first, so only the roots on it are summarised
+func (q *Queries) GetMessagesByConversation(ctx context.Context, arg GetMessagesByConversationParams) ([]GetMessagesByConversationRow, error) {
 	rows, err := q.query(ctx, q.getMessagesByConversationStmt, getMessagesByConversation, arg.ConversationID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
+	var items []GetMessagesByConversationRow
+	for rows.Next() {
+		var i GetMessagesByConversationRow
+		if err := rows.Scan(
+			&i.Message.ID,
+			&i.Message.ConversationID,
+			&i.Message.SenderID,
+			&i.Message.Content,
+			&i.Message.FileID,
+			&i.Message.ReplyToID,
+			&i.Message.Status,
+			&i.Message.IsEdited,
+			&i.Message.DeletedAt,
+			&i.Message.CreatedAt,
+			&i.Message.UpdatedAt,
+			&i.Message.Seq,
+			&i.Message.ModifiedSeq,
+			&i.Message.ClientMsgID,
+			&i.Message.ThreadID,
+			&i.ReplyCount,
+			&i.LastReplyAt,
+			pq.Array(&i.ThreadParticipantIds),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMessagesByConversationAfter = `-- name: GetMessagesByConversationAfter :many
+WITH page AS (
+    SELECT id FROM messages
+    WHERE conversation_id = $1 AND deleted_at IS NULL AND thread_id IS NULL
+    AND (created_at, id) > ($3::timestamptz, $4::uuid)
+    ORDER BY created_at ASC, id ASC
+    LIMIT $2
+)
+SELECT
+    m.id, m.conversation_id, m.sender_id, m.content, m.file_id, m.reply_to_id, m.status, m.is_edited, m.deleted_at, m.created_at, m.updated_at, m.seq, m.modified_seq, m.client_msg_id, m.thread_id,
+    COALESCE(t.reply_count, 0)::bigint AS reply_count,
+    t.last_reply_at,
+    COALESCE(t.participant_ids, '{}')::uuid[] AS thread_participant_ids
+FROM page
+JOIN messages m ON m.id = page.id
+LEFT JOIN LATERAL (
+    SELECT
+        COUNT(*) AS reply_count,
+        MAX(r.created_at) AS last_reply_at,
+        ARRAY_AGG(DISTINCT r.sender_id) AS participant_ids
+    FROM messages r
+    WHERE r.thread_id = m.id AND r.deleted_at IS NULL
+) t ON TRUE
+ORDER BY m.created_at ASC, m.id ASC
+`
+
+type GetMessagesByConversationAfterParams struct {
+	ConversationID  uuid.UUID `db:"conversation_id" json:"conversation_id"`
+	Limit           int32     `db:"limit" json:"limit"`
+	CursorCreatedAt time.Time `db:"cursor_created_at" json:"cursor_created_at"`
+	CursorID        uuid.UUID `db:"cursor_id" json:"cursor_id"`
+}
+
+type GetMessagesByConversationAfterRow struct {
+	Message              Message      `db:"message" json:"message"`
+	ReplyCount           int64        `db:"reply_count" json:"reply_count"`
+	LastReplyAt          sql.NullTime `db:"last_reply_at" json:"last_reply_at"`
+	ThreadParticipantIds []uuid.UUID  `db:"thread_participant_ids" json:"thread_participant_ids"`
+}
+
+func (q *Queries) GetMessagesByConversationAfter(ctx context.Context, arg GetMessagesByConversationAfterParams) ([]GetMessagesByConversationAfterRow, error) {
+	rows, err := q.query(ctx, q.getMessagesByConversationAfterStmt, getMessagesByConversationAfter,
+		arg.ConversationID,
+		arg.Limit,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetMessagesByConversationAfterRow
+	for rows.Next() {
+		var i GetMessagesByConversationAfterRow
+		if err := rows.Scan(
+			&i.Message.ID,
+			&i.Message.ConversationID,
+			&i.Message.SenderID,
+			&i.Message.Content,
+			&i.Message.FileID,
+			&i.Message.ReplyToID,
+			&i.Message.Status,
+			&i.Message.IsEdited,
+			&i.Message.DeletedAt,
+			&i.Message.CreatedAt,
+			&i.Message.UpdatedAt,
+			&i.Message.Seq,
+			&i.Message.ModifiedSeq,
+			&i.Message.ClientMsgID,
+			&i.Message.ThreadID,
+			&i.ReplyCount,
+			&i.LastReplyAt,
+			pq.Array(&i.ThreadParticipantIds),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMessagesByConversationBefore = `-- name: GetMessagesByConversationBefore :many
+WITH page AS (
+    SELECT id FROM messages
+    WHERE conversation_id = $1 AND deleted_at IS NULL AND thread_id IS NULL
+    AND (created_at, id) < ($3::timestamptz, $4::uuid)
+    ORDER BY created_at DESC, id DESC
+    LIMIT $2
+)
+SELECT
+    m.id, m.conversation_id, m.sender_id, m.content, m.file_id, m.reply_to_id, m.status, m.is_edited, m.deleted_at, m.created_at, m.updated_at, m.seq, m.modified_seq, m.client_msg_id, m.thread_id,
+    COALESCE(t.reply_count, 0)::bigint AS reply_count,
+    t.last_reply_at,
+    COALESCE(t.participant_ids, '{}')::uuid[] AS thread_participant_ids
+FROM page
+JOIN messages m ON m.id = page.id
+LEFT JOIN LATERAL (
+    SELECT
+        COUNT(*) AS reply_count,
+        MAX(r.created_at) AS last_reply_at,
+        ARRAY_AGG(DISTINCT r.sender_id) AS participant_ids
+    FROM messages r
+    WHERE r.thread_id = m.id AND r.deleted_at IS NULL
+) t ON TRUE
+ORDER BY m.created_at DESC, m.id DESC
+`
+
+type GetMessagesByConversationBeforeParams struct {
+	ConversationID  uuid.UUID `db:"conversation_id" json:"conversation_id"`
+	Limit           int32     `db:"limit" json:"limit"`
+	CursorCreatedAt time.Time `db:"cursor_created_at" json:"cursor_created_at"`
+	CursorID        uuid.UUID `db:"cursor_id" json:"cursor_id"`
+}
+
+type GetMessagesByConversationBeforeRow struct {
+	Message              Message      `db:"message" json:"message"`
+	ReplyCount           int64        `db:"reply_count" json:"reply_count"`
+	LastReplyAt          sql.NullTime `db:"last_reply_at" json:"last_reply_at"`
+	ThreadParticipantIds []uuid.UUID  `db:"thread_participant_ids" json:"thread_participant_ids"`
+}
+
+func (q *Queries) GetMessagesByConversationBefore(ctx context.Context, arg GetMessagesByConversationBeforeParams) ([]GetMessagesByConversationBeforeRow, error) {
+	rows, err := q.query(ctx, q.getMessagesByConversationBeforeStmt, getMessagesByConversationBefore,
+		arg.ConversationID,
+		arg.Limit,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetMessagesByConversationBeforeRow
+	for rows.Next() {
+		var i GetMessagesByConversationBeforeRow
+		if err := rows.Scan(
+			&i.Message.ID,
+			&i.Message.ConversationID,
+			&i.Message.SenderID,
+			&i.Message.Content,
+			&i.Message.FileID,
+			&i.Message.ReplyToID,
+			&i.Message.Status,
+			&i.Message.IsEdited,
+			&i.Message.DeletedAt,
+			&i.Message.CreatedAt,
+			&i.Message.UpdatedAt,
+			&i.Message.Seq,
+			&i.Message.ModifiedSeq,
+			&i.Message.ClientMsgID,
+			&i.Message.ThreadID,
+			&i.ReplyCount,
+			&i.LastReplyAt,
+			pq.Array(&i.ThreadParticipantIds),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMessagesForReplay = `-- name: GetMessagesForReplay :many
+SELECT id, conversation_id, sender_id, content, file_id, reply_to_id, status, is_edited, deleted_at, created_at, updated_at, seq, modified_seq, client_msg_id, thread_id FROM messages
+WHERE conversation_id = $1
+AND (seq > $2 OR modified_seq >= $2)
+ORDER BY seq ASC
+LIMIT $3
+`
+
+type GetMessagesForReplayParams struct {
+	ConversationID uuid.UUID `db:"conversation_id" json:"conversation_id"`
+	Seq            int64     `db:"seq" json:"seq"`
+	Limit          int32     `db:"limit" json:"limit"`
+}
+
+func (q *Queries) GetMessagesForReplay(ctx context.Context, arg GetMessagesForReplayParams) ([]Message, error) {
+	rows, err := q.query(ctx, q.getMessagesForReplayStmt, getMessagesForReplay, arg.ConversationID, arg.Seq, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 	var items []Message
 	for rows.Next() {
 		var i Message
@@ -172,6 +497,10 @@ func (q *Queries) GetMessagesByConversation(ctx context.Context, arg GetMessages
 			&i.DeletedAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Seq,
+			&i.ModifiedSeq,
+			&i.ClientMsgID,
+			&i.ThreadID,
 		); err != nil {
 			return nil, err
 		}
@@ -186,46 +515,21 @@ func (q *Queries) GetMessagesByConversation(ctx context.Context, arg GetMessages
 	return items, nil
 }
 
-const markMessageRead = `-- name: MarkMessageRead :exec
-INSERT INTO message_receipts (message_id, user_id, read_at)
-VALUES ($1, $2, NOW())
-ON CONFLICT (message_id, user_id) DO UPDATE
-SET read_at = NOW()
-`
-
-type MarkMessageReadParams struct {
-	MessageID uuid.UUID `db:"message_id" json:"message_id"`
-	UserID    uuid.UUID `db:"user_id" json:"user_id"`
-}
-
-func (q *Queries) MarkMessageRead(ctx context.Context, arg MarkMessageReadParams) error {
-	_, err := q.exec(ctx, q.markMessageReadStmt, markMessageRead, arg.MessageID, arg.UserID)
-	return err
-}
-
-const searchMessages = `-- name: SearchMessages :many
-SELECT id, conversation_id, sender_id, content, file_id, reply_to_id, status, is_edited, deleted_at, created_at, updated_at FROM messages
-WHERE conversation_id = $1
-AND deleted_at IS NULL
-AND content ILIKE '%' || $2 || '%'
-ORDER BY created_at DESC
-LIMIT $3 OFFSET $4
+const getThreadReplies = `-- name: GetThreadReplies :many
+SELECT id, conversation_id, sender_id, content, file_id, reply_to_id, status, is_edited, deleted_at, created_at, updated_at, seq, modified_seq, client_msg_id, thread_id FROM messages
+WHERE thread_id = $1 AND deleted_at IS NULL
+ORDER BY created_at ASC
+LIMIT $2 OFFSET $3
 `
 
-type SearchMessagesParams struct {
-	ConversationID uuid.UUID      `db:"conversation_id" json:"conversation_id"`
-	Column2        sql.NullString `db:"column_2" json:"column_2"`
-	Limit          int32          `db:"limit" json:"limit"`
-	Offset         int32          `db:"offset" json:"offset"`
+type GetThreadRepliesParams struct {
+	ThreadID uuid.NullUUID `db:"thread_id" json:"thread_id"`
+	Limit    int32         `db:"limit" json:"limit"`
+	Offset   int32         `db:"offset" json:"offset"`
 }
 
-func (q *Queries) SearchMessages(ctx context.Context, arg SearchMessagesParams) ([]Message, error) {
-	rows, err := q.query(ctx, q.searchMessagesStmt, searchMessages,
-		arg.ConversationID,
-		arg.Column2,
-		arg.Limit,
-		arg.Offset,
-	)
+func (q *Queries) GetThreadReplies(ctx context.Context, arg GetThreadRepliesParams) ([]Message, error) {
+	rows, err := q.query(ctx, q.getThreadRepliesStmt, getThreadReplies, arg.ThreadID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -245,6 +549,10 @@ func (q *Queries) SearchMessages(ctx context.Context, arg SearchMessagesParams)
 			&i.DeletedAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Seq,
+			&i.ModifiedSeq,
+			&i.ClientMsgID,
+			&i.ThreadID,
 		); err != nil {
 			return nil, err
 		}
@@ -259,20 +567,58 @@ func (q *Queries) SearchMessages(ctx context.Context, arg SearchMessagesParams)
 	return items, nil
 }
 
-const softDeleteMessage = `-- name: SoftDeleteMessage :exec
+const markMessageRead = `-- name: MarkMessageRead :exec
+INSERT INTO message_receipts (message_id, user_id, read_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (message_id, user_id) DO UPDATE
+SET read_at = NOW()
+`
+
+type MarkMessageReadParams struct {
+	MessageID uuid.UUID `db:"message_id" json:"message_id"`
+	UserID    uuid.UUID `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) MarkMessageRead(ctx context.Context, arg MarkMessageReadParams) error {
+	_, err := q.exec(ctx, q.markMessageReadStmt, markMessageRead, arg.MessageID, arg.UserID)
+	return err
+}
+
+const softDeleteMessage = `-- name: SoftDeleteMessage :one
 UPDATE messages
-SET deleted_at = NOW()
-WHERE id = $1 AND sender_id = $2
+SET deleted_at = NOW(),
+    modified_seq = (SELECT last_seq FROM conversations WHERE id = messages.conversation_id)
+WHERE id = $1 AND sender_id = $2 AND conversation_id = $3 AND deleted_at IS NULL
+RETURNING id, conversation_id, sender_id, content, file_id, reply_to_id, status, is_edited, deleted_at, created_at, updated_at, seq, modified_seq, client_msg_id, thread_id
 `
 
 type SoftDeleteMessageParams struct {
-	ID       uuid.UUID `db:"id" json:"id"`
-	SenderID uuid.UUID `db:"sender_id" json:"sender_id"`
+	ID             uuid.UUID `db:"id" json:"id"`
+	SenderID       uuid.UUID `db:"sender_id" json:"sender_id"`
+	ConversationID uuid.UUID `db:"conversation_id" json:"conversation_id"`
 }
 
-func (q *Queries) SoftDeleteMessage(ctx context.Context, arg SoftDeleteMessageParams) error {
-	_, err := q.exec(ctx, q.softDeleteMessageStmt, softDeleteMessage, arg.ID, arg.SenderID)
-	return err
+func (q *Queries) SoftDeleteMessage(ctx context.Context, arg SoftDeleteMessageParams) (Message, error) {
+	row := q.queryRow(ctx, q.softDeleteMessageStmt, softDeleteMessage, arg.ID, arg.SenderID, arg.ConversationID)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.ConversationID,
+		&i.SenderID,
+		&i.Content,
+		&i.FileID,
+		&i.ReplyToID,
+		&i.Status,
+		&i.IsEdited,
+		&i.DeletedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Seq,
+		&i.ModifiedSeq,
+		&i.ClientMsgID,
+		&i.ThreadID,
+	)
+	return i, err
 }
 
 const upsertMessageReceipt = `-- name: UpsertMessageReceipt :exec
@@ -14,6 +14,51 @@ import (
 	"github.com/sqlc-dev/pqtype"
 )
 
+type FileScanStatus string
+
+const (
+	FileScanStatusPending  FileScanStatus = "pending"
+	FileScanStatusClean    FileScanStatus = "clean"
+	FileScanStatusInfected FileScanStatus = "infected"
+	FileScanStatusError    FileScanStatus = "error"
+	FileScanStatusSkipped  FileScanStatus = "skipped"
+)
+
+func (e *FileScanStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = FileScanStatus(s)
+	case string:
+		*e = FileScanStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for FileScanStatus: %T", src)
+	}
+	return nil
+}
+
+type NullFileScanStatus struct {
+	FileScanStatus FileScanStatus `json:"file_scan_status"`
+	Valid          bool           `json:"valid"` // Valid is true if FileScanStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullFileScanStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.FileScanStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.FileScanStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullFileScanStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.FileScanStatus), nil
+}
+
 type MemberRole string
 
 const (
@@ -100,6 +145,98 @@ func (ns NullMessageStatus) Value() (driver.Value, error) {
 	return string(ns.MessageStatus), nil
 }
 
+type SecurityEventType string
+
+const (
+	SecurityEventTypeRefreshTokenReuse SecurityEventType = "refresh_token_reuse"
+)
+
+func (e *SecurityEventType) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = SecurityEventType(s)
+	case string:
+		*e = SecurityEventType(s)
+	default:
+		return fmt.Errorf("unsupported scan type for SecurityEventType: %T", src)
+	}
+	return nil
+}
+
+type NullSecurityEventType struct {
+	SecurityEventType SecurityEventType `json:"security_event_type"`
+	Valid             bool              `json:"valid"` // Valid is true if SecurityEventType is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullSecurityEventType) Scan(value interface{}) error {
+	if value == nil {
+		ns.SecurityEventType, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.SecurityEventType.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullSecurityEventType) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.SecurityEventType), nil
+}
+
+type UploadStatus string
+
+const (
+	UploadStatusUploading  UploadStatus = "uploading"
+	UploadStatusCompleting UploadStatus = "completing"
+)
+
+func (e *UploadStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = UploadStatus(s)
+	case string:
+		*e = UploadStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for UploadStatus: %T", src)
+	}
+	return nil
+}
+
+type NullUploadStatus struct {
+	UploadStatus UploadStatus `json:"upload_status"`
+	Valid        bool         `json:"valid"` // Valid is true if UploadStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullUploadStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.UploadStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.UploadStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullUploadStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.UploadStatus), nil
+}
+
+type Blob struct {
+	Sha256         string    `db:"sha256" json:"sha256"`
+	Path           string    `db:"path" json:"path"`
+	Size           int64     `db:"size" json:"size"`
+	RefCount       int32     `db:"ref_count" json:"ref_count"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+	LastAcquiredAt time.Time `db:"last_acquired_at" json:"last_acquired_at"`
+}
+
 type Conversation struct {
 	ID        uuid.UUID      `db:"id" json:"id"`
 	IsGroup   bool           `db:"is_group" json:"is_group"`
@@ -108,6 +245,7 @@ type Conversation struct {
 	CreatedAt time.Time      `db:"created_at" json:"created_at"`
 	UpdatedAt time.Time      `db:"updated_at" json:"updated_at"`
 	DeletedAt sql.NullTime   `db:"deleted_at" json:"deleted_at"`
+	LastSeq   int64          `db:"last_seq" json:"last_seq"`
 }
 
 type ConversationMember struct {
@@ -118,14 +256,49 @@ type ConversationMember struct {
 	LastReadAt     sql.NullTime `db:"last_read_at" json:"last_read_at"`
 }
 
+type ConversationStorageQuota struct {
+	ConversationID uuid.UUID     `db:"conversation_id" json:"conversation_id"`
+	QuotaBytes     sql.NullInt64 `db:"quota_bytes" json:"quota_bytes"`
+	UpdatedAt      time.Time     `db:"updated_at" json:"updated_at"`
+}
+
 type File struct {
-	ID         uuid.UUID `db:"id" json:"id"`
-	UploaderID uuid.UUID `db:"uploader_id" json:"uploader_id"`
-	Name       string    `db:"name" json:"name"`
-	MimeType   string    `db:"mime_type" json:"mime_type"`
-	Size       int64     `db:"size" json:"size"`
-	Path       string    `db:"path" json:"path"`
-	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+	ID             uuid.UUID      `db:"id" json:"id"`
+	UploaderID     uuid.UUID      `db:"uploader_id" json:"uploader_id"`
+	Name           string         `db:"name" json:"name"`
+	MimeType       string         `db:"mime_type" json:"mime_type"`
+	Size           int64          `db:"size" json:"size"`
+	Path           string         `db:"path" json:"path"`
+	CreatedAt      time.Time      `db:"created_at" json:"created_at"`
+	Width          sql.NullInt32  `db:"width" json:"width"`
+	Height         sql.NullInt32  `db:"height" json:"height"`
+	Blurhash       sql.NullString `db:"blurhash" json:"blurhash"`
+	OriginalPath   sql.NullString `db:"original_path" json:"original_path"`
+	Sha256         sql.NullString `db:"sha256" json:"sha256"`
+	UploadSha256   sql.NullString `db:"upload_sha256" json:"upload_sha256"`
+	ConversationID uuid.NullUUID  `db:"conversation_id" json:"conversation_id"`
+	ScanStatus     FileScanStatus `db:"scan_status" json:"scan_status"`
+	ScanSignature  sql.NullString `db:"scan_signature" json:"scan_signature"`
+	ScannedAt      sql.NullTime   `db:"scanned_at" json:"scanned_at"`
+}
+
+type FileThumbnail struct {
+	FileID   uuid.UUID `db:"file_id" json:"file_id"`
+	Size     int32     `db:"size" json:"size"`
+	Width    int32     `db:"width" json:"width"`
+	Height   int32     `db:"height" json:"height"`
+	MimeType string    `db:"mime_type" json:"mime_type"`
+	Path     string    `db:"path" json:"path"`
+}
+
+type LoginChallenge struct {
+	ID        uuid.UUID    `db:"id" json:"id"`
+	UserID    uuid.UUID    `db:"user_id" json:"user_id"`
+	TokenHash string       `db:"token_hash" json:"token_hash"`
+	ExpiresAt time.Time    `db:"expires_at" json:"expires_at"`
+	Attempts  int32        `db:"attempts" json:"attempts"`
+	UsedAt    sql.NullTime `db:"used_at" json:"used_at"`
+	CreatedAt time.Time    `db:"created_at" json:"created_at"`
 }
 
 type Message struct {
@@ -140,6 +313,10 @@ type Message struct {
 	DeletedAt      sql.NullTime   `db:"deleted_at" json:"deleted_at"`
 	CreatedAt      time.Time      `db:"created_at" json:"created_at"`
 	UpdatedAt      time.Time      `db:"updated_at" json:"updated_at"`
+	Seq            int64          `db:"seq" json:"seq"`
+	ModifiedSeq    sql.NullInt64  `db:"modified_seq" json:"modified_seq"`
+	ClientMsgID    uuid.NullUUID  `db:"client_msg_id" json:"client_msg_id"`
+	ThreadID       uuid.NullUUID  `db:"thread_id" json:"thread_id"`
 }
 
 type MessageReceipt struct {
@@ -149,6 +326,42 @@ type MessageReceipt struct {
 	ReadAt      sql.NullTime `db:"read_at" json:"read_at"`
 }
 
+type MessageReaction struct {
+	MessageID uuid.UUID `db:"message_id" json:"message_id"`
+	UserID    uuid.UUID `db:"user_id" json:"user_id"`
+	Emoji     string    `db:"emoji" json:"emoji"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+type MessageSearch struct {
+	MessageID    uuid.UUID   `db:"message_id" json:"message_id"`
+	SearchVector interface{} `db:"search_vector" json:"search_vector"`
+}
+
+type PasswordResetToken struct {
+	ID        uuid.UUID    `db:"id" json:"id"`
+	UserID    uuid.UUID    `db:"user_id" json:"user_id"`
+	TokenHash string       `db:"token_hash" json:"token_hash"`
+	ExpiresAt time.Time    `db:"expires_at" json:"expires_at"`
+	UsedAt    sql.NullTime `db:"used_at" json:"used_at"`
+	CreatedAt time.Time    `db:"created_at" json:"created_at"`
+}
+
+type PinnedMessage struct {
+	ConversationID uuid.UUID `db:"conversation_id" json:"conversation_id"`
+	MessageID      uuid.UUID `db:"message_id" json:"message_id"`
+	PinnedBy       uuid.UUID `db:"pinned_by" json:"pinned_by"`
+	PinnedAt       time.Time `db:"pinned_at" json:"pinned_at"`
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID    `db:"id" json:"id"`
+	UserID    uuid.UUID    `db:"user_id" json:"user_id"`
+	CodeHash  string       `db:"code_hash" json:"code_hash"`
+	UsedAt    sql.NullTime `db:"used_at" json:"used_at"`
+	CreatedAt time.Time    `db:"created_at" json:"created_at"`
+}
+
 type RefreshToken struct {
 	ID                uuid.UUID      `db:"id" json:"id"`
 	UserID            uuid.UUID      `db:"user_id" json:"user_id"`
@@ -159,13 +372,59 @@ type RefreshToken struct {
 	UserAgent         sql.NullString `db:"user_agent" json:"user_agent"`
 	IpAddress         pqtype.Inet    `db:"ip_address" json:"ip_address"`
 	ReplacedByTokenID uuid.NullUUID  `db:"replaced_by_token_id" json:"replaced_by_token_id"`
+	SessionID         uuid.UUID      `db:"session_id" json:"session_id"`
+}
+
+type SecurityEvent struct {
+	ID             uuid.UUID         `db:"id" json:"id"`
+	UserID         uuid.UUID         `db:"user_id" json:"user_id"`
+	EventType      SecurityEventType `db:"event_type" json:"event_type"`
+	RefreshTokenID uuid.NullUUID     `db:"refresh_token_id" json:"refresh_token_id"`
+	IpAddress      pqtype.Inet       `db:"ip_address" json:"ip_address"`
+	UserAgent      sql.NullString    `db:"user_agent" json:"user_agent"`
+	CreatedAt      time.Time         `db:"created_at" json:"created_at"`
+}
+
+type ThreadFollower struct {
+	ThreadID  uuid.UUID `db:"thread_id" json:"thread_id"`
+	UserID    uuid.UUID `db:"user_id" json:"user_id"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+type Upload struct {
+	ID             uuid.UUID     `db:"id" json:"id"`
+	UploaderID     uuid.UUID     `db:"uploader_id" json:"uploader_id"`
+	Name           string        `db:"name" json:"name"`
+	MimeType       string        `db:"mime_type" json:"mime_type"`
+	Size           int64         `db:"size" json:"size"`
+	UploadOffset   int64         `db:"upload_offset" json:"upload_offset"`
+	PartPaths      []string      `db:"part_paths" json:"part_paths"`
+	Status         UploadStatus  `db:"status" json:"status"`
+	ExpiresAt      time.Time     `db:"expires_at" json:"expires_at"`
+	CreatedAt      time.Time     `db:"created_at" json:"created_at"`
+	ConversationID uuid.NullUUID `db:"conversation_id" json:"conversation_id"`
 }
 
 type User struct {
-	ID           uuid.UUID      `db:"id" json:"id"`
-	CreatedAt    time.Time      `db:"created_at" json:"created_at"`
-	UpdatedAt    time.Time      `db:"updated_at" json:"updated_at"`
-	Name         sql.NullString `db:"name" json:"name"`
-	Email        string         `db:"email" json:"email"`
-	PasswordHash string         `db:"password_hash" json:"password_hash"`
+	ID              uuid.UUID      `db:"id" json:"id"`
+	CreatedAt       time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time      `db:"updated_at" json:"updated_at"`
+	Name            sql.NullString `db:"name" json:"name"`
+	Email           string         `db:"email" json:"email"`
+	PasswordHash    string         `db:"password_hash" json:"password_hash"`
+	EmailVerifiedAt sql.NullTime   `db:"email_verified_at" json:"email_verified_at"`
+}
+
+type UserStorageQuota struct {
+	UserID     uuid.UUID     `db:"user_id" json:"user_id"`
+	QuotaBytes sql.NullInt64 `db:"quota_bytes" json:"quota_bytes"`
+	UpdatedAt  time.Time     `db:"updated_at" json:"updated_at"`
+}
+
+type UserTotp struct {
+	UserID       uuid.UUID    `db:"user_id" json:"user_id"`
+	Secret       string       `db:"secret" json:"secret"`
+	EnabledAt    sql.NullTime `db:"enabled_at" json:"enabled_at"`
+	LastUsedStep int64        `db:"last_used_step" json:"last_used_step"`
+	CreatedAt    time.Time    `db:"created_at" json:"created_at"`
 }
@@ -6,48 +6,193 @@ package database
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Querier interface {
+	// records freshly stored content, or takes another reference on the copy
+	// that is already there; callers compare the returned path with their own
+	AcquireBlob(ctx context.Context, arg AcquireBlobParams) (Blob, error)
+	AddBlobRef(ctx context.Context, sha256 string) (Blob, error)
 	AddConversationMember(ctx context.Context, arg AddConversationMemberParams) error
+	AddMessageReaction(ctx context.Context, arg AddMessageReactionParams) (int64, error)
+	// only applies when no other chunk landed first
+	AppendUploadPart(ctx context.Context, arg AppendUploadPartParams) (Upload, error)
+	// binds a file uploaded outside any conversation to the first one it is
+	// posted in, so it counts against that conversation's quota; nothing comes
+	// back if it doesn't fit. Without an override the default applies, 0 for
+	// unlimited.
+	AttachFileToConversation(ctx context.Context, arg AttachFileToConversationParams) (File, error)
+	// counts an attempt at a live challenge; returns no row once it is used,
+	// expired or out of attempts
+	AttemptLoginChallenge(ctx context.Context, arg AttemptLoginChallengeParams) (LoginChallenge, error)
+	// like CanAccessFile, but through any file holding the same bytes
+	CanAccessContent(ctx context.Context, arg CanAccessContentParams) (bool, error)
+	// the uploader, and members of any conversation with a message carrying the file
+	CanAccessFile(ctx context.Context, arg CanAccessFileParams) (bool, error)
+	// marks a fully received upload as taken by the one request completing it;
+	// committed on its own so no transaction stays open while the parts are joined
+	ClaimUpload(ctx context.Context, arg ClaimUploadParams) (Upload, error)
+	CopyFileThumbnails(ctx context.Context, arg CopyFileThumbnailsParams) ([]FileThumbnail, error)
+	CountRecentPasswordResetTokens(ctx context.Context, arg CountRecentPasswordResetTokensParams) (int64, error)
+	CountUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) (int64, error)
 	CreateConversation(ctx context.Context, arg CreateConversationParams) (Conversation, error)
 	CreateFile(ctx context.Context, arg CreateFileParams) (File, error)
+	CreateFileThumbnail(ctx context.Context, arg CreateFileThumbnailParams) (FileThumbnail, error)
+	CreateLoginChallenge(ctx context.Context, arg CreateLoginChallengeParams) (LoginChallenge, error)
 	CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error)
+	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) (PasswordResetToken, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error
 	CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error)
+	CreateSecurityEvent(ctx context.Context, arg CreateSecurityEventParams) error
+	CreateUpload(ctx context.Context, arg CreateUploadParams) (Upload, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
 	DeleteConversation(ctx context.Context, id uuid.UUID) error
+	DeleteConversationStorageQuota(ctx context.Context, conversationID uuid.UUID) error
+	DeleteExpiredLoginChallenges(ctx context.Context, userID uuid.UUID) error
+	DeleteExpiredUploads(ctx context.Context) ([]Upload, error)
 	DeleteFile(ctx context.Context, arg DeleteFileParams) error
+	// rechecks, as the file may have been attached since it was listed
+	DeleteOrphanedFile(ctx context.Context, arg DeleteOrphanedFileParams) (File, error)
+	DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error
+	// only while still unreferenced; an upload of the same content may have
+	// claimed it meanwhile
+	DeleteUnreferencedBlob(ctx context.Context, sha256 string) (Blob, error)
+	DeleteUpload(ctx context.Context, arg DeleteUploadParams) (Upload, error)
 	DeleteUser(ctx context.Context, id uuid.UUID) error
+	DeleteUserStorageQuota(ctx context.Context, userID uuid.UUID) error
+	DeleteUserTOTP(ctx context.Context, userID uuid.UUID) error
 	EditMessage(ctx context.Context, arg EditMessageParams) (Message, error)
+	EnableUserTOTP(ctx context.Context, arg EnableUserTOTPParams) (UserTotp, error)
+	FollowThread(ctx context.Context, arg FollowThreadParams) error
+	// deduplicated files share paths, so of the files holding path as their
+	// content, original or a thumbnail, the caller's own comes first, then the
+	// earliest shared with them
+	GetAccessibleFileByPath(ctx context.Context, arg GetAccessibleFileByPathParams) (File, error)
+	// an image matches the hash of its stored content or of the bytes uploaded
+	GetAccessibleFileBySHA256(ctx context.Context, arg GetAccessibleFileBySHA256Params) (File, error)
+	GetBlob(ctx context.Context, sha256 string) (Blob, error)
 	GetConversationByID(ctx context.Context, id uuid.UUID) (Conversation, error)
 	GetConversationMember(ctx context.Context, arg GetConversationMemberParams) (ConversationMember, error)
 	GetConversationMembers(ctx context.Context, conversationID uuid.UUID) ([]GetConversationMembersRow, error)
+	GetConversationStorageQuota(ctx context.Context, conversationID uuid.UUID) (ConversationStorageQuota, error)
+	GetConversationStorageUsage(ctx context.Context, conversationID uuid.NullUUID) (int64, error)
 	GetDirectConversation(ctx context.Context, arg GetDirectConversationParams) (Conversation, error)
 	GetFileByID(ctx context.Context, id uuid.UUID) (File, error)
+	GetFileThumbnails(ctx context.Context, fileID uuid.UUID) ([]FileThumbnail, error)
 	GetFirstAdminOrMember(ctx context.Context, arg GetFirstAdminOrMemberParams) (GetFirstAdminOrMemberRow, error)
+	GetFollowedThreads(ctx context.Context, arg GetFollowedThreadsParams) ([]uuid.UUID, error)
+	GetMessageByClientMsgID(ctx context.Context, arg GetMessageByClientMsgIDParams) (Message, error)
 	GetMessageByID(ctx context.Context, id uuid.UUID) (Message, error)
+	GetMessageReactions(ctx context.Context, arg GetMessageReactionsParams) ([]GetMessageReactionsRow, error)
 	GetMessageReceipts(ctx context.Context, messageID uuid.UUID) ([]MessageReceipt, error)
-	GetMessagesByConversation(ctx context.Context, arg GetMessagesByConversationParams) ([]Message, error)
+	// thread replies are left out of the timeline and summarised on their root;
+	// the page is picked first, so only the roots on it are summarised
+	GetMessagesByConversation(ctx context.Context, arg GetMessagesByConversationParams) ([]GetMessagesByConversationRow, error)
+	GetMessagesByConversationAfter(ctx context.Context, arg GetMessagesByConversationAfterParams) ([]GetMessagesByConversationAfterRow, error)
+	GetMessagesByConversationBefore(ctx context.Context, arg GetMessagesByConversationBeforeParams) ([]GetMessagesByConversationBeforeRow, error)
+	GetMessagesForReplay(ctx context.Context, arg GetMessagesForReplayParams) ([]Message, error)
+	GetPinnedMessage(ctx context.Context, arg GetPinnedMessageParams) (PinnedMessage, error)
+	GetPinnedMessages(ctx context.Context, conversationID uuid.UUID) ([]GetPinnedMessagesRow, error)
+	// another file with the same content whose image info is already worked out
+	GetProcessedFileBySHA256(ctx context.Context, arg GetProcessedFileBySHA256Params) (File, error)
 	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (RefreshToken, error)
+	// identical content already scanned under another file
+	GetScanVerdictBySHA256(ctx context.Context, arg GetScanVerdictBySHA256Params) (GetScanVerdictBySHA256Row, error)
+	GetThreadFollowers(ctx context.Context, threadID uuid.UUID) ([]uuid.UUID, error)
+	GetThreadReplies(ctx context.Context, arg GetThreadRepliesParams) ([]Message, error)
+	GetUpload(ctx context.Context, arg GetUploadParams) (Upload, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (User, error)
-	GetUserConversations(ctx context.Context, arg GetUserConversationsParams) ([]Conversation, error)
+	// one row per conversation with everything the inbox renders: the latest
+	// timeline message, the caller's unread count and, for direct chats, the
+	// other participant. The page is picked first, so the summaries are only
+	// worked out for the conversations on it.
+	GetUserConversations(ctx context.Context, arg GetUserConversationsParams) ([]GetUserConversationsRow, error)
+	GetUserConversationsAfter(ctx context.Context, arg GetUserConversationsAfterParams) ([]GetUserConversationsAfterRow, error)
+	GetUserConversationsBefore(ctx context.Context, arg GetUserConversationsBeforeParams) ([]GetUserConversationsBeforeRow, error)
+	GetUserStorageQuota(ctx context.Context, userID uuid.UUID) (UserStorageQuota, error)
+	GetUserStorageUsage(ctx context.Context, uploaderID uuid.UUID) (int64, error)
+	GetUserTOTP(ctx context.Context, userID uuid.UUID) (UserTotp, error)
+	InvalidatePasswordResetTokens(ctx context.Context, userID uuid.UUID) error
+	// a session lasts as long as it has a live refresh token
+	IsSessionActive(ctx context.Context, sessionID uuid.UUID) (bool, error)
+	// whether any row still points at a stored object
+	IsStoragePathReferenced(ctx context.Context, path string) (bool, error)
+	// one row per session, from its newest live refresh token; that token was
+	// issued by the session's last refresh
+	ListActiveSessions(ctx context.Context, userID uuid.UUID) ([]ListActiveSessionsRow, error)
+	// blobs whose count disagrees with the files holding them, e.g. after a
+	// crash between storing content and creating its file; a blob taken again
+	// lately may have an upload whose file doesn't exist yet, so it waits
+	ListBlobRefCountDrift(ctx context.Context, lastAcquiredAt time.Time) ([]ListBlobRefCountDriftRow, error)
+	ListFilesAfter(ctx context.Context, arg ListFilesAfterParams) ([]File, error)
+	// files older than cutoff that no message carries, apart from messages
+	// deleted before cutoff
+	ListOrphanedFiles(ctx context.Context, arg ListOrphanedFilesParams) ([]File, error)
+	ListPendingScans(ctx context.Context, arg ListPendingScansParams) ([]File, error)
+	ListUnreferencedBlobs(ctx context.Context, createdAt time.Time) ([]Blob, error)
+	// serialises pinning in a conversation until the transaction ends, so two
+	// pins can't both pass the cap; an advisory lock rather than a row lock, as
+	// every new message updates the conversation row
+	LockConversationPins(ctx context.Context, conversationID uuid.UUID) error
+	// the same for the files counted against a conversation
+	LockConversationStorage(ctx context.Context, conversationID uuid.UUID) error
+	// held until the transaction ends, so quota checks for one uploader and the
+	// files they let in happen one at a time
+	LockUserStorage(ctx context.Context, userID uuid.UUID) error
+	// the email is checked so a link for an address the user has since
+	// changed away from verifies nothing
+	MarkEmailVerified(ctx context.Context, arg MarkEmailVerifiedParams) (User, error)
 	MarkMessageRead(ctx context.Context, arg MarkMessageReadParams) error
+	// deleted messages do not count towards the cap
+	PinMessage(ctx context.Context, arg PinMessageParams) (int64, error)
+	ReleaseBlob(ctx context.Context, sha256 string) (Blob, error)
+	// hands a claimed upload back after a completion failed, so it can be retried
+	ReleaseUpload(ctx context.Context, arg ReleaseUploadParams) error
 	RemoveConversationMember(ctx context.Context, arg RemoveConversationMemberParams) error
+	RemoveMessageReaction(ctx context.Context, arg RemoveMessageReactionParams) (int64, error)
 	RevokeAllUserRefreshTokens(ctx context.Context, userID uuid.UUID) error
+	RevokeOtherSessions(ctx context.Context, arg RevokeOtherSessionsParams) error
 	RevokeRefreshToken(ctx context.Context, id uuid.UUID) error
-	RotateRefreshToken(ctx context.Context, arg RotateRefreshTokenParams) error
-	SearchMessages(ctx context.Context, arg SearchMessagesParams) ([]Message, error)
+	RevokeSession(ctx context.Context, arg RevokeSessionParams) (int64, error)
+	// affects no row when the token was rotated or revoked meanwhile, so only
+	// one of two concurrent refreshes wins
+	RotateRefreshToken(ctx context.Context, arg RotateRefreshTokenParams) (int64, error)
+	// ranked full-text search over every conversation the user belongs to; the
+	// optional filters narrow it down. Highlights mark matches with \x02 and \x03
+	// so the caller can escape the content before turning them into markup.
+	// Ranks are worked out once per match, and highlights only for the page.
+	SearchMessages(ctx context.Context, arg SearchMessagesParams) ([]SearchMessagesRow, error)
+	// the page ranked just above a cursor, lowest rank first
+	SearchMessagesAfter(ctx context.Context, arg SearchMessagesAfterParams) ([]SearchMessagesAfterRow, error)
+	SetBlobRefCount(ctx context.Context, arg SetBlobRefCountParams) error
+	SetConversationStorageQuota(ctx context.Context, arg SetConversationStorageQuotaParams) (ConversationStorageQuota, error)
+	SetFileImageInfo(ctx context.Context, arg SetFileImageInfoParams) (File, error)
+	SetFileScanResult(ctx context.Context, arg SetFileScanResultParams) (File, error)
 	SetMemberRole(ctx context.Context, arg SetMemberRoleParams) error
-	SoftDeleteMessage(ctx context.Context, arg SoftDeleteMessageParams) error
+	// starts or restarts enrolment; returns no row once 2FA is enabled
+	SetPendingUserTOTP(ctx context.Context, arg SetPendingUserTOTPParams) (UserTotp, error)
+	SetUserStorageQuota(ctx context.Context, arg SetUserStorageQuotaParams) (UserStorageQuota, error)
+	SoftDeleteMessage(ctx context.Context, arg SoftDeleteMessageParams) (Message, error)
+	UnfollowThread(ctx context.Context, arg UnfollowThreadParams) error
+	UnpinMessage(ctx context.Context, arg UnpinMessageParams) (int64, error)
 	UpdateConversationName(ctx context.Context, arg UpdateConversationNameParams) (Conversation, error)
 	UpdateConversationTimestamp(ctx context.Context, id uuid.UUID) error
+	UpdateFilePath(ctx context.Context, arg UpdateFilePathParams) error
 	UpdateLastRead(ctx context.Context, arg UpdateLastReadParams) error
+	// a changed email has to be verified again
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
 	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error
 	UpsertMessageReceipt(ctx context.Context, arg UpsertMessageReceiptParams) error
+	UseLoginChallenge(ctx context.Context, id uuid.UUID) (int64, error)
+	// claims the token in one statement, so it works at most once
+	UsePasswordResetToken(ctx context.Context, tokenHash string) (PasswordResetToken, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	// fails when the step or a later one was used already, so codes are single-use
+	UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (int64, error)
 	UserExistsByEmail(ctx context.Context, email string) (bool, error)
 }
 
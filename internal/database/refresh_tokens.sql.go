@@ -15,9 +15,9 @@ import (
 )
 
 const createRefreshToken = `-- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (id, user_id, token_hash, expires_at, user_agent, ip_address)
-VALUES (gen_random_uuid(), $1, $2, $3, $4, $5)
-RETURNING id, user_id, token_hash, expires_at, revoked_at, created_at, user_agent, ip_address, replaced_by_token_id
+INSERT INTO refresh_tokens (id, user_id, token_hash, expires_at, user_agent, ip_address, session_id)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6)
+RETURNING id, user_id, token_hash, expires_at, revoked_at, created_at, user_agent, ip_address, replaced_by_token_id, session_id
 `
 
 type CreateRefreshTokenParams struct {
@@ -26,6 +26,7 @@ type CreateRefreshTokenParams struct {
 	ExpiresAt time.Time      `db:"expires_at" json:"expires_at"`
 	UserAgent sql.NullString `db:"user_agent" json:"user_agent"`
 	IpAddress pqtype.Inet    `db:"ip_address" json:"ip_address"`
+	SessionID uuid.UUID      `db:"session_id" json:"session_id"`
 }
 
 func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error) {
@@ -35,6 +36,7 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 		arg.ExpiresAt,
 		arg.UserAgent,
 		arg.IpAddress,
+		arg.SessionID,
 	)
 	var i RefreshToken
 	err := row.Scan(
@@ -47,12 +49,13 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 		&i.UserAgent,
 		&i.IpAddress,
 		&i.ReplacedByTokenID,
+		&i.SessionID,
 	)
 	return i, err
 }
 
 const getRefreshTokenByHash = `-- name: GetRefreshTokenByHash :one
-SELECT id, user_id, token_hash, expires_at, revoked_at, created_at, user_agent, ip_address, replaced_by_token_id FROM refresh_tokens WHERE token_hash = $1
+SELECT id, user_id, token_hash, expires_at, revoked_at, created_at, user_agent, ip_address, replaced_by_token_id, session_id FROM refresh_tokens WHERE token_hash = $1
 `
 
 func (q *Queries) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (RefreshToken, error) {
@@ -68,10 +71,84 @@ func (q *Queries) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (
 		&i.UserAgent,
 		&i.IpAddress,
 		&i.ReplacedByTokenID,
+		&i.SessionID,
 	)
 	return i, err
 }
 
+const isSessionActive = `-- name: IsSessionActive :one
+SELECT EXISTS (
+    SELECT 1 FROM refresh_tokens
+    WHERE session_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+)::boolean AS active
+`
+
+// a session lasts as long as it has a live refresh token
+func (q *Queries) IsSessionActive(ctx context.Context, sessionID uuid.UUID) (bool, error) {
+	row := q.queryRow(ctx, q.isSessionActiveStmt, isSessionActive, sessionID)
+	var active bool
+	err := row.Scan(&active)
+	return active, err
+}
+
+const listActiveSessions = `-- name: ListActiveSessions :many
+SELECT s.session_id, s.user_agent, s.ip_address, s.last_used_at, s.expires_at, s.started_at
+FROM (
+    SELECT DISTINCT ON (rt.session_id)
+        rt.session_id,
+        rt.user_agent,
+        rt.ip_address,
+        rt.created_at AS last_used_at,
+        rt.expires_at,
+        (SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.session_id = rt.session_id)::timestamptz AS started_at
+    FROM refresh_tokens rt
+    WHERE rt.user_id = $1 AND rt.revoked_at IS NULL AND rt.expires_at > NOW()
+    ORDER BY rt.session_id, rt.created_at DESC
+) s
+ORDER BY s.last_used_at DESC
+`
+
+type ListActiveSessionsRow struct {
+	SessionID  uuid.UUID      `db:"session_id" json:"session_id"`
+	UserAgent  sql.NullString `db:"user_agent" json:"user_agent"`
+	IpAddress  pqtype.Inet    `db:"ip_address" json:"ip_address"`
+	LastUsedAt time.Time      `db:"last_used_at" json:"last_used_at"`
+	ExpiresAt  time.Time      `db:"expires_at" json:"expires_at"`
+	StartedAt  time.Time      `db:"started_at" json:"started_at"`
+}
+
+// one row per session, from its newest live refresh token; that token was
+// issued by the session's last refresh
+func (q *Queries) ListActiveSessions(ctx context.Context, userID uuid.UUID) ([]ListActiveSessionsRow, error) {
+	rows, err := q.query(ctx, q.listActiveSessionsStmt, listActiveSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListActiveSessionsRow
+	for rows.Next() {
+		var i ListActiveSessionsRow
+		if err := rows.Scan(
+			&i.SessionID,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.LastUsedAt,
+			&i.ExpiresAt,
+			&i.StartedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const revokeAllUserRefreshTokens = `-- name: RevokeAllUserRefreshTokens :exec
 UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL
 `
@@ -81,6 +158,21 @@ func (q *Queries) RevokeAllUserRefreshTokens(ctx context.Context, userID uuid.UU
 	return err
 }
 
+const revokeOtherSessions = `-- name: RevokeOtherSessions :exec
+UPDATE refresh_tokens SET revoked_at = NOW()
+WHERE user_id = $1 AND session_id <> $2 AND revoked_at IS NULL
+`
+
+type RevokeOtherSessionsParams struct {
+	UserID    uuid.UUID `db:"user_id" json:"user_id"`
+	SessionID uuid.UUID `db:"session_id" json:"session_id"`
+}
+
+func (q *Queries) RevokeOtherSessions(ctx context.Context, arg RevokeOtherSessionsParams) error {
+	_, err := q.exec(ctx, q.revokeOtherSessionsStmt, revokeOtherSessions, arg.UserID, arg.SessionID)
+	return err
+}
+
 const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens SET revoked_at = NOW() WHERE id = $1
 `
@@ -90,8 +182,26 @@ func (q *Queries) RevokeRefreshToken(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
-const rotateRefreshToken = `-- name: RotateRefreshToken :exec
-UPDATE refresh_tokens SET revoked_at = NOW(), replaced_by_token_id = $2 WHERE id = $1
+const revokeSession = `-- name: RevokeSession :execrows
+UPDATE refresh_tokens SET revoked_at = NOW()
+WHERE user_id = $1 AND session_id = $2 AND revoked_at IS NULL
+`
+
+type RevokeSessionParams struct {
+	UserID    uuid.UUID `db:"user_id" json:"user_id"`
+	SessionID uuid.UUID `db:"session_id" json:"session_id"`
+}
+
+func (q *Queries) RevokeSession(ctx context.Context, arg RevokeSessionParams) (int64, error) {
+	result, err := q.exec(ctx, q.revokeSessionStmt, revokeSession, arg.UserID, arg.SessionID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const rotateRefreshToken = `-- name: RotateRefreshToken :execrows
+UPDATE refresh_tokens SET revoked_at = NOW(), replaced_by_token_id = $2 WHERE id = $1 AND revoked_at IS NULL
 `
 
 type RotateRefreshTokenParams struct {
@@ -99,7 +209,12 @@ type RotateRefreshTokenParams struct {
 	ReplacedByTokenID uuid.NullUUID `db:"replaced_by_token_id" json:"replaced_by_token_id"`
 }
 
-func (q *Queries) RotateRefreshToken(ctx context.Context, arg RotateRefreshTokenParams) error {
-	_, err := q.exec(ctx, q.rotateRefreshTokenStmt, rotateRefreshToken, arg.ID, arg.ReplacedByTokenID)
-	return err
+// affects no row when the token was rotated or revoked meanwhile, so only
+// one of two concurrent refreshes wins
+func (q *Queries) RotateRefreshToken(ctx context.Context, arg RotateRefreshTokenParams) (int64, error) {
+	result, err := q.exec(ctx, q.rotateRefreshTokenStmt, rotateRefreshToken, arg.ID, arg.ReplacedByTokenID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
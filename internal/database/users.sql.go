@@ -13,9 +13,9 @@ import (
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name, email, password_hash)
+INSERT INTO users (id, created_at, updated_at, name, email, password_hash, email_verified_at)
 VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3 )
-RETURNING id, created_at, updated_at, name, email, password_hash
+RETURNING id, created_at, updated_at, name, email, password_hash, email_verified_at
 `
 
 type CreateUserParams struct {
@@ -34,6 +34,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Name,
 		&i.Email,
 		&i.PasswordHash,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
@@ -48,7 +49,7 @@ func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, name, email, password_hash FROM users WHERE email = $1
+SELECT id, created_at, updated_at, name, email, password_hash, email_verified_at FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -61,12 +62,13 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Name,
 		&i.Email,
 		&i.PasswordHash,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, created_at, updated_at, name, email, password_hash FROM users WHERE id = $1
+SELECT id, created_at, updated_at, name, email, password_hash, email_verified_at FROM users WHERE id = $1
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
@@ -79,6 +81,36 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.Name,
 		&i.Email,
 		&i.PasswordHash,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const markEmailVerified = `-- name: MarkEmailVerified :one
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+WHERE id = $1 AND email = $2
+RETURNING id, created_at, updated_at, name, email, password_hash, email_verified_at
+`
+
+type MarkEmailVerifiedParams struct {
+	ID    uuid.UUID `db:"id" json:"id"`
+	Email string    `db:"email" json:"email"`
+}
+
+// the email is checked so a link for an address the user has since
+// changed away from verifies nothing
+func (q *Queries) MarkEmailVerified(ctx context.Context, arg MarkEmailVerifiedParams) (User, error) {
+	row := q.queryRow(ctx, q.markEmailVerifiedStmt, markEmailVerified, arg.ID, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Email,
+		&i.PasswordHash,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
@@ -89,9 +121,10 @@ SET
     name = COALESCE($2, name),
     email = COALESCE($3, email),
     password_hash = COALESCE($4, password_hash),
+    email_verified_at = CASE WHEN email = $3 THEN email_verified_at END,
     updated_at = NOW() 
 WHERE id = $1 
-RETURNING id, created_at, updated_at, name, email, password_hash
+RETURNING id, created_at, updated_at, name, email, password_hash, email_verified_at
 `
 
 type UpdateUserParams struct {
@@ -101,6 +134,7 @@ type UpdateUserParams struct {
 	PasswordHash string         `db:"password_hash" json:"password_hash"`
 }
 
+// a changed email has to be verified again
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
 	row := q.queryRow(ctx, q.updateUserStmt, updateUser,
 		arg.ID,
@@ -116,6 +150,7 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Name,
 		&i.Email,
 		&i.PasswordHash,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
@@ -4,7 +4,10 @@ import "github.com/Anything-That-Works/GoPath/internal/model"
 
 type contextKey string
 
-const contextKeyUserID contextKey = "userID"
+const (
+	contextKeyUserID    contextKey = "userID"
+	contextKeySessionID contextKey = "sessionID"
+)
 
 type Handler struct {
 	ApiConfig *model.ApiConfig
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor builds an opaque keyset cursor from a row's sort key. Clients
+// pass it back unchanged as before/after.
+func encodeCursor(t time.Time, id uuid.UUID) *string {
+	raw := t.UTC().Format(time.RFC3339Nano) + "|" + id.String()
+	cursor := base64.RawURLEncoding.EncodeToString([]byte(raw))
+	return &cursor
+}
+
+func decodeCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errInvalidCursor
+	}
+
+	ts, idStr, found := strings.Cut(string(raw), "|")
+	if !found {
+		return time.Time{}, uuid.Nil, errInvalidCursor
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errInvalidCursor
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errInvalidCursor
+	}
+	return t, id, nil
+}
+
+// encodeSearchCursor is the search equivalent of encodeCursor: results are
+// ordered by rank, so the rank takes the place of the timestamp.
+func encodeSearchCursor(rank float32, id uuid.UUID) *string {
+	raw := strconv.FormatFloat(float64(rank), 'g', -1, 32) + "|" + id.String()
+	cursor := base64.RawURLEncoding.EncodeToString([]byte(raw))
+	return &cursor
+}
+
+func decodeSearchCursor(cursor string) (float32, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, uuid.Nil, errInvalidCursor
+	}
+
+	rankStr, idStr, found := strings.Cut(string(raw), "|")
+	if !found {
+		return 0, uuid.Nil, errInvalidCursor
+	}
+
+	rank, err := strconv.ParseFloat(rankStr, 32)
+	if err != nil {
+		return 0, uuid.Nil, errInvalidCursor
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return 0, uuid.Nil, errInvalidCursor
+	}
+	return float32(rank), id, nil
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// sniffLen is how much of a file detectMimeType looks at.
+const sniffLen = 512
+
+// detectMimeType sniffs the first bytes of a file, filling in the allowed
+// formats http.DetectContentType doesn't know.
+func detectMimeType(head []byte) string {
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+
+	switch detected := http.DetectContentType(head); detected {
+	case "application/ogg":
+		return "audio/ogg"
+	case "application/octet-stream":
+		// QuickTime shares the ISO media layout with mp4 but has its own brand
+		if len(head) >= 12 && bytes.Equal(head[4:12], []byte("ftypqt  ")) {
+			return "video/quicktime"
+		}
+		// MP3 without an ID3 tag starts straight on an MPEG audio frame sync
+		if len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0 {
+			return "audio/mpeg"
+		}
+		return detected
+	default:
+		return detected
+	}
+}
+
+// verifyMimeType checks the sniffed content against the declared type and
+// returns the type to store, or false when they disagree.
+func verifyMimeType(head []byte, declared string) (string, bool) {
+	detected := detectMimeType(head)
+
+	// Office Open XML documents are zip archives led by [Content_Types].xml;
+	// telling docx from xlsx would mean reading the archive, so trust the
+	// declared type once the container checks out
+	if detected == "application/zip" && isOfficeOpenXML(declared) {
+		return declared, bytes.Contains(head, []byte("[Content_Types].xml"))
+	}
+
+	return detected, detected == declared && allowedMimeTypes[detected]
+}
+
+func isOfficeOpenXML(mimeType string) bool {
+	return mimeType == "application/vnd.openxmlformats-officedocument.wordprocessingml.document" ||
+		mimeType == "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+}
@@ -1,9 +1,9 @@
 package handler
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
 
@@ -39,6 +39,10 @@ func (handler *Handler) HandlerCreateConversation(w http.ResponseWriter, r *http
 		return
 	}
 
+	if !handler.requireVerifiedEmail(w, r, userID) {
+		return
+	}
+
 	if params.IsGroup && (params.Name == nil || *params.Name == "") {
 		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Group name required"})
 		return
@@ -111,9 +115,7 @@ func (handler *Handler) HandlerCreateConversation(w http.ResponseWriter, r *http
 		}
 	}
 
-	if err := handler.ApiConfig.Cache.DeleteByPattern(r.Context(), fmt.Sprintf("conversations:list:%s:*", userID.String())); err != nil {
-		log.Printf("Failed to invalidate conversations cache: %v", err)
-	}
+	handler.invalidateConversationLists(r.Context(), conversation.ID)
 
 	respondWithJSON(w, 201, model.APIResponse{
 		Success: true,
@@ -182,6 +184,9 @@ func (handler *Handler) HandlerAddMember(w http.ResponseWriter, r *http.Request)
 	if err := handler.ApiConfig.Cache.Delete(r.Context(), cache.KeyConversationMembers(params.ConversationID.String())); err != nil {
 		log.Printf("Failed to invalidate conversation members cache: %v", err)
 	}
+	if err := handler.ApiConfig.Cache.Delete(r.Context(), cache.KeyConversationsList(params.UserID.String())); err != nil {
+		log.Printf("Failed to invalidate conversations cache: %v", err)
+	}
 
 	respondWithJSON(w, 200, model.APIResponse{
 		Success: true,
@@ -262,6 +267,8 @@ func (handler *Handler) HandlerRemoveMember(w http.ResponseWriter, r *http.Reque
 		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to remove member"})
 		return
 	}
+	// their open connections stop receiving the conversation's events
+	handler.ApiConfig.Hub.RemoveUsers(params.ConversationID, []uuid.UUID{params.UserID})
 
 	// if super admin removed themselves, assign new super admin
 	if isSelf && requester.Role == database.MemberRoleSuperAdmin {
@@ -285,6 +292,9 @@ func (handler *Handler) HandlerRemoveMember(w http.ResponseWriter, r *http.Reque
 	if err := handler.ApiConfig.Cache.Delete(r.Context(), cache.KeyConversationMembers(params.ConversationID.String())); err != nil {
 		log.Printf("Failed to invalidate conversation members cache: %v", err)
 	}
+	if err := handler.ApiConfig.Cache.Delete(r.Context(), cache.KeyConversationsList(params.UserID.String())); err != nil {
+		log.Printf("Failed to invalidate conversations cache: %v", err)
+	}
 
 	respondWithJSON(w, 200, model.APIResponse{
 		Success: true,
@@ -473,9 +483,7 @@ func (handler *Handler) HandlerRenameGroup(w http.ResponseWriter, r *http.Reques
 		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to rename group"})
 		return
 	}
-	if err := handler.ApiConfig.Cache.DeleteByPattern(r.Context(), fmt.Sprintf("conversations:list:%s:*", userID.String())); err != nil {
-		log.Printf("Failed to invalidate conversations cache: %v", err)
-	}
+	handler.invalidateConversationLists(r.Context(), params.ConversationID)
 	respondWithJSON(w, 200, model.APIResponse{
 		Success: true,
 		Message: "Group renamed successfully",
@@ -483,6 +491,21 @@ func (handler *Handler) HandlerRenameGroup(w http.ResponseWriter, r *http.Reques
 	})
 }
 
+// invalidateConversationLists drops the cached inbox of every member, since
+// each shows the conversation's name, last message and unread count.
+func (handler *Handler) invalidateConversationLists(ctx context.Context, conversationID uuid.UUID) {
+	members, err := handler.ApiConfig.DB.GetConversationMembers(ctx, conversationID)
+	if err != nil {
+		log.Printf("Failed to fetch members for cache invalidation: %v", err)
+		return
+	}
+	for _, member := range members {
+		if err := handler.ApiConfig.Cache.Delete(ctx, cache.KeyConversationsList(member.UserID.String())); err != nil {
+			log.Printf("Failed to invalidate conversations cache: %v", err)
+		}
+	}
+}
+
 func (handler *Handler) HandlerGetConversations(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
 	if !ok {
@@ -491,8 +514,10 @@ func (handler *Handler) HandlerGetConversations(w http.ResponseWriter, r *http.R
 	}
 
 	type parameters struct {
-		Limit int32 `json:"limit"`
-		Page  int32 `json:"page"`
+		Limit  int32  `json:"limit"`
+		Page   int32  `json:"page"`
+		Before string `json:"before"`
+		After  string `json:"after"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -502,6 +527,11 @@ func (handler *Handler) HandlerGetConversations(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	if params.Before != "" && params.After != "" {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Use either before or after, not both"})
+		return
+	}
+
 	if params.Limit == 0 {
 		params.Limit = 20
 	}
@@ -509,53 +539,105 @@ func (handler *Handler) HandlerGetConversations(w http.ResponseWriter, r *http.R
 		params.Page = 1
 	}
 
-	cacheKey := cache.KeyConversationsList(userID.String(), params.Page, params.Limit)
+	cacheKey := cache.KeyConversationsList(userID.String())
+	cacheField := cache.FieldConversationsListPage(params.Page, params.Limit)
+	switch {
+	case params.Before != "":
+		cacheField = cache.FieldConversationsListCursor("before", params.Before, params.Limit)
+	case params.After != "":
+		cacheField = cache.FieldConversationsListCursor("after", params.After, params.Limit)
+	}
+
+	respond := func(data map[string]interface{}) {
+		data["limit"] = params.Limit
+		if params.Before == "" && params.After == "" {
+			data["page"] = params.Page
+		}
+		respondWithJSON(w, 200, model.APIResponse{
+			Success: true,
+			Message: "Conversations fetched successfully",
+			Data:    data,
+		})
+	}
 
 	// try cache first
-	if cached, err := handler.ApiConfig.Cache.Get(r.Context(), cacheKey); err == nil {
-		var conversations interface{}
-		if err := json.Unmarshal([]byte(cached), &conversations); err == nil {
-			respondWithJSON(w, 200, model.APIResponse{
-				Success: true,
-				Message: "Conversations fetched successfully",
-				Data: map[string]interface{}{
-					"conversations": conversations,
-					"limit":         params.Limit,
-					"page":          params.Page,
-				},
-			})
+	if cached, err := handler.ApiConfig.Cache.GetField(r.Context(), cacheKey, cacheField); err == nil {
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(cached), &data); err == nil {
+			respond(data)
 			return
 		}
 	}
 
-	offset := (params.Page - 1) * params.Limit
-
-	conversations, err := handler.ApiConfig.DB.GetUserConversations(r.Context(), database.GetUserConversationsParams{
-		UserID: userID,
-		Limit:  params.Limit,
-		Offset: offset,
-	})
+	var rows []database.GetUserConversationsRow
+	var err error
+	switch {
+	case params.Before != "":
+		cursorAt, cursorID, cerr := decodeCursor(params.Before)
+		if cerr != nil {
+			respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid cursor"})
+			return
+		}
+		var before []database.GetUserConversationsBeforeRow
+		before, err = handler.ApiConfig.DB.GetUserConversationsBefore(r.Context(), database.GetUserConversationsBeforeParams{
+			UserID:          userID,
+			Limit:           params.Limit,
+			CursorUpdatedAt: cursorAt,
+			CursorID:        cursorID,
+		})
+		for _, row := range before {
+			rows = append(rows, database.GetUserConversationsRow(row))
+		}
+	case params.After != "":
+		cursorAt, cursorID, cerr := decodeCursor(params.After)
+		if cerr != nil {
+			respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid cursor"})
+			return
+		}
+		var after []database.GetUserConversationsAfterRow
+		after, err = handler.ApiConfig.DB.GetUserConversationsAfter(r.Context(), database.GetUserConversationsAfterParams{
+			UserID:          userID,
+			Limit:           params.Limit,
+			CursorUpdatedAt: cursorAt,
+			CursorID:        cursorID,
+		})
+		// fetched oldest first to stay next to the cursor; the inbox is newest first
+		for i := len(after) - 1; i >= 0; i-- {
+			rows = append(rows, database.GetUserConversationsRow(after[i]))
+		}
+	default:
+		rows, err = handler.ApiConfig.DB.GetUserConversations(r.Context(), database.GetUserConversationsParams{
+			UserID: userID,
+			Limit:  params.Limit,
+			Offset: (params.Page - 1) * params.Limit,
+		})
+	}
 	if err != nil {
 		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to fetch conversations"})
 		return
 	}
 
+	data := map[string]interface{}{
+		"conversations": model.DatabaseConversationRowsToListItems(rows),
+		"next_cursor":   nil,
+		"prev_cursor":   nil,
+	}
+	if len(rows) > 0 {
+		first, last := rows[0].Conversation, rows[len(rows)-1].Conversation
+		data["prev_cursor"] = encodeCursor(first.UpdatedAt, first.ID)
+		if len(rows) == int(params.Limit) || params.After != "" {
+			data["next_cursor"] = encodeCursor(last.UpdatedAt, last.ID)
+		}
+	}
+
 	// store in cache
-	if data, err := json.Marshal(conversations); err == nil {
-		if err := handler.ApiConfig.Cache.Set(r.Context(), cacheKey, string(data), cache.TTLConversationsList); err != nil {
+	if cached, err := json.Marshal(data); err == nil {
+		if err := handler.ApiConfig.Cache.SetField(r.Context(), cacheKey, cacheField, string(cached), cache.TTLConversationsList); err != nil {
 			log.Printf("Failed to cache conversations list: %v", err)
 		}
 	}
 
-	respondWithJSON(w, 200, model.APIResponse{
-		Success: true,
-		Message: "Conversations fetched successfully",
-		Data: map[string]interface{}{
-			"conversations": conversations,
-			"limit":         params.Limit,
-			"page":          params.Page,
-		},
-	})
+	respond(data)
 }
 
 func (handler *Handler) HandlerGetConversationMembers(w http.ResponseWriter, r *http.Request) {
@@ -635,6 +717,8 @@ func (handler *Handler) HandlerGetMessages(w http.ResponseWriter, r *http.Reques
 		ConversationID uuid.UUID `json:"conversation_id"`
 		Limit          int32     `json:"limit"`
 		Page           int32     `json:"page"`
+		Before         string    `json:"before"`
+		After          string    `json:"after"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -649,6 +733,11 @@ func (handler *Handler) HandlerGetMessages(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if params.Before != "" && params.After != "" {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Use either before or after, not both"})
+		return
+	}
+
 	if params.Limit == 0 {
 		params.Limit = 50
 	}
@@ -656,8 +745,6 @@ func (handler *Handler) HandlerGetMessages(w http.ResponseWriter, r *http.Reques
 		params.Page = 1
 	}
 
-	offset := (params.Page - 1) * params.Limit
-
 	_, err := handler.ApiConfig.DB.GetConversationMember(r.Context(), database.GetConversationMemberParams{
 		ConversationID: params.ConversationID,
 		UserID:         userID,
@@ -671,16 +758,67 @@ func (handler *Handler) HandlerGetMessages(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	messages, err := handler.ApiConfig.DB.GetMessagesByConversation(r.Context(), database.GetMessagesByConversationParams{
-		ConversationID: params.ConversationID,
-		Limit:          params.Limit,
-		Offset:         offset,
-	})
+	var messages []database.GetMessagesByConversationRow
+	switch {
+	case params.Before != "":
+		cursorAt, cursorID, cerr := decodeCursor(params.Before)
+		if cerr != nil {
+			respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid cursor"})
+			return
+		}
+		var before []database.GetMessagesByConversationBeforeRow
+		before, err = handler.ApiConfig.DB.GetMessagesByConversationBefore(r.Context(), database.GetMessagesByConversationBeforeParams{
+			ConversationID:  params.ConversationID,
+			Limit:           params.Limit,
+			CursorCreatedAt: cursorAt,
+			CursorID:        cursorID,
+		})
+		for _, row := range before {
+			messages = append(messages, database.GetMessagesByConversationRow(row))
+		}
+	case params.After != "":
+		cursorAt, cursorID, cerr := decodeCursor(params.After)
+		if cerr != nil {
+			respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid cursor"})
+			return
+		}
+		var after []database.GetMessagesByConversationAfterRow
+		after, err = handler.ApiConfig.DB.GetMessagesByConversationAfter(r.Context(), database.GetMessagesByConversationAfterParams{
+			ConversationID:  params.ConversationID,
+			Limit:           params.Limit,
+			CursorCreatedAt: cursorAt,
+			CursorID:        cursorID,
+		})
+		// fetched oldest first to stay next to the cursor; pages are newest first
+		for i := len(after) - 1; i >= 0; i-- {
+			messages = append(messages, database.GetMessagesByConversationRow(after[i]))
+		}
+	default:
+		messages, err = handler.ApiConfig.DB.GetMessagesByConversation(r.Context(), database.GetMessagesByConversationParams{
+			ConversationID: params.ConversationID,
+			Limit:          params.Limit,
+			Offset:         (params.Page - 1) * params.Limit,
+		})
+	}
 	if err != nil {
 		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to fetch messages"})
 		return
 	}
 
+	messageIDs := make([]uuid.UUID, 0, len(messages))
+	for _, message := range messages {
+		messageIDs = append(messageIDs, message.Message.ID)
+	}
+
+	reactions, err := handler.ApiConfig.DB.GetMessageReactions(r.Context(), database.GetMessageReactionsParams{
+		UserID:     userID,
+		MessageIds: messageIDs,
+	})
+	if err != nil {
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to fetch reactions"})
+		return
+	}
+
 	err = handler.ApiConfig.DB.UpdateLastRead(r.Context(), database.UpdateLastReadParams{
 		ConversationID: params.ConversationID,
 		UserID:         userID,
@@ -689,19 +827,36 @@ func (handler *Handler) HandlerGetMessages(w http.ResponseWriter, r *http.Reques
 		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to update last read"})
 		return
 	}
+	// unread counts in the inbox are based on last_read_at
+	if err := handler.ApiConfig.Cache.Delete(r.Context(), cache.KeyConversationsList(userID.String())); err != nil {
+		log.Printf("Failed to invalidate conversations cache: %v", err)
+	}
+
+	data := map[string]interface{}{
+		"messages":    model.ConversationRowsToMessages(messages, reactions),
+		"limit":       params.Limit,
+		"next_cursor": nil,
+		"prev_cursor": nil,
+	}
+	if params.Before == "" && params.After == "" {
+		data["page"] = params.Page
+	}
+	if len(messages) > 0 {
+		newest, oldest := messages[0].Message, messages[len(messages)-1].Message
+		data["prev_cursor"] = encodeCursor(newest.CreatedAt, newest.ID)
+		if len(messages) == int(params.Limit) || params.After != "" {
+			data["next_cursor"] = encodeCursor(oldest.CreatedAt, oldest.ID)
+		}
+	}
 
 	respondWithJSON(w, 200, model.APIResponse{
 		Success: true,
 		Message: "Messages fetched successfully",
-		Data: map[string]interface{}{
-			"messages": messages,
-			"limit":    params.Limit,
-			"page":     params.Page,
-		},
+		Data:    data,
 	})
 }
 
-func (handler *Handler) HandlerSearchMessages(w http.ResponseWriter, r *http.Request) {
+func (handler *Handler) HandlerGetThreadReplies(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
 	if !ok {
 		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Unauthorized"})
@@ -709,10 +864,9 @@ func (handler *Handler) HandlerSearchMessages(w http.ResponseWriter, r *http.Req
 	}
 
 	type parameters struct {
-		ConversationID uuid.UUID `json:"conversation_id"`
-		Query          string    `json:"query"`
-		Limit          int32     `json:"limit"`
-		Page           int32     `json:"page"`
+		MessageID uuid.UUID `json:"message_id"`
+		Limit     int32     `json:"limit"`
+		Page      int32     `json:"page"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -722,18 +876,13 @@ func (handler *Handler) HandlerSearchMessages(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	if params.ConversationID == uuid.Nil {
-		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "conversation_id required"})
-		return
-	}
-
-	if params.Query == "" {
-		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "query required"})
+	if params.MessageID == uuid.Nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "message_id required"})
 		return
 	}
 
 	if params.Limit == 0 {
-		params.Limit = 20
+		params.Limit = 50
 	}
 	if params.Page == 0 {
 		params.Page = 1
@@ -741,9 +890,24 @@ func (handler *Handler) HandlerSearchMessages(w http.ResponseWriter, r *http.Req
 
 	offset := (params.Page - 1) * params.Limit
 
-	// verify membership
-	_, err := handler.ApiConfig.DB.GetConversationMember(r.Context(), database.GetConversationMemberParams{
-		ConversationID: params.ConversationID,
+	root, err := handler.ApiConfig.DB.GetMessageByID(r.Context(), params.MessageID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "Message not found"})
+			return
+		}
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to fetch message"})
+		return
+	}
+	// a deleted root's content is gone, whatever replies it had
+	if root.DeletedAt.Valid {
+		respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "Message not found"})
+		return
+	}
+
+	// the caller may only read threads in conversations they belong to
+	_, err = handler.ApiConfig.DB.GetConversationMember(r.Context(), database.GetConversationMemberParams{
+		ConversationID: root.ConversationID,
 		UserID:         userID,
 	})
 	if err != nil {
@@ -755,25 +919,44 @@ func (handler *Handler) HandlerSearchMessages(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	messages, err := handler.ApiConfig.DB.SearchMessages(r.Context(), database.SearchMessagesParams{
-		ConversationID: params.ConversationID,
-		Column2:        sql.NullString{String: params.Query, Valid: true},
-		Limit:          params.Limit,
-		Offset:         offset,
+	if root.ThreadID.Valid {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Message is a thread reply, not a thread root"})
+		return
+	}
+
+	replies, err := handler.ApiConfig.DB.GetThreadReplies(r.Context(), database.GetThreadRepliesParams{
+		ThreadID: uuid.NullUUID{UUID: root.ID, Valid: true},
+		Limit:    params.Limit,
+		Offset:   offset,
 	})
 	if err != nil {
-		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to search messages"})
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to fetch replies"})
+		return
+	}
+
+	messageIDs := make([]uuid.UUID, 0, len(replies)+1)
+	messageIDs = append(messageIDs, root.ID)
+	for _, reply := range replies {
+		messageIDs = append(messageIDs, reply.ID)
+	}
+
+	reactions, err := handler.ApiConfig.DB.GetMessageReactions(r.Context(), database.GetMessageReactionsParams{
+		UserID:     userID,
+		MessageIds: messageIDs,
+	})
+	if err != nil {
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to fetch reactions"})
 		return
 	}
 
 	respondWithJSON(w, 200, model.APIResponse{
 		Success: true,
-		Message: "Search results fetched successfully",
+		Message: "Replies fetched successfully",
 		Data: map[string]interface{}{
-			"messages": messages,
-			"query":    params.Query,
-			"limit":    params.Limit,
-			"page":     params.Page,
+			"root":    model.DatabaseMessagesToMessages([]database.Message{root}, reactions)[0],
+			"replies": model.DatabaseMessagesToMessages(replies, reactions),
+			"limit":   params.Limit,
+			"page":    params.Page,
 		},
 	})
 }
@@ -885,9 +1068,8 @@ func (handler *Handler) HandlerDeleteConversation(w http.ResponseWriter, r *http
 		ConversationID: &params.ConversationID,
 		SenderID:       &userID,
 	})
-	if err := handler.ApiConfig.Cache.DeleteByPattern(r.Context(), fmt.Sprintf("conversations:list:%s:*", userID.String())); err != nil {
-		log.Printf("Failed to invalidate conversations cache: %v", err)
-	}
+	handler.ApiConfig.Hub.RemoveUsers(params.ConversationID, nil)
+	handler.invalidateConversationLists(r.Context(), params.ConversationID)
 	respondWithJSON(w, 200, model.APIResponse{
 		Success: true,
 		Message: "Conversation deleted successfully",
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"github.com/Anything-That-Works/GoPath/internal/auth"
+	"github.com/Anything-That-Works/GoPath/internal/cache"
+	"github.com/Anything-That-Works/GoPath/internal/database"
+	"github.com/Anything-That-Works/GoPath/internal/mailer"
+	"github.com/Anything-That-Works/GoPath/internal/model"
+	"github.com/google/uuid"
+)
+
+const emailVerificationTTL = 24 * time.Hour
+
+// sendVerificationEmail mails user a link that verifies their current email.
+func (handler *Handler) sendVerificationEmail(ctx context.Context, user database.User) error {
+	token, err := auth.GenerateEmailVerificationToken(user.ID, user.Email, handler.ApiConfig.JWTSecretKey, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link, err := url.Parse(handler.ApiConfig.EmailVerificationURL)
+	if err != nil {
+		return err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return handler.ApiConfig.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Follow this link to verify your email address:\n\n%s\n\n"+
+			"The link expires in %d hours. If you didn't ask for it, you can ignore this email.\n",
+			link, int(emailVerificationTTL.Hours())),
+	})
+}
+
+func (handler *Handler) HandlerVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Token required"})
+		return
+	}
+
+	userID, email, err := auth.ValidateEmailVerificationToken(token, handler.ApiConfig.JWTSecretKey)
+	if err != nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid or expired verification link"})
+		return
+	}
+
+	user, err := handler.ApiConfig.DB.MarkEmailVerified(r.Context(), database.MarkEmailVerifiedParams{
+		ID:    userID,
+		Email: email,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// the email was changed since the link was sent
+			respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid or expired verification link"})
+			return
+		}
+		log.Printf("Failed to verify email: %v", err)
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to verify email"})
+		return
+	}
+
+	if err := handler.ApiConfig.Cache.Delete(r.Context(), cache.KeyUserProfile(userID.String())); err != nil {
+		log.Println("Cache.Delete error:", err)
+	}
+
+	respondWithJSON(w, 200, model.APIResponse{
+		Success: true,
+		Message: "Email verified",
+		Data:    model.DatabaseUserToUserSummary(user),
+	})
+}
+
+func (handler *Handler) HandlerResendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
+	if !ok {
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	user, err := handler.ApiConfig.DB.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "User not found"})
+			return
+		}
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to fetch user"})
+		return
+	}
+
+	if user.EmailVerifiedAt.Valid {
+		respondWithJSON(w, 409, model.APIResponse{Success: false, Message: "Email already verified"})
+		return
+	}
+
+	if err := handler.sendVerificationEmail(r.Context(), user); err != nil {
+		log.Printf("Failed to send verification email: %v", err)
+		respondWithJSON(w, 502, model.APIResponse{Success: false, Message: "Failed to send verification email"})
+		return
+	}
+
+	respondWithJSON(w, 200, model.APIResponse{Success: true, Message: "Verification email sent"})
+}
+
+// validEmail accepts a bare address such as alice@example.com, without a
+// display name.
+func validEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
+
+// requireVerifiedEmail responds 403 and returns false unless userID has
+// verified their email.
+func (handler *Handler) requireVerifiedEmail(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	user, err := handler.ApiConfig.DB.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "User not found"})
+			return false
+		}
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to fetch user"})
+		return false
+	}
+	if !user.EmailVerifiedAt.Valid {
+		respondWithJSON(w, 403, model.APIResponse{Success: false, Message: "Verify your email address first"})
+		return false
+	}
+	return true
+}
@@ -1,13 +1,24 @@
 package handler
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"path/filepath"
 	"strings"
 
 	"github.com/Anything-That-Works/GoPath/internal/database"
+	"github.com/Anything-That-Works/GoPath/internal/media"
 	"github.com/Anything-That-Works/GoPath/internal/model"
+	"github.com/Anything-That-Works/GoPath/internal/storage"
 	"github.com/google/uuid"
 )
 
@@ -71,69 +82,753 @@ func (handler *Handler) HandlerUploadFile(w http.ResponseWriter, r *http.Request
 		}
 	}()
 
-	// validate extension
-	ext := strings.ToLower(filepath.Ext(header.Filename))
-	if !allowedExtensions[ext] {
+	mimeType := header.Header.Get("Content-Type")
+	if !isAllowedFileType(header.Filename, mimeType) {
 		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "File type not allowed"})
 		return
 	}
 
-	// validate mime type
-	mimeType := header.Header.Get("Content-Type")
-	if !allowedMimeTypes[mimeType] {
-		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "File type not allowed"})
+	conversationID, ok := handler.uploadConversation(w, r, userID, r.FormValue("conversation_id"))
+	if !ok {
+		return
+	}
+	if !handler.checkStorageQuota(w, r, userID, conversationID, header.Size) {
 		return
 	}
 
-	// save to storage
-	path, err := handler.ApiConfig.Storage.Save(file, header.Filename, mimeType)
+	// never trust the declared type alone: a renamed HTML page must not
+	// end up stored as an image
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Failed to read file"})
+		return
+	}
+	mimeType, ok = verifyMimeType(head[:n], mimeType)
+	if !ok {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "File content does not match its type"})
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to save file"})
+		return
+	}
+
+	stored, err := handler.saveUpload(r.Context(), file, header.Size, header.Filename, mimeType)
 	if err != nil {
+		if errors.Is(err, media.ErrInvalidImage) {
+			respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid image"})
+			return
+		}
+		log.Printf("Failed to save file: %v", err)
 		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to save file"})
 		return
 	}
 
 	// save to db
-	savedFile, err := handler.ApiConfig.DB.CreateFile(r.Context(), database.CreateFileParams{
-		UploaderID: userID,
-		Name:       header.Filename,
-		MimeType:   mimeType,
-		Size:       header.Size,
-		Path:       path,
+	var savedFile database.File
+	err = handler.withTx(r.Context(), func(queries *database.Queries) error {
+		var err error
+		savedFile, err = handler.createFile(r.Context(), queries, database.CreateFileParams{
+			UploaderID:     userID,
+			Name:           header.Filename,
+			MimeType:       mimeType,
+			Size:           stored.Size,
+			Path:           stored.Path,
+			OriginalPath:   stored.OriginalPath,
+			Sha256:         stored.sha256(),
+			ConversationID: conversationID,
+			ScanStatus:     handler.newFileScanStatus(),
+			UploadSha256:   stored.uploadSHA256(),
+		})
+		return err
 	})
 	if err != nil {
-		if deleteErr := handler.ApiConfig.Storage.Delete(path); deleteErr != nil {
-			fmt.Println("Failed to delete file after DB error:", deleteErr)
+		handler.deleteStoredUpload(r.Context(), stored)
+		if respondIfQuotaExceeded(w, err) {
+			return
 		}
 		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to save file metadata"})
 		return
 	}
 
+	savedFile, thumbnails := handler.describeImage(r.Context(), savedFile, file)
+
+	// the original upload is scanned: stripping metadata only removes bytes
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		log.Printf("Failed to rewind file %s for scanning: %v", savedFile.ID, err)
+	} else {
+		savedFile = handler.scanFile(r.Context(), savedFile, file)
+	}
+	if savedFile.ScanStatus == database.FileScanStatusInfected {
+		respondWithJSON(w, 422, model.APIResponse{Success: false, Message: scanRefusal(savedFile.ScanStatus)})
+		return
+	}
+
 	respondWithJSON(w, 201, model.APIResponse{
 		Success: true,
 		Message: "File uploaded successfully",
+		Data:    handler.fileData(savedFile, thumbnails),
+	})
+}
+
+// storedUpload is where an upload ended up in storage.
+type storedUpload struct {
+	Path         string
+	Size         int64
+	OriginalPath sql.NullString
+	SHA256       string // hex digest of the content at Path
+	// hex digest of an image as uploaded, before its metadata was stripped,
+	// so clients can match the copy they hold
+	UploadSHA256 string
+}
+
+func (stored storedUpload) sha256() sql.NullString {
+	return sql.NullString{String: stored.SHA256, Valid: stored.SHA256 != ""}
+}
+
+func (stored storedUpload) uploadSHA256() sql.NullString {
+	return sql.NullString{String: stored.UploadSHA256, Valid: stored.UploadSHA256 != "" && stored.UploadSHA256 != stored.SHA256}
+}
+
+// saveUpload stores content, rewriting images without their EXIF/GPS and
+// other metadata. With KeepOriginalImages the untouched upload is stored as
+// well, for the uploader alone.
+func (handler *Handler) saveUpload(ctx context.Context, content io.ReadSeeker, size int64, filename string, mimeType string) (storedUpload, error) {
+	if !media.CanStripMetadata(mimeType) {
+		return handler.saveBlob(ctx, content, size, filename, mimeType)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return storedUpload{}, err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return storedUpload{}, err
+	}
+	stored := storedUpload{UploadSHA256: hex.EncodeToString(hash.Sum(nil))}
+
+	stripped, err := media.StripMetadata(content, mimeType)
+	if err != nil {
+		return storedUpload{}, err
+	}
+
+	if handler.ApiConfig.KeepOriginalImages {
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			return storedUpload{}, err
+		}
+		originalPath, err := handler.ApiConfig.Storage.Save(content, size, filename, mimeType)
+		if err != nil {
+			return storedUpload{}, err
+		}
+		stored.OriginalPath = sql.NullString{String: originalPath, Valid: true}
+	}
+
+	blob, err := handler.saveBlob(ctx, stripped, stripped.Size, filename, mimeType)
+	if err != nil {
+		handler.deleteStoredUpload(ctx, stored)
+		return storedUpload{}, err
+	}
+	stored.Path, stored.Size, stored.SHA256 = blob.Path, blob.Size, blob.SHA256
+	return stored, nil
+}
+
+// saveBlob stores content hashed as it streams. Identical content is kept
+// once: when the hash is already known, the copy just written is dropped in
+// favour of the existing one, which gains a reference.
+func (handler *Handler) saveBlob(ctx context.Context, content io.Reader, size int64, filename string, mimeType string) (storedUpload, error) {
+	hash := sha256.New()
+	path, err := handler.ApiConfig.Storage.Save(io.TeeReader(content, hash), size, filename, mimeType)
+	if err != nil {
+		return storedUpload{}, err
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+
+	blob, err := handler.ApiConfig.DB.AcquireBlob(ctx, database.AcquireBlobParams{
+		Sha256: sum,
+		Path:   path,
+		Size:   size,
+	})
+	if err != nil || blob.Path != path {
+		if deleteErr := handler.ApiConfig.Storage.Delete(path); deleteErr != nil {
+			log.Printf("Failed to delete duplicate file %s: %v", path, deleteErr)
+		}
+	}
+	if err != nil {
+		return storedUpload{}, err
+	}
+	return storedUpload{Path: blob.Path, Size: blob.Size, SHA256: blob.Sha256}, nil
+}
+
+// deleteStoredUpload undoes a save whose file row couldn't be created.
+// Shared content only loses a reference; the blob itself stays, since
+// another upload may already be claiming it.
+func (handler *Handler) deleteStoredUpload(ctx context.Context, stored storedUpload) {
+	paths := []string{stored.OriginalPath.String}
+	if stored.SHA256 != "" {
+		if _, err := handler.ApiConfig.DB.ReleaseBlob(ctx, stored.SHA256); err != nil {
+			log.Printf("Failed to release blob %s after error: %v", stored.SHA256, err)
+		}
+	} else {
+		paths = append(paths, stored.Path)
+	}
+
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if err := handler.ApiConfig.Storage.Delete(path); err != nil {
+			log.Printf("Failed to delete file after error: %v", err)
+		}
+	}
+}
+
+func isAllowedFileType(filename string, mimeType string) bool {
+	return allowedExtensions[strings.ToLower(filepath.Ext(filename))] && allowedMimeTypes[mimeType]
+}
+
+// fileData is the upload response shared by single-request and resumable
+// uploads. Images also carry their size and placeholders so clients can lay
+// out a message before loading it. Links are left out until the file has
+// passed its scan.
+func (handler *Handler) fileData(file database.File, thumbnails []database.FileThumbnail) map[string]interface{} {
+	data := map[string]interface{}{
+		"id":          file.ID,
+		"name":        file.Name,
+		"mime_type":   file.MimeType,
+		"size":        file.Size,
+		"scan_status": file.ScanStatus,
+	}
+	if !fileServable(file) {
+		thumbnails = nil
+	} else {
+		data["url"] = handler.ApiConfig.Storage.URL(file.Path, servedFile(file))
+	}
+	if file.Sha256.Valid {
+		data["sha256"] = file.Sha256.String
+	}
+	if file.Width.Valid && file.Height.Valid {
+		data["width"] = file.Width.Int32
+		data["height"] = file.Height.Int32
+	}
+	if file.Blurhash.Valid {
+		data["blurhash"] = file.Blurhash.String
+	}
+	// upload responses only ever go to the uploader
+	if file.OriginalPath.Valid && fileServable(file) {
+		data["original_url"] = handler.ApiConfig.Storage.URL(file.OriginalPath.String, servedFile(file))
+	}
+	if len(thumbnails) > 0 {
+		thumbnailData := make([]map[string]interface{}, 0, len(thumbnails))
+		for _, thumbnail := range thumbnails {
+			thumbnailData = append(thumbnailData, map[string]interface{}{
+				"size":   thumbnail.Size,
+				"width":  thumbnail.Width,
+				"height": thumbnail.Height,
+				"url":    handler.ApiConfig.Storage.URL(thumbnail.Path, servedThumbnail(file, thumbnail)),
+			})
+		}
+		data["thumbnails"] = thumbnailData
+	}
+	return data
+}
+
+func servedFile(file database.File) storage.ServedFile {
+	return storage.ServedFile{ID: file.ID, Name: file.Name, MimeType: file.MimeType}
+}
+
+// servedThumbnail keeps the file's name, with the thumbnail's own type.
+func servedThumbnail(file database.File, thumbnail database.FileThumbnail) storage.ServedFile {
+	return storage.ServedFile{ID: file.ID, Name: file.Name, MimeType: thumbnail.MimeType}
+}
+
+// describeImage fills in the image info of a new file, copying it from an
+// earlier file with the same content when there is one. content may be nil,
+// in which case the stored file is read.
+func (handler *Handler) describeImage(ctx context.Context, file database.File, content io.ReadSeeker) (database.File, []database.FileThumbnail) {
+	if !media.IsImage(file.MimeType) {
+		return file, nil
+	}
+
+	if file.Sha256.Valid {
+		source, err := handler.ApiConfig.DB.GetProcessedFileBySHA256(ctx, database.GetProcessedFileBySHA256Params{
+			Sha256: file.Sha256,
+			ID:     file.ID,
+		})
+		if err == nil {
+			return handler.copyImageInfo(ctx, file, source)
+		}
+		if err != sql.ErrNoRows {
+			log.Printf("Failed to look up image info for %s: %v", file.ID, err)
+		}
+	}
+
+	if content == nil {
+		stored, err := handler.ApiConfig.Storage.Open(file.Path)
+		if err != nil {
+			log.Printf("Failed to open uploaded image %s: %v", file.ID, err)
+			return file, nil
+		}
+		defer func() {
+			if err := stored.Close(); err != nil {
+				log.Printf("Failed to close file: %v", err)
+			}
+		}()
+		content = stored
+	}
+	return handler.processImage(ctx, file, content)
+}
+
+// copyImageInfo gives file the dimensions, blurhash and thumbnails of
+// source, which holds the same content.
+func (handler *Handler) copyImageInfo(ctx context.Context, file database.File, source database.File) (database.File, []database.FileThumbnail) {
+	thumbnails, err := handler.ApiConfig.DB.CopyFileThumbnails(ctx, database.CopyFileThumbnailsParams{
+		ToFileID:   file.ID,
+		FromFileID: source.ID,
+	})
+	if err != nil {
+		log.Printf("Failed to copy thumbnails for %s: %v", file.ID, err)
+	}
+
+	updated, err := handler.ApiConfig.DB.SetFileImageInfo(ctx, database.SetFileImageInfoParams{
+		ID:       file.ID,
+		Width:    source.Width,
+		Height:   source.Height,
+		Blurhash: source.Blurhash,
+	})
+	if err != nil {
+		log.Printf("Failed to save image info for %s: %v", file.ID, err)
+		return file, thumbnails
+	}
+	return updated, thumbnails
+}
+
+// processImage records the dimensions, thumbnails and blurhash of an image
+// upload. Failures are only logged, as the file itself is already stored.
+func (handler *Handler) processImage(ctx context.Context, file database.File, content io.ReadSeeker) (database.File, []database.FileThumbnail) {
+	if !media.IsImage(file.MimeType) {
+		return file, nil
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		log.Printf("Failed to rewind image %s: %v", file.ID, err)
+		return file, nil
+	}
+
+	info, err := media.AnalyzeImage(content, file.MimeType)
+	if err != nil {
+		log.Printf("Failed to analyze image %s: %v", file.ID, err)
+		return file, nil
+	}
+
+	var thumbnails []database.FileThumbnail
+	for _, rendered := range info.Thumbnails {
+		path, err := handler.ApiConfig.Storage.Save(bytes.NewReader(rendered.Data), int64(len(rendered.Data)), file.Name, rendered.MimeType)
+		if err != nil {
+			log.Printf("Failed to save thumbnail for %s: %v", file.ID, err)
+			continue
+		}
+		thumbnail, err := handler.ApiConfig.DB.CreateFileThumbnail(ctx, database.CreateFileThumbnailParams{
+			FileID:   file.ID,
+			Size:     int32(rendered.Size),
+			Width:    int32(rendered.Width),
+			Height:   int32(rendered.Height),
+			MimeType: rendered.MimeType,
+			Path:     path,
+		})
+		if err != nil {
+			log.Printf("Failed to save thumbnail metadata for %s: %v", file.ID, err)
+			if deleteErr := handler.ApiConfig.Storage.Delete(path); deleteErr != nil {
+				log.Printf("Failed to delete thumbnail after DB error: %v", deleteErr)
+			}
+			continue
+		}
+		thumbnails = append(thumbnails, thumbnail)
+	}
+
+	updated, err := handler.ApiConfig.DB.SetFileImageInfo(ctx, database.SetFileImageInfoParams{
+		ID:       file.ID,
+		Width:    sql.NullInt32{Int32: int32(info.Width), Valid: true},
+		Height:   sql.NullInt32{Int32: int32(info.Height), Valid: true},
+		Blurhash: sql.NullString{String: info.Blurhash, Valid: info.Blurhash != ""},
+	})
+	if err != nil {
+		log.Printf("Failed to save image info for %s: %v", file.ID, err)
+		return file, thumbnails
+	}
+	return updated, thumbnails
+}
+
+func (handler *Handler) HandlerServeFile(w http.ResponseWriter, r *http.Request) {
+	filename := r.URL.Path[len("/v1/files/"):]
+	if filename == "" {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Filename required"})
+		return
+	}
+
+	// a signed URL is proof the holder was given access to the file it names
+	// when it was issued
+	if r.URL.Query().Has("signature") {
+		if err := handler.ApiConfig.URLSigner.Verify(filename, r.URL.Query()); err != nil {
+			if err == storage.ErrURLExpired {
+				respondWithJSON(w, 403, model.APIResponse{Success: false, Message: "File link expired"})
+				return
+			}
+			respondWithJSON(w, 403, model.APIResponse{Success: false, Message: "Invalid file link"})
+			return
+		}
+
+		fileID, err := uuid.Parse(r.URL.Query().Get("file"))
+		if err != nil {
+			respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "File not found"})
+			return
+		}
+		file, err := handler.getServedFile(r.Context(), filename, fileID)
+		if err != nil {
+			respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "File not found"})
+			return
+		}
+		handler.serveFile(w, r, file)
+		return
+	}
+
+	handler.MiddlewareAuth(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
+		if !ok {
+			respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Unauthorized"})
+			return
+		}
+
+		// without a file named, serve the caller's own file at the path, or
+		// one shared with them
+		fileID, err := uuid.Parse(r.URL.Query().Get("file"))
+		if err != nil {
+			accessible, err := handler.ApiConfig.DB.GetAccessibleFileByPath(r.Context(), database.GetAccessibleFileByPathParams{
+				Path:   filename,
+				UserID: userID,
+			})
+			if err != nil {
+				respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "File not found"})
+				return
+			}
+			fileID = accessible.ID
+		}
+
+		file, err := handler.getServedFile(r.Context(), filename, fileID)
+		if err != nil {
+			respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "File not found"})
+			return
+		}
+
+		// answer 404 rather than 403 so file names can't be probed
+		if !handler.canAccessContent(w, r, file, userID) {
+			return
+		}
+		if file.OriginalPath.Valid && file.Path == file.OriginalPath.String && file.UploaderID != userID {
+			respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "File not found"})
+			return
+		}
+		handler.serveFile(w, r, file)
+	})(w, r)
+}
+
+// HandlerGetFileURL issues a fresh signed URL, e.g. once the one delivered
+// with a message has expired.
+func (handler *Handler) HandlerGetFileURL(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
+	if !ok {
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	type parameters struct {
+		FileID uuid.UUID `json:"file_id"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid request payload"})
+		return
+	}
+
+	if params.FileID == uuid.Nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "file_id required"})
+		return
+	}
+
+	if !handler.canAccessFile(w, r, params.FileID, userID) {
+		return
+	}
+
+	file, err := handler.ApiConfig.DB.GetFileByID(r.Context(), params.FileID)
+	if err != nil {
+		respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "File not found"})
+		return
+	}
+	if !fileServable(file) {
+		respondWithJSON(w, 403, model.APIResponse{Success: false, Message: scanRefusal(file.ScanStatus)})
+		return
+	}
+
+	respondWithJSON(w, 200, model.APIResponse{
+		Success: true,
+		Message: "File URL created successfully",
 		Data: map[string]interface{}{
-			"id":        savedFile.ID,
-			"name":      savedFile.Name,
-			"mime_type": savedFile.MimeType,
-			"size":      savedFile.Size,
-			"url":       handler.ApiConfig.Storage.URL(savedFile.Path),
+			"id":  file.ID,
+			"url": handler.ApiConfig.Storage.URL(file.Path, servedFile(file)),
 		},
 	})
 }
 
-func (handler *Handler) HandlerServeFile(w http.ResponseWriter, r *http.Request) {
+// getServedFile resolves a served path to the file it was requested for.
+// Deduplicated files share paths, so the name, type and scan status served
+// are always that file's own. Originals and thumbnails resolve to the file
+// they belong to, carrying their own path (and, for thumbnails, type) so
+// access checks treat them alike.
+func (handler *Handler) getServedFile(ctx context.Context, path string, fileID uuid.UUID) (database.File, error) {
+	file, err := handler.ApiConfig.DB.GetFileByID(ctx, fileID)
+	if err != nil {
+		return database.File{}, err
+	}
+	if file.Path == path {
+		return file, nil
+	}
+	if file.OriginalPath.Valid && file.OriginalPath.String == path {
+		file.Path = path
+		return file, nil
+	}
+
+	thumbnails, err := handler.ApiConfig.DB.GetFileThumbnails(ctx, file.ID)
+	if err != nil {
+		return database.File{}, err
+	}
+	for _, thumbnail := range thumbnails {
+		if thumbnail.Path == path {
+			file.Path = thumbnail.Path
+			file.MimeType = thumbnail.MimeType
+			return file, nil
+		}
+	}
+	return database.File{}, sql.ErrNoRows
+}
+
+// HandlerGetOriginalFileURL gives the uploader a signed URL for the
+// untouched original of an image, when the deployment keeps those.
+func (handler *Handler) HandlerGetOriginalFileURL(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
 	if !ok {
 		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Unauthorized"})
 		return
 	}
-	_ = userID // can be used for access control later
 
-	filename := r.URL.Path[len("/v1/files/"):]
-	if filename == "" {
-		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Filename required"})
+	type parameters struct {
+		FileID uuid.UUID `json:"file_id"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid request payload"})
+		return
+	}
+
+	if params.FileID == uuid.Nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "file_id required"})
+		return
+	}
+
+	file, err := handler.ApiConfig.DB.GetFileByID(r.Context(), params.FileID)
+	if err != nil || file.UploaderID != userID || !file.OriginalPath.Valid {
+		respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "Original not found"})
+		return
+	}
+	if !fileServable(file) {
+		respondWithJSON(w, 403, model.APIResponse{Success: false, Message: scanRefusal(file.ScanStatus)})
+		return
+	}
+
+	respondWithJSON(w, 200, model.APIResponse{
+		Success: true,
+		Message: "File URL created successfully",
+		Data: map[string]interface{}{
+			"id":  file.ID,
+			"url": handler.ApiConfig.Storage.URL(file.OriginalPath.String, servedFile(file)),
+		},
+	})
+}
+
+// HandlerCreateFileFromHash lets a client skip uploading content the server
+// already holds, creating a file of its own for it. Only content the caller
+// can already see is offered, so hashes can't be used to probe what others
+// have uploaded; a 404 means the client should upload as usual. An image
+// matches both the hash of the file the client sent and that of the copy
+// stored without its metadata.
+func (handler *Handler) HandlerCreateFileFromHash(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
+	if !ok {
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	type parameters struct {
+		SHA256         string `json:"sha256"`
+		Name           string `json:"name"`
+		ConversationID string `json:"conversation_id"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid request payload"})
+		return
+	}
+
+	params.SHA256 = strings.ToLower(params.SHA256)
+	if digest, err := hex.DecodeString(params.SHA256); err != nil || len(digest) != sha256.Size {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "sha256 must be a hex SHA-256 digest"})
+		return
+	}
+	if params.Name == "" {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "name required"})
+		return
+	}
+
+	source, err := handler.ApiConfig.DB.GetAccessibleFileBySHA256(r.Context(), database.GetAccessibleFileBySHA256Params{
+		Sha256: sql.NullString{String: params.SHA256, Valid: true},
+		UserID: userID,
+	})
+	if err == sql.ErrNoRows {
+		respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "File not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to look up file by hash: %v", err)
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to look up file"})
+		return
+	}
+
+	if !isAllowedFileType(params.Name, source.MimeType) {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "File type not allowed"})
+		return
+	}
+	if source.ScanStatus == database.FileScanStatusInfected {
+		respondWithJSON(w, 422, model.APIResponse{Success: false, Message: scanRefusal(source.ScanStatus)})
+		return
+	}
+
+	conversationID, ok := handler.uploadConversation(w, r, userID, params.ConversationID)
+	if !ok {
+		return
+	}
+	if !handler.checkStorageQuota(w, r, userID, conversationID, source.Size) {
+		return
+	}
+
+	// the hash may be of an image as uploaded, rather than of what is stored
+	blob, err := handler.ApiConfig.DB.AddBlobRef(r.Context(), source.Sha256.String)
+	if err != nil {
+		log.Printf("Failed to reference blob %s: %v", source.Sha256.String, err)
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to save file metadata"})
+		return
+	}
+	stored := storedUpload{Path: blob.Path, Size: blob.Size, SHA256: blob.Sha256, UploadSHA256: params.SHA256}
+
+	var savedFile database.File
+	err = handler.withTx(r.Context(), func(queries *database.Queries) error {
+		var err error
+		savedFile, err = handler.createFile(r.Context(), queries, database.CreateFileParams{
+			UploaderID:     userID,
+			Name:           params.Name,
+			MimeType:       source.MimeType,
+			Size:           stored.Size,
+			Path:           stored.Path,
+			Sha256:         stored.sha256(),
+			ConversationID: conversationID,
+			ScanStatus:     handler.newFileScanStatus(),
+			UploadSha256:   stored.uploadSHA256(),
+		})
+		return err
+	})
+	if err != nil {
+		handler.deleteStoredUpload(r.Context(), stored)
+		if respondIfQuotaExceeded(w, err) {
+			return
+		}
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to save file metadata"})
+		return
+	}
+
+	savedFile, thumbnails := handler.describeImage(r.Context(), savedFile, nil)
+	savedFile = handler.scanStoredFile(r.Context(), savedFile)
+
+	respondWithJSON(w, 201, model.APIResponse{
+		Success: true,
+		Message: "File created successfully",
+		Data:    handler.fileData(savedFile, thumbnails),
+	})
+}
+
+// canAccessContent checks access to a served file. Deduplicated content is
+// visible to anyone who can see one of the files holding it; originals stay
+// with their own file.
+func (handler *Handler) canAccessContent(w http.ResponseWriter, r *http.Request, file database.File, userID uuid.UUID) bool {
+	if !file.Sha256.Valid || (file.OriginalPath.Valid && file.Path == file.OriginalPath.String) {
+		return handler.canAccessFile(w, r, file.ID, userID)
+	}
+
+	allowed, err := handler.ApiConfig.DB.CanAccessContent(r.Context(), database.CanAccessContentParams{
+		Sha256: file.Sha256,
+		UserID: userID,
+	})
+	if err != nil {
+		log.Printf("Failed to check file access: %v", err)
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to check file access"})
+		return false
+	}
+	if !allowed {
+		respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "File not found"})
+		return false
+	}
+	return true
+}
+
+func (handler *Handler) canAccessFile(w http.ResponseWriter, r *http.Request, fileID uuid.UUID, userID uuid.UUID) bool {
+	allowed, err := handler.ApiConfig.DB.CanAccessFile(r.Context(), database.CanAccessFileParams{
+		FileID: fileID,
+		UserID: userID,
+	})
+	if err != nil {
+		log.Printf("Failed to check file access: %v", err)
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to check file access"})
+		return false
+	}
+	if !allowed {
+		respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "File not found"})
+		return false
+	}
+	return true
+}
+
+func (handler *Handler) serveFile(w http.ResponseWriter, r *http.Request, file database.File) {
+	if !fileServable(file) {
+		respondWithJSON(w, 403, model.APIResponse{Success: false, Message: scanRefusal(file.ScanStatus)})
+		return
+	}
+
+	content, err := handler.ApiConfig.Storage.Open(file.Path)
+	if err != nil {
+		log.Printf("Failed to open file %s: %v", file.Path, err)
+		respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "File not found"})
 		return
 	}
+	defer func() {
+		if err := content.Close(); err != nil {
+			log.Printf("Failed to close file: %v", err)
+		}
+	}()
 
-	http.ServeFile(w, r, filepath.Join("./uploads", filename))
+	w.Header().Set("Content-Type", file.MimeType)
+	w.Header().Set("Content-Disposition", storage.ContentDisposition(file.MimeType, file.Name))
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	http.ServeContent(w, r, file.Name, file.CreatedAt, content)
 }
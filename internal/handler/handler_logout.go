@@ -3,6 +3,7 @@ package handler
 import (
 	"net/http"
 
+	"github.com/Anything-That-Works/GoPath/internal/database"
 	"github.com/Anything-That-Works/GoPath/internal/model"
 	"github.com/google/uuid"
 )
@@ -14,7 +15,13 @@ func (handler *Handler) HandlerLogout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := handler.ApiConfig.DB.RevokeRefreshToken(r.Context(), userID)
+	sessionID, _ := r.Context().Value(contextKeySessionID).(uuid.UUID)
+
+	// revokes only the caller's refresh token; other devices stay logged in
+	_, err := handler.ApiConfig.DB.RevokeSession(r.Context(), database.RevokeSessionParams{
+		UserID:    userID,
+		SessionID: sessionID,
+	})
 	if err != nil {
 		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to logout"})
 		return
@@ -61,7 +61,7 @@ func (handler *Handler) HandlerLogoutAll(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	tr, tokenHash, err := auth.GenerateToken(userID, handler.ApiConfig.JWTSecretKey)
+	tr, tokenHash, err := auth.GenerateToken(userID, existing.SessionID, handler.ApiConfig.JWTSecretKey)
 	if err != nil {
 		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to generate token"})
 		return
@@ -73,6 +73,7 @@ func (handler *Handler) HandlerLogoutAll(w http.ResponseWriter, r *http.Request)
 		ExpiresAt: tr.RefreshToken.Expires,
 		UserAgent: sql.NullString{String: r.UserAgent(), Valid: true},
 		IpAddress: handler.getIPAddress(r),
+		SessionID: existing.SessionID, // the caller stays logged in
 	})
 	if err != nil {
 		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to store refresh token"})
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Anything-That-Works/GoPath/internal/auth"
+	"github.com/Anything-That-Works/GoPath/internal/database"
+	"github.com/Anything-That-Works/GoPath/internal/mailer"
+	"github.com/Anything-That-Works/GoPath/internal/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	passwordResetTTL = 30 * time.Minute
+	// at most this many reset emails per user per hour
+	maxPasswordResetsPerHour = 3
+)
+
+func (handler *Handler) HandlerForgotPassword(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid request payload"})
+		return
+	}
+
+	if params.Email == "" {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Email required"})
+		return
+	}
+
+	// the lookup and the mail happen after responding, so neither the answer
+	// nor its timing tells whether the email has an account
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := handler.sendPasswordReset(ctx, params.Email); err != nil {
+			log.Printf("Failed to send password reset: %v", err)
+		}
+	}()
+
+	respondWithJSON(w, 200, model.APIResponse{
+		Success: true,
+		Message: "If an account exists for that email, a password reset link has been sent",
+	})
+}
+
+func (handler *Handler) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := handler.ApiConfig.DB.GetUserByEmail(ctx, email)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	recent, err := handler.ApiConfig.DB.CountRecentPasswordResetTokens(ctx, database.CountRecentPasswordResetTokensParams{
+		UserID:    user.ID,
+		CreatedAt: time.Now().Add(-time.Hour),
+	})
+	if err != nil {
+		return err
+	}
+	if recent >= maxPasswordResetsPerHour {
+		return nil
+	}
+
+	token, tokenHash, err := auth.GeneratePasswordResetToken()
+	if err != nil {
+		return err
+	}
+	if _, err := handler.ApiConfig.DB.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	}); err != nil {
+		return err
+	}
+
+	instructions := fmt.Sprintf("Use this code to reset your password:\n\n%s\n\n", token)
+	if handler.ApiConfig.PasswordResetURL != "" {
+		link, err := url.Parse(handler.ApiConfig.PasswordResetURL)
+		if err != nil {
+			return err
+		}
+		query := link.Query()
+		query.Set("token", token)
+		link.RawQuery = query.Encode()
+		instructions = fmt.Sprintf("Follow this link to reset your password:\n\n%s\n\n", link)
+	}
+
+	return handler.ApiConfig.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: instructions + fmt.Sprintf("It expires in %d minutes and works once. "+
+			"If you didn't ask to reset your password, you can ignore this email.\n",
+			int(passwordResetTTL.Minutes())),
+	})
+}
+
+func (handler *Handler) HandlerResetPassword(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid request payload"})
+		return
+	}
+
+	if params.Token == "" || params.Password == "" {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Token and password required"})
+		return
+	}
+
+	// hash before the transaction rather than holding it open for bcrypt
+	hash, err := bcrypt.GenerateFromPassword([]byte(params.Password), bcrypt.DefaultCost)
+	if err != nil {
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to process password"})
+		return
+	}
+
+	// the token is only spent if the password changes, and the password only
+	// changes if whoever knew the old one is logged out everywhere
+	err = handler.withTx(r.Context(), func(queries *database.Queries) error {
+		resetToken, err := queries.UsePasswordResetToken(r.Context(), auth.HashToken(params.Token))
+		if err != nil {
+			return err
+		}
+		if err := queries.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+			ID:           resetToken.UserID,
+			PasswordHash: string(hash),
+		}); err != nil {
+			return err
+		}
+		if err := queries.RevokeAllUserRefreshTokens(r.Context(), resetToken.UserID); err != nil {
+			return err
+		}
+		return queries.InvalidatePasswordResetTokens(r.Context(), resetToken.UserID)
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid or expired reset token"})
+			return
+		}
+		log.Printf("Failed to reset password: %v", err)
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to reset password"})
+		return
+	}
+
+	respondWithJSON(w, 200, model.APIResponse{Success: true, Message: "Password reset successfully"})
+}
@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Anything-That-Works/GoPath/internal/database"
+	"github.com/Anything-That-Works/GoPath/internal/model"
+	"github.com/Anything-That-Works/GoPath/internal/ws"
+	"github.com/google/uuid"
+)
+
+// canManagePins reports whether userID may pin and unpin in the conversation:
+// any member of a direct chat, admins and the super admin of a group.
+func (handler *Handler) canManagePins(w http.ResponseWriter, r *http.Request, conversationID uuid.UUID, userID uuid.UUID) bool {
+	conversation, err := handler.ApiConfig.DB.GetConversationByID(r.Context(), conversationID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "Conversation not found"})
+			return false
+		}
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to fetch conversation"})
+		return false
+	}
+
+	member, err := handler.ApiConfig.DB.GetConversationMember(r.Context(), database.GetConversationMemberParams{
+		ConversationID: conversationID,
+		UserID:         userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithJSON(w, 403, model.APIResponse{Success: false, Message: "Not a member of this conversation"})
+			return false
+		}
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to verify membership"})
+		return false
+	}
+
+	if conversation.IsGroup && member.Role == database.MemberRoleMember {
+		respondWithJSON(w, 403, model.APIResponse{Success: false, Message: "Only admins can pin messages"})
+		return false
+	}
+	return true
+}
+
+func (handler *Handler) HandlerPinMessage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
+	if !ok {
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	type parameters struct {
+		ConversationID uuid.UUID `json:"conversation_id"`
+		MessageID      uuid.UUID `json:"message_id"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid request payload"})
+		return
+	}
+
+	if params.ConversationID == uuid.Nil || params.MessageID == uuid.Nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "conversation_id and message_id required"})
+		return
+	}
+
+	if !handler.canManagePins(w, r, params.ConversationID, userID) {
+		return
+	}
+
+	message, err := handler.ApiConfig.DB.GetMessageByID(r.Context(), params.MessageID)
+	if err != nil || message.ConversationID != params.ConversationID || message.DeletedAt.Valid {
+		respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "Message not found"})
+		return
+	}
+
+	var pinned int64
+	err = handler.withTx(r.Context(), func(queries *database.Queries) error {
+		if err := queries.LockConversationPins(r.Context(), params.ConversationID); err != nil {
+			return err
+		}
+		var err error
+		pinned, err = queries.PinMessage(r.Context(), database.PinMessageParams{
+			ConversationID: params.ConversationID,
+			MessageID:      params.MessageID,
+			PinnedBy:       userID,
+			MaxPins:        handler.ApiConfig.MaxPins,
+		})
+		return err
+	})
+	if err != nil {
+		log.Printf("Failed to pin message: %v", err)
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to pin message"})
+		return
+	}
+
+	if pinned == 0 {
+		// either the message is already pinned or the conversation is at its cap
+		_, err := handler.ApiConfig.DB.GetPinnedMessage(r.Context(), database.GetPinnedMessageParams{
+			ConversationID: params.ConversationID,
+			MessageID:      params.MessageID,
+		})
+		if err == nil {
+			respondWithJSON(w, 200, model.APIResponse{Success: true, Message: "Message already pinned"})
+			return
+		}
+		if err != sql.ErrNoRows {
+			respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to pin message"})
+			return
+		}
+		respondWithJSON(w, 409, model.APIResponse{
+			Success: false,
+			Message: "Pin limit reached, unpin a message first",
+			Data:    map[string]int32{"max_pins": handler.ApiConfig.MaxPins},
+		})
+		return
+	}
+
+	// update the pinned bar of open clients
+	handler.ApiConfig.Hub.BroadcastToConversation(params.ConversationID, userID, ws.OutgoingMessage{
+		Type:           ws.TypePin,
+		MessageID:      &params.MessageID,
+		ConversationID: &params.ConversationID,
+		SenderID:       &userID,
+		CreatedAt:      time.Now().UTC().Format(time.RFC3339),
+	})
+
+	respondWithJSON(w, 200, model.APIResponse{
+		Success: true,
+		Message: "Message pinned successfully",
+	})
+}
+
+func (handler *Handler) HandlerUnpinMessage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
+	if !ok {
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	type parameters struct {
+		ConversationID uuid.UUID `json:"conversation_id"`
+		MessageID      uuid.UUID `json:"message_id"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid request payload"})
+		return
+	}
+
+	if params.ConversationID == uuid.Nil || params.MessageID == uuid.Nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "conversation_id and message_id required"})
+		return
+	}
+
+	if !handler.canManagePins(w, r, params.ConversationID, userID) {
+		return
+	}
+
+	unpinned, err := handler.ApiConfig.DB.UnpinMessage(r.Context(), database.UnpinMessageParams{
+		ConversationID: params.ConversationID,
+		MessageID:      params.MessageID,
+	})
+	if err != nil {
+		log.Printf("Failed to unpin message: %v", err)
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to unpin message"})
+		return
+	}
+
+	if unpinned == 0 {
+		respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "Message is not pinned"})
+		return
+	}
+
+	handler.ApiConfig.Hub.BroadcastToConversation(params.ConversationID, userID, ws.OutgoingMessage{
+		Type:           ws.TypeUnpin,
+		MessageID:      &params.MessageID,
+		ConversationID: &params.ConversationID,
+		SenderID:       &userID,
+	})
+
+	respondWithJSON(w, 200, model.APIResponse{
+		Success: true,
+		Message: "Message unpinned successfully",
+	})
+}
+
+func (handler *Handler) HandlerGetPinnedMessages(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
+	if !ok {
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	type parameters struct {
+		ConversationID uuid.UUID `json:"conversation_id"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid request payload"})
+		return
+	}
+
+	if params.ConversationID == uuid.Nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "conversation_id required"})
+		return
+	}
+
+	_, err := handler.ApiConfig.DB.GetConversationMember(r.Context(), database.GetConversationMemberParams{
+		ConversationID: params.ConversationID,
+		UserID:         userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithJSON(w, 403, model.APIResponse{Success: false, Message: "Not a member of this conversation"})
+			return
+		}
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to verify membership"})
+		return
+	}
+
+	pins, err := handler.ApiConfig.DB.GetPinnedMessages(r.Context(), params.ConversationID)
+	if err != nil {
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to fetch pinned messages"})
+		return
+	}
+	if pins == nil {
+		pins = []database.GetPinnedMessagesRow{}
+	}
+
+	respondWithJSON(w, 200, model.APIResponse{
+		Success: true,
+		Message: "Pinned messages fetched successfully",
+		Data: map[string]interface{}{
+			"pins":     pins,
+			"max_pins": handler.ApiConfig.MaxPins,
+		},
+	})
+}
@@ -47,6 +47,14 @@ func (handler *Handler) HandlerRefreshToken(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	// a rotated token can only come back if someone besides the client that
+	// rotated it has a copy, so the whole session is revoked
+	if existing.RevokedAt.Valid && existing.ReplacedByTokenID.Valid {
+		handler.revokeStolenSession(r, existing)
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Refresh token reuse detected, log in again"})
+		return
+	}
+
 	// check if revoked
 	if existing.RevokedAt.Valid {
 		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Refresh token has been revoked"})
@@ -60,7 +68,7 @@ func (handler *Handler) HandlerRefreshToken(w http.ResponseWriter, r *http.Reque
 	}
 
 	// generate new tokens
-	tr, newTokenHash, err := auth.GenerateToken(existing.UserID, handler.ApiConfig.JWTSecretKey)
+	tr, newTokenHash, err := auth.GenerateToken(existing.UserID, existing.SessionID, handler.ApiConfig.JWTSecretKey)
 	if err != nil {
 		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to generate token"})
 		return
@@ -73,6 +81,7 @@ func (handler *Handler) HandlerRefreshToken(w http.ResponseWriter, r *http.Reque
 		ExpiresAt: tr.RefreshToken.Expires,
 		UserAgent: sql.NullString{String: r.UserAgent(), Valid: true},
 		IpAddress: handler.getIPAddress(r),
+		SessionID: existing.SessionID,
 	})
 	if err != nil {
 		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to store refresh token"})
@@ -80,7 +89,7 @@ func (handler *Handler) HandlerRefreshToken(w http.ResponseWriter, r *http.Reque
 	}
 
 	// rotate — revoke old token and point to new one
-	err = handler.ApiConfig.DB.RotateRefreshToken(r.Context(), database.RotateRefreshTokenParams{
+	rotated, err := handler.ApiConfig.DB.RotateRefreshToken(r.Context(), database.RotateRefreshTokenParams{
 		ID:                existing.ID,
 		ReplacedByTokenID: uuid.NullUUID{UUID: newToken.ID, Valid: true},
 	})
@@ -88,6 +97,15 @@ func (handler *Handler) HandlerRefreshToken(w http.ResponseWriter, r *http.Reque
 		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to rotate refresh token"})
 		return
 	}
+	if rotated == 0 {
+		// another request presented the same token first
+		handler.revokeStolenSession(r, existing)
+		if err := handler.ApiConfig.DB.RevokeRefreshToken(r.Context(), newToken.ID); err != nil {
+			log.Printf("Failed to revoke refresh token %s: %v", newToken.ID, err)
+		}
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Refresh token reuse detected, log in again"})
+		return
+	}
 
 	respondWithJSON(w, 200, model.APIResponse{
 		Success: true,
@@ -95,3 +113,29 @@ func (handler *Handler) HandlerRefreshToken(w http.ResponseWriter, r *http.Reque
 		Data:    tr,
 	})
 }
+
+// revokeStolenSession ends the session token was issued to, logging out
+// both the device holding its newest refresh token and, through
+// MiddlewareAuth, every access token of the session. The reuse is recorded
+// as a security event.
+func (handler *Handler) revokeStolenSession(r *http.Request, token database.RefreshToken) {
+	revoked, err := handler.ApiConfig.DB.RevokeSession(r.Context(), database.RevokeSessionParams{
+		UserID:    token.UserID,
+		SessionID: token.SessionID,
+	})
+	if err != nil {
+		log.Printf("Failed to revoke session %s: %v", token.SessionID, err)
+	}
+	log.Printf("Refresh token %s of user %s was reused; revoked %d tokens of session %s", token.ID, token.UserID, revoked, token.SessionID)
+
+	err = handler.ApiConfig.DB.CreateSecurityEvent(r.Context(), database.CreateSecurityEventParams{
+		UserID:         token.UserID,
+		EventType:      database.SecurityEventTypeRefreshTokenReuse,
+		RefreshTokenID: uuid.NullUUID{UUID: token.ID, Valid: true},
+		IpAddress:      handler.getIPAddress(r),
+		UserAgent:      sql.NullString{String: r.UserAgent(), Valid: true},
+	})
+	if err != nil {
+		log.Printf("Failed to record security event: %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"time"
+
+	"github.com/Anything-That-Works/GoPath/internal/clamav"
+	"github.com/Anything-That-Works/GoPath/internal/database"
+)
+
+// newFileScanStatus is pending until clamd has seen the file, or skipped
+// when no scanner is configured.
+func (handler *Handler) newFileScanStatus() database.FileScanStatus {
+	if handler.ApiConfig.Scanner == nil {
+		return database.FileScanStatusSkipped
+	}
+	return database.FileScanStatusPending
+}
+
+// scanFile records clamd's verdict on content. When clamd can't be reached
+// the file stays pending and RescanPendingFiles tries again later.
+func (handler *Handler) scanFile(ctx context.Context, file database.File, content io.Reader) database.File {
+	if file.ScanStatus != database.FileScanStatusPending || handler.ApiConfig.Scanner == nil {
+		return file
+	}
+
+	// identical content needs no second scan
+	if file.Sha256.Valid {
+		verdict, err := handler.ApiConfig.DB.GetScanVerdictBySHA256(ctx, database.GetScanVerdictBySHA256Params{
+			Sha256: file.Sha256,
+			ID:     file.ID,
+		})
+		if err == nil {
+			return handler.setScanResult(ctx, file, verdict.ScanStatus, verdict.ScanSignature)
+		}
+		if err != sql.ErrNoRows {
+			log.Printf("Failed to look up scan verdict for %s: %v", file.ID, err)
+		}
+	}
+
+	result, err := handler.ApiConfig.Scanner.Scan(ctx, content)
+	switch {
+	case errors.Is(err, clamav.ErrSizeLimit):
+		return handler.setScanResult(ctx, file, database.FileScanStatusError, sql.NullString{})
+	case err != nil:
+		log.Printf("Failed to scan file %s: %v", file.ID, err)
+		return file
+	case result.Infected:
+		log.Printf("File %s is infected: %s", file.ID, result.Signature)
+		return handler.setScanResult(ctx, file, database.FileScanStatusInfected, sql.NullString{String: result.Signature, Valid: true})
+	default:
+		return handler.setScanResult(ctx, file, database.FileScanStatusClean, sql.NullString{})
+	}
+}
+
+func (handler *Handler) setScanResult(ctx context.Context, file database.File, status database.FileScanStatus, signature sql.NullString) database.File {
+	updated, err := handler.ApiConfig.DB.SetFileScanResult(ctx, database.SetFileScanResultParams{
+		ID:            file.ID,
+		ScanStatus:    status,
+		ScanSignature: signature,
+	})
+	if err != nil {
+		log.Printf("Failed to save scan result for %s: %v", file.ID, err)
+		return file
+	}
+	return updated
+}
+
+// scanStoredFile scans a file already in storage.
+func (handler *Handler) scanStoredFile(ctx context.Context, file database.File) database.File {
+	if file.ScanStatus != database.FileScanStatusPending {
+		return file
+	}
+	content, err := handler.ApiConfig.Storage.Open(file.Path)
+	if err != nil {
+		log.Printf("Failed to open file %s for scanning: %v", file.ID, err)
+		return file
+	}
+	defer func() {
+		if err := content.Close(); err != nil {
+			log.Printf("Failed to close file: %v", err)
+		}
+	}()
+	return handler.scanFile(ctx, file, content)
+}
+
+// RescanPendingFiles retries files left pending, e.g. while clamd was down.
+func (handler *Handler) RescanPendingFiles(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		// leave files uploaded moments ago to the scan of their own request
+		files, err := handler.ApiConfig.DB.ListPendingScans(context.Background(), database.ListPendingScansParams{
+			CreatedAt: time.Now().Add(-time.Minute),
+			Limit:     50,
+		})
+		if err != nil {
+			log.Printf("Failed to list pending scans: %v", err)
+			continue
+		}
+		for _, file := range files {
+			handler.scanStoredFile(context.Background(), file)
+		}
+	}
+}
+
+// fileServable reports whether a file may be handed out: scanned clean, or
+// from before scanning.
+func fileServable(file database.File) bool {
+	return file.ScanStatus == database.FileScanStatusClean || file.ScanStatus == database.FileScanStatusSkipped
+}
+
+func scanRefusal(status database.FileScanStatus) string {
+	switch status {
+	case database.FileScanStatusPending:
+		return "File is still being scanned"
+	case database.FileScanStatusInfected:
+		return "File is infected"
+	default:
+		return "File could not be scanned"
+	}
+}
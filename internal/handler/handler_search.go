@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Anything-That-Works/GoPath/internal/database"
+	"github.com/Anything-That-Works/GoPath/internal/model"
+	"github.com/google/uuid"
+)
+
+type searchParameters struct {
+	ConversationID   uuid.UUID  `json:"conversation_id"`
+	Query            string     `json:"query"`
+	SenderID         *uuid.UUID `json:"sender_id"`
+	From             *time.Time `json:"from"`
+	To               *time.Time `json:"to"`
+	HasFile          *bool      `json:"has_file"`
+	ConversationType string     `json:"conversation_type"` // "group" or "direct"
+	Limit            int32      `json:"limit"`
+	Page             int32      `json:"page"`
+	Before           string     `json:"before"`
+	After            string     `json:"after"`
+}
+
+// HandlerSearchMessages searches a single conversation.
+func (handler *Handler) HandlerSearchMessages(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
+	if !ok {
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := searchParameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid request payload"})
+		return
+	}
+
+	if params.ConversationID == uuid.Nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "conversation_id required"})
+		return
+	}
+
+	// verify membership
+	_, err := handler.ApiConfig.DB.GetConversationMember(r.Context(), database.GetConversationMemberParams{
+		ConversationID: params.ConversationID,
+		UserID:         userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithJSON(w, 403, model.APIResponse{Success: false, Message: "Not a member of this conversation"})
+			return
+		}
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to verify membership"})
+		return
+	}
+
+	handler.searchMessages(w, r, userID, params)
+}
+
+// HandlerSearchAllMessages searches every conversation the caller is a member of.
+func (handler *Handler) HandlerSearchAllMessages(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
+	if !ok {
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := searchParameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid request payload"})
+		return
+	}
+
+	handler.searchMessages(w, r, userID, params)
+}
+
+func (handler *Handler) searchMessages(w http.ResponseWriter, r *http.Request, userID uuid.UUID, params searchParameters) {
+	if params.Query == "" {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "query required"})
+		return
+	}
+
+	if params.Before != "" && params.After != "" {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Use either before or after, not both"})
+		return
+	}
+
+	if params.Limit == 0 {
+		params.Limit = 20
+	}
+	if params.Page == 0 {
+		params.Page = 1
+	}
+
+	filters := database.SearchMessagesParams{
+		Query:       params.Query,
+		UserID:      userID,
+		ResultLimit: params.Limit,
+	}
+	if params.ConversationID != uuid.Nil {
+		filters.ConversationID = uuid.NullUUID{UUID: params.ConversationID, Valid: true}
+	}
+	if params.SenderID != nil {
+		filters.SenderID = uuid.NullUUID{UUID: *params.SenderID, Valid: true}
+	}
+	if params.From != nil {
+		filters.SentAfter = sql.NullTime{Time: *params.From, Valid: true}
+	}
+	if params.To != nil {
+		filters.SentBefore = sql.NullTime{Time: *params.To, Valid: true}
+	}
+	if params.HasFile != nil {
+		filters.HasFile = sql.NullBool{Bool: *params.HasFile, Valid: true}
+	}
+	switch params.ConversationType {
+	case "":
+	case "group":
+		filters.IsGroup = sql.NullBool{Bool: true, Valid: true}
+	case "direct":
+		filters.IsGroup = sql.NullBool{Bool: false, Valid: true}
+	default:
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "conversation_type must be group or direct"})
+		return
+	}
+
+	var rows []database.SearchMessagesRow
+	var err error
+	switch {
+	case params.Before != "":
+		cursorRank, cursorID, cerr := decodeSearchCursor(params.Before)
+		if cerr != nil {
+			respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid cursor"})
+			return
+		}
+		filters.CursorRank = sql.NullFloat64{Float64: float64(cursorRank), Valid: true}
+		filters.CursorID = uuid.NullUUID{UUID: cursorID, Valid: true}
+		rows, err = handler.ApiConfig.DB.SearchMessages(r.Context(), filters)
+	case params.After != "":
+		cursorRank, cursorID, cerr := decodeSearchCursor(params.After)
+		if cerr != nil {
+			respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid cursor"})
+			return
+		}
+		var after []database.SearchMessagesAfterRow
+		after, err = handler.ApiConfig.DB.SearchMessagesAfter(r.Context(), database.SearchMessagesAfterParams{
+			Query:          filters.Query,
+			UserID:         filters.UserID,
+			ConversationID: filters.ConversationID,
+			SenderID:       filters.SenderID,
+			SentAfter:      filters.SentAfter,
+			SentBefore:     filters.SentBefore,
+			HasFile:        filters.HasFile,
+			IsGroup:        filters.IsGroup,
+			CursorRank:     cursorRank,
+			CursorID:       cursorID,
+			ResultLimit:    filters.ResultLimit,
+		})
+		// fetched lowest rank first to stay next to the cursor; results are best first
+		for i := len(after) - 1; i >= 0; i-- {
+			rows = append(rows, database.SearchMessagesRow(after[i]))
+		}
+	default:
+		filters.ResultOffset = (params.Page - 1) * params.Limit
+		rows, err = handler.ApiConfig.DB.SearchMessages(r.Context(), filters)
+	}
+	if err != nil {
+		log.Printf("Failed to search messages: %v", err)
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to search messages"})
+		return
+	}
+
+	data := map[string]interface{}{
+		"messages":    model.DatabaseSearchRowsToResults(rows),
+		"query":       params.Query,
+		"limit":       params.Limit,
+		"next_cursor": nil,
+		"prev_cursor": nil,
+	}
+	if params.Before == "" && params.After == "" {
+		data["page"] = params.Page
+	}
+	if len(rows) > 0 {
+		best, worst := rows[0], rows[len(rows)-1]
+		data["prev_cursor"] = encodeSearchCursor(best.Rank, best.Message.ID)
+		if len(rows) == int(params.Limit) || params.After != "" {
+			data["next_cursor"] = encodeSearchCursor(worst.Rank, worst.Message.ID)
+		}
+	}
+
+	respondWithJSON(w, 200, model.APIResponse{
+		Success: true,
+		Message: "Search results fetched successfully",
+		Data:    data,
+	})
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Anything-That-Works/GoPath/internal/database"
+	"github.com/Anything-That-Works/GoPath/internal/model"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func (handler *Handler) HandlerGetSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
+	if !ok {
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+	sessionID, _ := r.Context().Value(contextKeySessionID).(uuid.UUID)
+
+	rows, err := handler.ApiConfig.DB.ListActiveSessions(r.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to list sessions: %v", err)
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to fetch sessions"})
+		return
+	}
+
+	respondWithJSON(w, 200, model.APIResponse{
+		Success: true,
+		Message: "Sessions fetched successfully",
+		Data:    model.DatabaseSessionRowsToSessions(rows, sessionID),
+	})
+}
+
+// HandlerRevokeSession logs one device out, ending its access token along
+// with its refresh token.
+func (handler *Handler) HandlerRevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
+	if !ok {
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	sessionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid session ID"})
+		return
+	}
+
+	revoked, err := handler.ApiConfig.DB.RevokeSession(r.Context(), database.RevokeSessionParams{
+		UserID:    userID,
+		SessionID: sessionID,
+	})
+	if err != nil {
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to revoke session"})
+		return
+	}
+	if revoked == 0 {
+		respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "Session not found"})
+		return
+	}
+
+	respondWithJSON(w, 200, model.APIResponse{Success: true, Message: "Session revoked successfully"})
+}
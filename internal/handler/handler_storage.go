@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/Anything-That-Works/GoPath/internal/database"
+	"github.com/Anything-That-Works/GoPath/internal/model"
+	"github.com/google/uuid"
+)
+
+// storageQuota is how much of a byte quota is in use. Usage is the sum of
+// files.size, so deduplicated content counts once per file.
+type storageQuota struct {
+	Used      int64
+	Limit     int64
+	Unlimited bool
+}
+
+func (quota storageQuota) allows(size int64) bool {
+	return quota.Unlimited || quota.Used+size <= quota.Limit
+}
+
+func (quota storageQuota) data() map[string]interface{} {
+	data := map[string]interface{}{
+		"used":      quota.Used,
+		"limit":     nil,
+		"remaining": nil,
+	}
+	if !quota.Unlimited {
+		data["limit"] = quota.Limit
+		data["remaining"] = max(quota.Limit-quota.Used, 0)
+	}
+	return data
+}
+
+// userQuota applies an operator override over the configured default.
+func (handler *Handler) userQuota(ctx context.Context, queries *database.Queries, userID uuid.UUID) (storageQuota, error) {
+	quota := storageQuota{
+		Limit:     handler.ApiConfig.UserStorageQuota,
+		Unlimited: handler.ApiConfig.UserStorageQuota == 0,
+	}
+
+	override, err := queries.GetUserStorageQuota(ctx, userID)
+	if err == nil {
+		quota.Limit, quota.Unlimited = override.QuotaBytes.Int64, !override.QuotaBytes.Valid
+	} else if err != sql.ErrNoRows {
+		return storageQuota{}, err
+	}
+
+	quota.Used, err = queries.GetUserStorageUsage(ctx, userID)
+	return quota, err
+}
+
+func (handler *Handler) conversationQuota(ctx context.Context, queries *database.Queries, conversationID uuid.UUID) (storageQuota, error) {
+	quota := storageQuota{
+		Limit:     handler.ApiConfig.ConversationStorageQuota,
+		Unlimited: handler.ApiConfig.ConversationStorageQuota == 0,
+	}
+
+	override, err := queries.GetConversationStorageQuota(ctx, conversationID)
+	if err == nil {
+		quota.Limit, quota.Unlimited = override.QuotaBytes.Int64, !override.QuotaBytes.Valid
+	} else if err != sql.ErrNoRows {
+		return storageQuota{}, err
+	}
+
+	quota.Used, err = queries.GetConversationStorageUsage(ctx, uuid.NullUUID{UUID: conversationID, Valid: true})
+	return quota, err
+}
+
+// quotaError is a quota the new bytes don't fit in.
+type quotaError struct {
+	message string
+	quota   storageQuota
+}
+
+func (err *quotaError) Error() string {
+	return err.message
+}
+
+// fitStorageQuota checks size more bytes fit in the uploader's quota and, for
+// a conversation's file, in the conversation's; a *quotaError says which
+// doesn't.
+func (handler *Handler) fitStorageQuota(ctx context.Context, queries *database.Queries, userID uuid.UUID, conversationID uuid.NullUUID, size int64) error {
+	quota, err := handler.userQuota(ctx, queries, userID)
+	if err != nil {
+		return fmt.Errorf("check storage quota: %w", err)
+	}
+	if !quota.allows(size) {
+		return &quotaError{message: "Storage quota exceeded", quota: quota}
+	}
+
+	if !conversationID.Valid {
+		return nil
+	}
+	quota, err = handler.conversationQuota(ctx, queries, conversationID.UUID)
+	if err != nil {
+		return fmt.Errorf("check conversation storage quota: %w", err)
+	}
+	if !quota.allows(size) {
+		return &quotaError{message: "Conversation storage quota exceeded", quota: quota}
+	}
+	return nil
+}
+
+// checkStorageQuota reports whether size more bytes fit in the uploader's
+// quota and, for a conversation's file, in the conversation's. It turns away
+// uploads early; createFile checks again as the file is recorded.
+func (handler *Handler) checkStorageQuota(w http.ResponseWriter, r *http.Request, userID uuid.UUID, conversationID uuid.NullUUID, size int64) bool {
+	err := handler.fitStorageQuota(r.Context(), handler.ApiConfig.DB, userID, conversationID, size)
+	if err == nil || respondIfQuotaExceeded(w, err) {
+		return err == nil
+	}
+	log.Printf("Failed to check storage quota: %v", err)
+	respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to check storage quota"})
+	return false
+}
+
+// createFile records a stored file within queries' transaction, checking
+// its quotas again under their locks so concurrent uploads can't both fit in
+// the space left for one. Locks are taken uploader first, then conversation.
+func (handler *Handler) createFile(ctx context.Context, queries *database.Queries, params database.CreateFileParams) (database.File, error) {
+	if err := queries.LockUserStorage(ctx, params.UploaderID); err != nil {
+		return database.File{}, err
+	}
+	if params.ConversationID.Valid {
+		if err := queries.LockConversationStorage(ctx, params.ConversationID.UUID); err != nil {
+			return database.File{}, err
+		}
+	}
+	if err := handler.fitStorageQuota(ctx, queries, params.UploaderID, params.ConversationID, params.Size); err != nil {
+		return database.File{}, err
+	}
+	return queries.CreateFile(ctx, params)
+}
+
+// respondIfQuotaExceeded answers 413 when err is a quota the file doesn't
+// fit in.
+func respondIfQuotaExceeded(w http.ResponseWriter, err error) bool {
+	var exceeded *quotaError
+	if !errors.As(err, &exceeded) {
+		return false
+	}
+	respondWithJSON(w, 413, model.APIResponse{Success: false, Message: exceeded.message, Data: exceeded.quota.data()})
+	return true
+}
+
+// uploadConversation parses the optional conversation an upload is meant
+// for; only members may upload into a conversation.
+func (handler *Handler) uploadConversation(w http.ResponseWriter, r *http.Request, userID uuid.UUID, value string) (uuid.NullUUID, bool) {
+	if value == "" {
+		return uuid.NullUUID{}, true
+	}
+	conversationID, err := uuid.Parse(value)
+	if err != nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid conversation_id"})
+		return uuid.NullUUID{}, false
+	}
+	if !handler.isConversationMember(w, r, conversationID, userID) {
+		return uuid.NullUUID{}, false
+	}
+	return uuid.NullUUID{UUID: conversationID, Valid: true}, true
+}
+
+func (handler *Handler) isConversationMember(w http.ResponseWriter, r *http.Request, conversationID uuid.UUID, userID uuid.UUID) bool {
+	_, err := handler.ApiConfig.DB.GetConversationMember(r.Context(), database.GetConversationMemberParams{
+		ConversationID: conversationID,
+		UserID:         userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithJSON(w, 403, model.APIResponse{Success: false, Message: "Not a member of this conversation"})
+			return false
+		}
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to verify membership"})
+		return false
+	}
+	return true
+}
+
+// HandlerGetStorageUsage returns the caller's used and remaining bytes; a
+// null limit means unlimited.
+func (handler *Handler) HandlerGetStorageUsage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
+	if !ok {
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	quota, err := handler.userQuota(r.Context(), handler.ApiConfig.DB, userID)
+	if err != nil {
+		log.Printf("Failed to fetch storage usage: %v", err)
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to fetch storage usage"})
+		return
+	}
+
+	respondWithJSON(w, 200, model.APIResponse{
+		Success: true,
+		Message: "Storage usage fetched successfully",
+		Data:    quota.data(),
+	})
+}
+
+func (handler *Handler) HandlerGetConversationStorageUsage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
+	if !ok {
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	type parameters struct {
+		ConversationID uuid.UUID `json:"conversation_id"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid request payload"})
+		return
+	}
+
+	if params.ConversationID == uuid.Nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "conversation_id required"})
+		return
+	}
+
+	if !handler.isConversationMember(w, r, params.ConversationID, userID) {
+		return
+	}
+
+	quota, err := handler.conversationQuota(r.Context(), handler.ApiConfig.DB, params.ConversationID)
+	if err != nil {
+		log.Printf("Failed to fetch conversation storage usage: %v", err)
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to fetch storage usage"})
+		return
+	}
+
+	respondWithJSON(w, 200, model.APIResponse{
+		Success: true,
+		Message: "Storage usage fetched successfully",
+		Data:    quota.data(),
+	})
+}
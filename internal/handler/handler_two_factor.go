@@ -0,0 +1,488 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Anything-That-Works/GoPath/internal/auth"
+	"github.com/Anything-That-Works/GoPath/internal/database"
+	"github.com/Anything-That-Works/GoPath/internal/model"
+	"github.com/Anything-That-Works/GoPath/internal/totp"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	totpIssuer = "GoPath"
+
+	loginChallengeTTL         = 5 * time.Minute
+	maxLoginChallengeAttempts = 5
+
+	recoveryCodeCount = 10
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func (handler *Handler) HandlerGetTwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
+	if !ok {
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	status := model.TwoFactorStatus{}
+	userTOTP, err := handler.ApiConfig.DB.GetUserTOTP(r.Context(), userID)
+	if err != nil && err != sql.ErrNoRows {
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to fetch two-factor settings"})
+		return
+	}
+	status.Enabled = err == nil && userTOTP.EnabledAt.Valid
+
+	if status.Enabled {
+		remaining, err := handler.ApiConfig.DB.CountUnusedRecoveryCodes(r.Context(), userID)
+		if err != nil {
+			respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to count recovery codes"})
+			return
+		}
+		status.RecoveryCodesRemaining = remaining
+	}
+
+	respondWithJSON(w, 200, model.APIResponse{
+		Success: true,
+		Message: "Two-factor status fetched successfully",
+		Data:    status,
+	})
+}
+
+// HandlerEnrollTwoFactor starts enrolment with a fresh secret. 2FA is on
+// only once HandlerConfirmTwoFactor has seen a code from it. It asks for the
+// password, so a stolen access token alone can't tie the account to an
+// attacker's authenticator.
+func (handler *Handler) HandlerEnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Password string `json:"password"`
+	}
+
+	userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
+	if !ok {
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid request payload"})
+		return
+	}
+
+	if params.Password == "" {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Password required"})
+		return
+	}
+
+	user, err := handler.ApiConfig.DB.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "User not found"})
+			return
+		}
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to fetch user"})
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(params.Password)); err != nil {
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Invalid password"})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to generate secret"})
+		return
+	}
+
+	_, err = handler.ApiConfig.DB.SetPendingUserTOTP(r.Context(), database.SetPendingUserTOTPParams{
+		UserID: userID,
+		Secret: secret,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithJSON(w, 409, model.APIResponse{Success: false, Message: "Two-factor authentication is already enabled"})
+			return
+		}
+		log.Printf("Failed to store TOTP secret: %v", err)
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to start enrolment"})
+		return
+	}
+
+	respondWithJSON(w, 200, model.APIResponse{
+		Success: true,
+		Message: "Scan the code with an authenticator app and confirm with a code from it",
+		Data: model.TwoFactorEnrollment{
+			Secret: secret,
+			URI:    totp.URI(totpIssuer, user.Email, secret),
+		},
+	})
+}
+
+// HandlerConfirmTwoFactor turns 2FA on and returns the recovery codes,
+// which are shown this once. Every other session is logged out, as none of
+// them passed a second factor.
+func (handler *Handler) HandlerConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code string `json:"code"`
+	}
+
+	userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
+	if !ok {
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+	sessionID, _ := r.Context().Value(contextKeySessionID).(uuid.UUID)
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid request payload"})
+		return
+	}
+
+	userTOTP, err := handler.ApiConfig.DB.GetUserTOTP(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithJSON(w, 409, model.APIResponse{Success: false, Message: "Start enrolment first"})
+			return
+		}
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to fetch two-factor settings"})
+		return
+	}
+	if userTOTP.EnabledAt.Valid {
+		respondWithJSON(w, 409, model.APIResponse{Success: false, Message: "Two-factor authentication is already enabled"})
+		return
+	}
+
+	step, valid := totp.Validate(userTOTP.Secret, params.Code, time.Now())
+	if !valid {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid code"})
+		return
+	}
+
+	// 2FA is never on without recovery codes to fall back on
+	var codes []string
+	err = handler.withTx(r.Context(), func(queries *database.Queries) error {
+		if _, err := queries.EnableUserTOTP(r.Context(), database.EnableUserTOTPParams{
+			UserID:       userID,
+			LastUsedStep: step,
+		}); err != nil {
+			return err
+		}
+		if err := queries.RevokeOtherSessions(r.Context(), database.RevokeOtherSessionsParams{
+			UserID:    userID,
+			SessionID: sessionID,
+		}); err != nil {
+			return err
+		}
+		var err error
+		codes, err = handler.replaceRecoveryCodes(r.Context(), queries, userID)
+		return err
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithJSON(w, 409, model.APIResponse{Success: false, Message: "Two-factor authentication is already enabled"})
+			return
+		}
+		log.Printf("Failed to enable two-factor authentication: %v", err)
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to enable two-factor authentication"})
+		return
+	}
+
+	respondWithJSON(w, 200, model.APIResponse{
+		Success: true,
+		Message: "Two-factor authentication enabled",
+		Data:    map[string]interface{}{"recovery_codes": codes},
+	})
+}
+
+// HandlerRegenerateRecoveryCodes replaces every recovery code, used or not.
+func (handler *Handler) HandlerRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code string `json:"code"`
+	}
+
+	userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
+	if !ok {
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid request payload"})
+		return
+	}
+
+	userTOTP, ok := handler.enabledTOTP(w, r, userID)
+	if !ok {
+		return
+	}
+	if !handler.checkSecondFactor(w, r, userTOTP, params.Code) {
+		return
+	}
+
+	var codes []string
+	err := handler.withTx(r.Context(), func(queries *database.Queries) error {
+		var err error
+		codes, err = handler.replaceRecoveryCodes(r.Context(), queries, userID)
+		return err
+	})
+	if err != nil {
+		log.Printf("Failed to create recovery codes: %v", err)
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to create recovery codes"})
+		return
+	}
+
+	respondWithJSON(w, 200, model.APIResponse{
+		Success: true,
+		Message: "Recovery codes replaced",
+		Data:    map[string]interface{}{"recovery_codes": codes},
+	})
+}
+
+// HandlerDisableTwoFactor asks for the password and a code again, so a
+// stolen access token alone can't turn 2FA off.
+func (handler *Handler) HandlerDisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Password string `json:"password"`
+		Code     string `json:"code"` // from the authenticator, or a recovery code
+	}
+
+	userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
+	if !ok {
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid request payload"})
+		return
+	}
+
+	if params.Password == "" || params.Code == "" {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Password and code required"})
+		return
+	}
+
+	user, err := handler.ApiConfig.DB.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "User not found"})
+			return
+		}
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to fetch user"})
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(params.Password)); err != nil {
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Invalid password"})
+		return
+	}
+
+	userTOTP, ok := handler.enabledTOTP(w, r, userID)
+	if !ok {
+		return
+	}
+	if !handler.checkSecondFactor(w, r, userTOTP, params.Code) {
+		return
+	}
+
+	err = handler.withTx(r.Context(), func(queries *database.Queries) error {
+		if err := queries.DeleteUserTOTP(r.Context(), userID); err != nil {
+			return err
+		}
+		return queries.DeleteRecoveryCodes(r.Context(), userID)
+	})
+	if err != nil {
+		log.Printf("Failed to disable two-factor authentication: %v", err)
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to disable two-factor authentication"})
+		return
+	}
+
+	respondWithJSON(w, 200, model.APIResponse{Success: true, Message: "Two-factor authentication disabled"})
+}
+
+// HandlerLoginTwoFactor finishes a login that HandlerLogin answered with a
+// challenge.
+func (handler *Handler) HandlerLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"` // from the authenticator, or a recovery code
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid request payload"})
+		return
+	}
+
+	if params.ChallengeToken == "" || params.Code == "" {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Challenge token and code required"})
+		return
+	}
+
+	challenge, err := handler.ApiConfig.DB.AttemptLoginChallenge(r.Context(), database.AttemptLoginChallengeParams{
+		TokenHash:   auth.HashToken(params.ChallengeToken),
+		MaxAttempts: maxLoginChallengeAttempts,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Login challenge is invalid or expired, log in again"})
+			return
+		}
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to fetch login challenge"})
+		return
+	}
+
+	userTOTP, ok := handler.enabledTOTP(w, r, challenge.UserID)
+	if !ok {
+		return
+	}
+	if !handler.checkSecondFactor(w, r, userTOTP, params.Code) {
+		return
+	}
+
+	// a challenge is good for one login, even if two codes race for it
+	used, err := handler.ApiConfig.DB.UseLoginChallenge(r.Context(), challenge.ID)
+	if err != nil {
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to use login challenge"})
+		return
+	}
+	if used == 0 {
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Login challenge is invalid or expired, log in again"})
+		return
+	}
+
+	tr, ok := handler.createSession(w, r, challenge.UserID)
+	if !ok {
+		return
+	}
+
+	respondWithJSON(w, 200, model.APIResponse{
+		Success: true,
+		Message: "Login successful",
+		Data:    tr,
+	})
+}
+
+func (handler *Handler) createLoginChallenge(ctx context.Context, userID uuid.UUID) (model.TwoFactorChallenge, error) {
+	if err := handler.ApiConfig.DB.DeleteExpiredLoginChallenges(ctx, userID); err != nil {
+		log.Printf("Failed to delete expired login challenges: %v", err)
+	}
+
+	token, tokenHash, err := auth.GenerateLoginChallengeToken()
+	if err != nil {
+		return model.TwoFactorChallenge{}, err
+	}
+	challenge, err := handler.ApiConfig.DB.CreateLoginChallenge(ctx, database.CreateLoginChallengeParams{
+		UserID:    userID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(loginChallengeTTL),
+	})
+	if err != nil {
+		return model.TwoFactorChallenge{}, err
+	}
+	return model.TwoFactorChallenge{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresAt:         challenge.ExpiresAt,
+	}, nil
+}
+
+// enabledTOTP responds 409 and returns false unless userID has 2FA on.
+func (handler *Handler) enabledTOTP(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (database.UserTotp, bool) {
+	userTOTP, err := handler.ApiConfig.DB.GetUserTOTP(r.Context(), userID)
+	if err != nil && err != sql.ErrNoRows {
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to fetch two-factor settings"})
+		return userTOTP, false
+	}
+	if err == sql.ErrNoRows || !userTOTP.EnabledAt.Valid {
+		respondWithJSON(w, 409, model.APIResponse{Success: false, Message: "Two-factor authentication is not enabled"})
+		return userTOTP, false
+	}
+	return userTOTP, true
+}
+
+// checkSecondFactor accepts a current authenticator code or an unused
+// recovery code, spending it either way. It responds 401 and returns false
+// when code is neither.
+func (handler *Handler) checkSecondFactor(w http.ResponseWriter, r *http.Request, userTOTP database.UserTotp, code string) bool {
+	if step, valid := totp.Validate(userTOTP.Secret, code, time.Now()); valid {
+		used, err := handler.ApiConfig.DB.UseTOTPStep(r.Context(), database.UseTOTPStepParams{
+			UserID:       userTOTP.UserID,
+			LastUsedStep: step,
+		})
+		if err != nil {
+			respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to check code"})
+			return false
+		}
+		if used == 1 {
+			return true
+		}
+	}
+
+	used, err := handler.ApiConfig.DB.UseRecoveryCode(r.Context(), database.UseRecoveryCodeParams{
+		UserID:   userTOTP.UserID,
+		CodeHash: auth.HashToken(normalizeRecoveryCode(code)),
+	})
+	if err != nil {
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to check code"})
+		return false
+	}
+	if used == 1 {
+		return true
+	}
+
+	respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Invalid code"})
+	return false
+}
+
+// replaceRecoveryCodes stores hashes of fresh codes in place of the old
+// ones within queries' transaction and returns the codes themselves.
+func (handler *Handler) replaceRecoveryCodes(ctx context.Context, queries *database.Queries, userID uuid.UUID) ([]string, error) {
+	if err := queries.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))[:10]
+		code := raw[:5] + "-" + raw[5:]
+		if err := queries.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: auth.HashToken(normalizeRecoveryCode(code)),
+		}); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode lets users type codes without the dash or in any case.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
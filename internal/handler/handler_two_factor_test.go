@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Anything-That-Works/GoPath/internal/database"
+	"github.com/Anything-That-Works/GoPath/internal/model"
+	"github.com/Anything-That-Works/GoPath/internal/totp"
+	"github.com/google/uuid"
+)
+
+// fakeTOTPStore answers the queries checkSecondFactor runs for one user,
+// applying UseTOTPStep's condition to the last step it accepted. It has no
+// recovery codes.
+type fakeTOTPStore struct {
+	t            *testing.T
+	lastUsedStep int64
+}
+
+func (store *fakeTOTPStore) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	switch {
+	case strings.Contains(query, "UseTOTPStep"):
+		// the step is only taken if it is later than the last one used
+		if !strings.Contains(query, "last_used_step < $2") {
+			store.t.Fatalf("UseTOTPStep no longer requires a later step:\n%s", query)
+		}
+		step := args[1].(int64)
+		if step <= store.lastUsedStep {
+			return driver.RowsAffected(0), nil
+		}
+		store.lastUsedStep = step
+		return driver.RowsAffected(1), nil
+	case strings.Contains(query, "UseRecoveryCode"):
+		return driver.RowsAffected(0), nil
+	}
+	return nil, errors.New("unexpected query")
+}
+
+func (store *fakeTOTPStore) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errors.New("unexpected prepare")
+}
+
+func (store *fakeTOTPStore) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("unexpected query")
+}
+
+func (store *fakeTOTPStore) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	store.t.Fatalf("unexpected query:\n%s", query)
+	return nil
+}
+
+// hotp is the 6-digit code of RFC 4226 for step, as an authenticator shows it.
+func hotp(key []byte, step int64) string {
+	mac := hmac.New(sha1.New, key)
+	mac.Write(binary.BigEndian.AppendUint64(nil, uint64(step)))
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:])&0x7fffffff)%1000000)
+}
+
+func TestCheckSecondFactorReplay(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+	now := time.Now()
+	current := now.Unix() / 30
+
+	tests := []struct {
+		name     string
+		lastUsed int64 // step accepted before
+		step     int64 // step of the code sent
+		want     bool
+	}{
+		{name: "fresh code", lastUsed: current - 5, step: current, want: true},
+		{name: "previous step after the current one", lastUsed: current, step: current - 1},
+		{name: "replayed code", lastUsed: current, step: current},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeTOTPStore{t: t, lastUsedStep: tt.lastUsed}
+			handler := New(&model.ApiConfig{DB: database.New(store)})
+			userTOTP := database.UserTotp{UserID: uuid.New(), Secret: secret}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "/", nil)
+			got := handler.checkSecondFactor(w, r, userTOTP, hotp(key, tt.step))
+			if got != tt.want {
+				t.Fatalf("checkSecondFactor = %v, want %v", got, tt.want)
+			}
+			if !got && w.Code != 401 {
+				t.Errorf("status = %d, want 401", w.Code)
+			}
+		})
+	}
+
+	// the same code twice: only the first gets in
+	store := &fakeTOTPStore{t: t}
+	handler := New(&model.ApiConfig{DB: database.New(store)})
+	userTOTP := database.UserTotp{UserID: uuid.New(), Secret: secret}
+	code := hotp(key, current)
+	for i, want := range []bool{true, false} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", nil)
+		if got := handler.checkSecondFactor(w, r, userTOTP, code); got != want {
+			t.Errorf("attempt %d: checkSecondFactor = %v, want %v", i+1, got, want)
+		}
+	}
+}
@@ -0,0 +1,474 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Anything-That-Works/GoPath/internal/database"
+	"github.com/Anything-That-Works/GoPath/internal/media"
+	"github.com/Anything-That-Works/GoPath/internal/model"
+	"github.com/Anything-That-Works/GoPath/internal/storage"
+	"github.com/google/uuid"
+)
+
+// Resumable uploads follow the tus 1.0 core protocol: POST creates an
+// upload, HEAD reports how much has been received and PATCH appends the next
+// chunk at that offset. Each chunk is stored as its own object; the parts
+// are joined into a file, and the files row created, once the last chunk
+// arrives. An interrupted chunk is discarded, so clients should keep chunks
+// small and resume from the offset HEAD returns.
+
+const (
+	maxUploadSize     = 2 << 30  // 2GB
+	maxChunkSize      = 16 << 20 // 16MB
+	uploadTTL         = 24 * time.Hour
+	tusVersion        = "1.0.0"
+	offsetContentType = "application/offset+octet-stream"
+)
+
+func (handler *Handler) HandlerCreateUpload(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
+	if !ok {
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	size, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || size <= 0 {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Upload-Length required"})
+		return
+	}
+	if size > maxUploadSize {
+		respondWithJSON(w, 413, model.APIResponse{Success: false, Message: "File too large, max 2GB allowed"})
+		return
+	}
+
+	metadata := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	filename, mimeType := metadata["filename"], metadata["filetype"]
+	if filename == "" {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "filename metadata required"})
+		return
+	}
+	if !isAllowedFileType(filename, mimeType) {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "File type not allowed"})
+		return
+	}
+
+	conversationID, ok := handler.uploadConversation(w, r, userID, metadata["conversation_id"])
+	if !ok {
+		return
+	}
+	if !handler.checkStorageQuota(w, r, userID, conversationID, size) {
+		return
+	}
+
+	upload, err := handler.ApiConfig.DB.CreateUpload(r.Context(), database.CreateUploadParams{
+		UploaderID:     userID,
+		Name:           filename,
+		MimeType:       mimeType,
+		Size:           size,
+		ExpiresAt:      time.Now().Add(uploadTTL),
+		ConversationID: conversationID,
+	})
+	if err != nil {
+		log.Printf("Failed to create upload: %v", err)
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to create upload"})
+		return
+	}
+
+	w.Header().Set("Location", "/v1/uploads/"+upload.ID.String())
+	setUploadHeaders(w, upload)
+	respondWithJSON(w, 201, model.APIResponse{
+		Success: true,
+		Message: "Upload created successfully",
+		Data: map[string]interface{}{
+			"id":         upload.ID,
+			"offset":     upload.UploadOffset,
+			"size":       upload.Size,
+			"expires_at": upload.ExpiresAt,
+		},
+	})
+}
+
+// HandlerGetUploadOffset answers HEAD with the offset the next chunk must start at.
+func (handler *Handler) HandlerGetUploadOffset(w http.ResponseWriter, r *http.Request) {
+	upload, ok := handler.getUpload(w, r)
+	if !ok {
+		return
+	}
+
+	setUploadHeaders(w, upload)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(200)
+}
+
+func (handler *Handler) HandlerPatchUpload(w http.ResponseWriter, r *http.Request) {
+	upload, ok := handler.getUpload(w, r)
+	if !ok {
+		return
+	}
+
+	if r.Header.Get("Content-Type") != offsetContentType {
+		respondWithJSON(w, 415, model.APIResponse{Success: false, Message: "Content-Type must be " + offsetContentType})
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Upload-Offset required"})
+		return
+	}
+	if offset != upload.UploadOffset {
+		setUploadHeaders(w, upload)
+		respondWithJSON(w, 409, model.APIResponse{Success: false, Message: "Upload-Offset does not match"})
+		return
+	}
+	if r.ContentLength < 0 {
+		respondWithJSON(w, 411, model.APIResponse{Success: false, Message: "Content-Length required"})
+		return
+	}
+	if r.ContentLength > maxChunkSize {
+		respondWithJSON(w, 413, model.APIResponse{Success: false, Message: "Chunk too large, max 16MB allowed"})
+		return
+	}
+	if r.ContentLength > upload.Size-upload.UploadOffset {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Chunk exceeds Upload-Length"})
+		return
+	}
+
+	// an empty PATCH at the end retries a completion that failed
+	if r.ContentLength > 0 {
+		body := bufio.NewReaderSize(http.MaxBytesReader(w, r.Body, r.ContentLength), sniffLen)
+		if offset == 0 && !handler.checkUploadType(w, r, body, upload) {
+			return
+		}
+
+		partPath, err := handler.ApiConfig.Storage.Save(body, r.ContentLength, upload.Name, "application/octet-stream")
+		if err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Incomplete chunk"})
+				return
+			}
+			log.Printf("Failed to save upload chunk: %v", err)
+			respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to save chunk"})
+			return
+		}
+
+		upload, err = handler.ApiConfig.DB.AppendUploadPart(r.Context(), database.AppendUploadPartParams{
+			PartSize:       r.ContentLength,
+			PartPath:       partPath,
+			ExpiresAt:      time.Now().Add(uploadTTL),
+			ID:             upload.ID,
+			UploaderID:     upload.UploaderID,
+			ExpectedOffset: offset,
+		})
+		if err != nil {
+			handler.deleteUploadParts([]string{partPath})
+			if err == sql.ErrNoRows {
+				respondWithJSON(w, 409, model.APIResponse{Success: false, Message: "Upload-Offset does not match"})
+				return
+			}
+			log.Printf("Failed to record upload chunk: %v", err)
+			respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to save chunk"})
+			return
+		}
+	}
+
+	setUploadHeaders(w, upload)
+	if upload.UploadOffset < upload.Size {
+		w.WriteHeader(204)
+		return
+	}
+	handler.completeUpload(w, r, upload)
+}
+
+func (handler *Handler) HandlerDeleteUpload(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
+	if !ok {
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	uploadID, err := uuid.Parse(strings.TrimPrefix(r.URL.Path, "/v1/uploads/"))
+	if err != nil {
+		respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "Upload not found"})
+		return
+	}
+
+	upload, err := handler.ApiConfig.DB.DeleteUpload(r.Context(), database.DeleteUploadParams{
+		ID:         uploadID,
+		UploaderID: userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "Upload not found"})
+			return
+		}
+		log.Printf("Failed to delete upload: %v", err)
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to delete upload"})
+		return
+	}
+
+	handler.deleteUploadParts(upload.PartPaths)
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.WriteHeader(204)
+}
+
+// ExpireUploads removes abandoned uploads and their parts every interval.
+func (handler *Handler) ExpireUploads(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		uploads, err := handler.ApiConfig.DB.DeleteExpiredUploads(context.Background())
+		if err != nil {
+			log.Printf("Failed to delete expired uploads: %v", err)
+			continue
+		}
+		for _, upload := range uploads {
+			handler.deleteUploadParts(upload.PartPaths)
+		}
+	}
+}
+
+// checkUploadType sniffs the first chunk against the type declared at
+// creation. A mismatch cancels the whole upload.
+func (handler *Handler) checkUploadType(w http.ResponseWriter, r *http.Request, body *bufio.Reader, upload database.Upload) bool {
+	head, err := body.Peek(int(min(r.ContentLength, sniffLen)))
+	if err != nil {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Incomplete chunk"})
+		return false
+	}
+	if _, ok := verifyMimeType(head, upload.MimeType); ok {
+		return true
+	}
+
+	handler.cancelUpload(r.Context(), upload)
+	respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "File content does not match its type"})
+	return false
+}
+
+// completeUpload joins the parts into the final object and records the file.
+// The upload is claimed first, in a short update of its own, so of two
+// requests completing it (a final chunk racing a retry) only one creates the
+// file; a failure hands the claim back and leaves it to retry.
+func (handler *Handler) completeUpload(w http.ResponseWriter, r *http.Request, upload database.Upload) {
+	claimed, err := handler.ApiConfig.DB.ClaimUpload(r.Context(), database.ClaimUploadParams{
+		ID:         upload.ID,
+		UploaderID: upload.UploaderID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithJSON(w, 409, model.APIResponse{Success: false, Message: "Upload is already being completed"})
+			return
+		}
+		log.Printf("Failed to claim upload %s: %v", upload.ID, err)
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to save file"})
+		return
+	}
+	upload = claimed
+
+	// the claim is handed back even if the client went away
+	release := func() {
+		if err := handler.ApiConfig.DB.ReleaseUpload(context.WithoutCancel(r.Context()), database.ReleaseUploadParams{
+			ID:         upload.ID,
+			UploaderID: upload.UploaderID,
+		}); err != nil {
+			log.Printf("Failed to release upload %s: %v", upload.ID, err)
+		}
+	}
+
+	// uploads in progress don't count, so check again now the file lands
+	if !handler.checkStorageQuota(w, r, upload.UploaderID, upload.ConversationID, upload.Size) {
+		handler.cancelUpload(r.Context(), upload)
+		return
+	}
+
+	parts := storage.Concat(handler.ApiConfig.Storage, upload.PartPaths)
+	defer func() {
+		if err := parts.Close(); err != nil {
+			log.Printf("Failed to close upload parts: %v", err)
+		}
+	}()
+
+	var stored storedUpload
+	if !media.CanStripMetadata(upload.MimeType) {
+		stored, err = handler.saveBlob(r.Context(), parts, upload.Size, upload.Name, upload.MimeType)
+		if err != nil {
+			log.Printf("Failed to assemble upload %s: %v", upload.ID, err)
+			release()
+			respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to save file"})
+			return
+		}
+	} else {
+		path, err := handler.ApiConfig.Storage.Save(parts, upload.Size, upload.Name, upload.MimeType)
+		if err != nil {
+			log.Printf("Failed to assemble upload %s: %v", upload.ID, err)
+			release()
+			respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to save file"})
+			return
+		}
+		stored, err = handler.stripAssembledImage(r.Context(), path, upload)
+		if err != nil {
+			if errors.Is(err, media.ErrInvalidImage) {
+				handler.cancelUpload(r.Context(), upload)
+				respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid image"})
+				return
+			}
+			log.Printf("Failed to strip metadata from upload %s: %v", upload.ID, err)
+			release()
+			respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to save file"})
+			return
+		}
+	}
+
+	// the file replaces the upload, so both change together
+	var savedFile database.File
+	err = handler.withTx(r.Context(), func(queries *database.Queries) error {
+		var err error
+		savedFile, err = handler.createFile(r.Context(), queries, database.CreateFileParams{
+			UploaderID:     upload.UploaderID,
+			Name:           upload.Name,
+			MimeType:       upload.MimeType,
+			Size:           stored.Size,
+			Path:           stored.Path,
+			OriginalPath:   stored.OriginalPath,
+			Sha256:         stored.sha256(),
+			ConversationID: upload.ConversationID,
+			ScanStatus:     handler.newFileScanStatus(),
+			UploadSha256:   stored.uploadSHA256(),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = queries.DeleteUpload(r.Context(), database.DeleteUploadParams{
+			ID:         upload.ID,
+			UploaderID: upload.UploaderID,
+		})
+		return err
+	})
+	if err != nil {
+		handler.deleteStoredUpload(r.Context(), stored)
+		// usage grew while the parts were joined
+		if respondIfQuotaExceeded(w, err) {
+			handler.cancelUpload(r.Context(), upload)
+			return
+		}
+		log.Printf("Failed to record upload %s: %v", upload.ID, err)
+		release()
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to save file metadata"})
+		return
+	}
+	handler.deleteUploadParts(upload.PartPaths)
+
+	savedFile, thumbnails := handler.describeImage(r.Context(), savedFile, nil)
+	savedFile = handler.scanStoredFile(r.Context(), savedFile)
+	if savedFile.ScanStatus == database.FileScanStatusInfected {
+		respondWithJSON(w, 422, model.APIResponse{Success: false, Message: scanRefusal(savedFile.ScanStatus)})
+		return
+	}
+
+	respondWithJSON(w, 201, model.APIResponse{
+		Success: true,
+		Message: "File uploaded successfully",
+		Data:    handler.fileData(savedFile, thumbnails),
+	})
+}
+
+// stripAssembledImage rewrites an assembled image without its metadata,
+// replacing the assembled object.
+func (handler *Handler) stripAssembledImage(ctx context.Context, path string, upload database.Upload) (storedUpload, error) {
+	content, err := handler.ApiConfig.Storage.Open(path)
+	if err != nil {
+		return storedUpload{}, err
+	}
+	stored, err := handler.saveUpload(ctx, content, upload.Size, upload.Name, upload.MimeType)
+	if closeErr := content.Close(); closeErr != nil {
+		log.Printf("Failed to close file: %v", closeErr)
+	}
+	if deleteErr := handler.ApiConfig.Storage.Delete(path); deleteErr != nil {
+		log.Printf("Failed to delete assembled upload %s: %v", path, deleteErr)
+	}
+	return stored, err
+}
+
+// cancelUpload drops an upload that can never complete, with its parts.
+func (handler *Handler) cancelUpload(ctx context.Context, upload database.Upload) {
+	if _, err := handler.ApiConfig.DB.DeleteUpload(ctx, database.DeleteUploadParams{
+		ID:         upload.ID,
+		UploaderID: upload.UploaderID,
+	}); err != nil {
+		log.Printf("Failed to delete rejected upload %s: %v", upload.ID, err)
+	}
+	handler.deleteUploadParts(upload.PartPaths)
+}
+
+func (handler *Handler) getUpload(w http.ResponseWriter, r *http.Request) (database.Upload, bool) {
+	userID, ok := r.Context().Value(contextKeyUserID).(uuid.UUID)
+	if !ok {
+		respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Unauthorized"})
+		return database.Upload{}, false
+	}
+
+	uploadID, err := uuid.Parse(strings.TrimPrefix(r.URL.Path, "/v1/uploads/"))
+	if err != nil {
+		respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "Upload not found"})
+		return database.Upload{}, false
+	}
+
+	upload, err := handler.ApiConfig.DB.GetUpload(r.Context(), database.GetUploadParams{
+		ID:         uploadID,
+		UploaderID: userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithJSON(w, 404, model.APIResponse{Success: false, Message: "Upload not found"})
+			return database.Upload{}, false
+		}
+		log.Printf("Failed to get upload: %v", err)
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to get upload"})
+		return database.Upload{}, false
+	}
+	return upload, true
+}
+
+func (handler *Handler) deleteUploadParts(paths []string) {
+	for _, path := range paths {
+		if err := handler.ApiConfig.Storage.Delete(path); err != nil {
+			log.Printf("Failed to delete upload part %s: %v", path, err)
+		}
+	}
+}
+
+func setUploadHeaders(w http.ResponseWriter, upload database.Upload) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.UploadOffset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+}
+
+// parseUploadMetadata decodes the tus Upload-Metadata header: comma
+// separated pairs of a key and a base64 value.
+func parseUploadMetadata(header string) map[string]string {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			continue
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			continue
+		}
+		metadata[key] = string(value)
+	}
+	return metadata
+}
@@ -44,6 +44,16 @@ func (handler *Handler) HandlerCreateUser(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if !validEmail(params.Email) {
+		payload := model.APIResponse{
+			Success: false,
+			Message: "Invalid email address",
+			Data:    nil,
+		}
+		respondWithJSON(w, 400, payload)
+		return
+	}
+
 	hash, err := bcrypt.GenerateFromPassword([]byte(params.Password), bcrypt.DefaultCost)
 	if err != nil {
 		log.Println("GenerateFromPassword error:", err)
@@ -76,7 +86,8 @@ func (handler *Handler) HandlerCreateUser(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	tr, tokenHash, err := auth.GenerateToken(user.ID, handler.ApiConfig.JWTSecretKey)
+	sessionID := uuid.New()
+	tr, tokenHash, err := auth.GenerateToken(user.ID, sessionID, handler.ApiConfig.JWTSecretKey)
 	if err != nil {
 		log.Println("GenerateToken error:", err)
 		payload := model.APIResponse{
@@ -95,6 +106,7 @@ func (handler *Handler) HandlerCreateUser(w http.ResponseWriter, r *http.Request
 		ExpiresAt: tr.RefreshToken.Expires,
 		UserAgent: sql.NullString{String: r.UserAgent(), Valid: true},
 		IpAddress: ip,
+		SessionID: sessionID,
 	})
 	if err != nil {
 		log.Println("CreateRefreshToken error:", err)
@@ -107,6 +119,11 @@ func (handler *Handler) HandlerCreateUser(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// the account works meanwhile; the user can ask for another link
+	if err := handler.sendVerificationEmail(r.Context(), user); err != nil {
+		log.Println("sendVerificationEmail error:", err)
+	}
+
 	payload := model.APIResponse{
 		Success: true,
 		Message: "User created successfully",
@@ -218,6 +235,11 @@ func (handler *Handler) HandlerUpdateUser(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if params.Email != nil && !validEmail(*params.Email) {
+		respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid email address"})
+		return
+	}
+
 	existingUser, err := handler.ApiConfig.DB.GetUserByID(r.Context(), userID)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -269,6 +291,12 @@ func (handler *Handler) HandlerUpdateUser(w http.ResponseWriter, r *http.Request
 		log.Println("Cache.Delete error:", err)
 	}
 
+	if user.Email != existingUser.Email {
+		if err := handler.sendVerificationEmail(r.Context(), user); err != nil {
+			log.Println("sendVerificationEmail error:", err)
+		}
+	}
+
 	respondWithJSON(w, 200, model.APIResponse{
 		Success: true,
 		Message: "User updated successfully",
@@ -309,27 +337,61 @@ func (handler *Handler) HandlerLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tr, tokenHash, err := auth.GenerateToken(user.ID, handler.ApiConfig.JWTSecretKey)
+	// with 2FA on, the password only earns a challenge to answer with a code
+	userTOTP, err := handler.ApiConfig.DB.GetUserTOTP(r.Context(), user.ID)
+	if err != nil && err != sql.ErrNoRows {
+		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to fetch two-factor settings"})
+		return
+	}
+	if err == nil && userTOTP.EnabledAt.Valid {
+		challenge, err := handler.createLoginChallenge(r.Context(), user.ID)
+		if err != nil {
+			log.Printf("Failed to create login challenge: %v", err)
+			respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to create login challenge"})
+			return
+		}
+		respondWithJSON(w, 200, model.APIResponse{
+			Success: true,
+			Message: "Two-factor authentication required",
+			Data:    challenge,
+		})
+		return
+	}
+
+	tr, ok := handler.createSession(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	respondWithJSON(w, 200, model.APIResponse{
+		Success: true,
+		Message: "Login successful",
+		Data:    tr,
+	})
+}
+
+// createSession starts a session for userID on the requesting device,
+// issuing an access token and a stored refresh token for it. It responds
+// and returns false when it fails.
+func (handler *Handler) createSession(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (model.TokenResponse, bool) {
+	sessionID := uuid.New()
+	tr, tokenHash, err := auth.GenerateToken(userID, sessionID, handler.ApiConfig.JWTSecretKey)
 	if err != nil {
 		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to generate token"})
-		return
+		return model.TokenResponse{}, false
 	}
 
 	_, err = handler.ApiConfig.DB.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
-		UserID:    user.ID,
+		UserID:    userID,
 		TokenHash: tokenHash,
 		ExpiresAt: tr.RefreshToken.Expires,
 		UserAgent: sql.NullString{String: r.UserAgent(), Valid: true},
 		IpAddress: handler.getIPAddress(r),
+		SessionID: sessionID,
 	})
 	if err != nil {
 		respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to store refresh token"})
-		return
+		return model.TokenResponse{}, false
 	}
-
-	respondWithJSON(w, 200, model.APIResponse{
-		Success: true,
-		Message: "Login successful",
-		Data:    tr,
-	})
+	return tr, true
 }
@@ -3,6 +3,7 @@ package handler
 import (
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/Anything-That-Works/GoPath/internal/database"
@@ -35,21 +36,36 @@ func (handler *Handler) HandlerWebSocket(hub *ws.Hub, msgHandler *ws.MessageHand
 			return
 		}
 
-		conversationIDStr := r.URL.Query().Get("conversation_id")
-		conversationID, err := uuid.Parse(conversationIDStr)
-		if err != nil {
-			respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid conversation ID"})
-			return
-		}
+		// conversation_id is optional: multiplexed clients subscribe over the
+		// socket instead, older clients still pass a single conversation here
+		var conversationID uuid.UUID
+		var lastSeq *int64
+		if conversationIDStr := r.URL.Query().Get("conversation_id"); conversationIDStr != "" {
+			parsed, err := uuid.Parse(conversationIDStr)
+			if err != nil {
+				respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid conversation ID"})
+				return
+			}
+			conversationID = parsed
 
-		// verify membership
-		_, err = handler.ApiConfig.DB.GetConversationMember(r.Context(), database.GetConversationMemberParams{
-			ConversationID: conversationID,
-			UserID:         userID,
-		})
-		if err != nil {
-			respondWithJSON(w, 403, model.APIResponse{Success: false, Message: "Not a member of this conversation"})
-			return
+			if lastSeqStr := r.URL.Query().Get("last_seq"); lastSeqStr != "" {
+				seq, err := strconv.ParseInt(lastSeqStr, 10, 64)
+				if err != nil || seq < 0 {
+					respondWithJSON(w, 400, model.APIResponse{Success: false, Message: "Invalid last_seq"})
+					return
+				}
+				lastSeq = &seq
+			}
+
+			// verify membership
+			_, err = handler.ApiConfig.DB.GetConversationMember(r.Context(), database.GetConversationMemberParams{
+				ConversationID: conversationID,
+				UserID:         userID,
+			})
+			if err != nil {
+				respondWithJSON(w, 403, model.APIResponse{Success: false, Message: "Not a member of this conversation"})
+				return
+			}
 		}
 
 		conn, err := upgrader.Upgrade(w, r, nil)
@@ -57,18 +73,15 @@ func (handler *Handler) HandlerWebSocket(hub *ws.Hub, msgHandler *ws.MessageHand
 			return
 		}
 
-		client := &ws.Client{
-			ID:             uuid.New(),
-			UserID:         userID,
-			ConversationID: conversationID,
-			Hub:            hub,
-			Conn:           conn,
-			Send:           make(chan []byte, 256),
-		}
+		client := ws.NewClient(hub, conn, userID)
+		client.DefaultConversationID = conversationID
 
 		hub.Register <- client
 
 		go client.WritePump()
+		if conversationID != uuid.Nil {
+			msgHandler.Subscribe(client, conversationID, lastSeq)
+		}
 		go client.ReadPump(msgHandler.Handle)
 	})
 }
@@ -1,10 +1,13 @@
 package handler
 
 import (
+	"context"
+	"log"
 	"net"
 	"net/http"
 	"strings"
 
+	"github.com/Anything-That-Works/GoPath/internal/database"
 	"github.com/Anything-That-Works/GoPath/internal/model"
 	"github.com/sqlc-dev/pqtype"
 )
@@ -73,3 +76,19 @@ func HandlerReadiness(w http.ResponseWriter, r *http.Request) {
 		Message: "Ready",
 	})
 }
+
+// withTx runs fn with queries bound to one transaction, committing if fn
+// succeeds and rolling back otherwise.
+func (handler *Handler) withTx(ctx context.Context, fn func(queries *database.Queries) error) error {
+	tx, err := handler.ApiConfig.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(handler.ApiConfig.DB.WithTx(tx)); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			log.Printf("Failed to roll back transaction: %v", rollbackErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
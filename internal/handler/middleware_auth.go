@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"log"
 	"net/http"
 	"strings"
 
@@ -29,7 +30,21 @@ func (handler *Handler) MiddlewareAuth(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
+		// ending a session (logout, a password reset, a stolen refresh token)
+		// ends its access tokens as well, rather than letting them run out
+		active, err := handler.ApiConfig.DB.IsSessionActive(r.Context(), claims.SessionID)
+		if err != nil {
+			log.Printf("Failed to check session: %v", err)
+			respondWithJSON(w, 500, model.APIResponse{Success: false, Message: "Failed to verify session"})
+			return
+		}
+		if !active {
+			respondWithJSON(w, 401, model.APIResponse{Success: false, Message: "Session has ended, log in again"})
+			return
+		}
+
 		ctx := context.WithValue(r.Context(), contextKeyUserID, claims.UserID)
+		ctx = context.WithValue(ctx, contextKeySessionID, claims.SessionID)
 		next(w, r.WithContext(ctx))
 	}
 }
@@ -0,0 +1,213 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// ThumbnailSizes are the longest-edge sizes generated for images, largest
+// first so each can be scaled down from the previous one.
+var ThumbnailSizes = []int{640, 320, 160}
+
+// images bigger than this only get their dimensions recorded, so a small
+// compressed file can't make the server decode gigabytes of pixels
+const maxImagePixels = 40_000_000
+
+const blurhashSourceSize = 32
+
+var ErrNotImage = errors.New("not a supported image")
+
+type Thumbnail struct {
+	Size     int
+	Width    int
+	Height   int
+	MimeType string
+	Data     []byte
+}
+
+type ImageInfo struct {
+	Width      int
+	Height     int
+	Blurhash   string
+	Thumbnails []Thumbnail
+}
+
+// IsImage reports whether AnalyzeImage handles mimeType.
+func IsImage(mimeType string) bool {
+	return mimeType == "image/jpeg" || mimeType == "image/png" || mimeType == "image/gif"
+}
+
+// AnalyzeImage measures an image and renders its thumbnails and blurhash.
+// Thumbnails are only made for sizes smaller than the image itself.
+func AnalyzeImage(r io.ReadSeeker, mimeType string) (*ImageInfo, error) {
+	if !IsImage(mimeType) {
+		return nil, ErrNotImage
+	}
+
+	orientation := ReadOrientation(r, mimeType)
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, err
+	}
+	// report the size the image is displayed at
+	info := &ImageInfo{Width: config.Width, Height: config.Height}
+	if orientation >= 5 {
+		info.Width, info.Height = config.Height, config.Width
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return info, nil
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	decoded, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	current := toRGBA(decoded)
+	for _, size := range ThumbnailSizes {
+		if size >= max(info.Width, info.Height) {
+			continue
+		}
+		width, height := fitWithin(current.Bounds().Dx(), current.Bounds().Dy(), size)
+		current = resize(current, width, height)
+
+		thumbnail, err := encodeThumbnail(orient(current, orientation), mimeType)
+		if err != nil {
+			return nil, err
+		}
+		thumbnail.Size = size
+		info.Thumbnails = append(info.Thumbnails, thumbnail)
+	}
+
+	width, height := fitWithin(current.Bounds().Dx(), current.Bounds().Dy(), blurhashSourceSize)
+	info.Blurhash = Blurhash(orient(resize(current, width, height), orientation))
+	return info, nil
+}
+
+// thumbnails keep JPEG for photos and PNG for anything that may be transparent
+func encodeThumbnail(img *image.RGBA, sourceType string) (Thumbnail, error) {
+	var buf bytes.Buffer
+	thumbnail := Thumbnail{Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+	if sourceType == "image/jpeg" {
+		thumbnail.MimeType = "image/jpeg"
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 80}); err != nil {
+			return Thumbnail{}, err
+		}
+	} else {
+		thumbnail.MimeType = "image/png"
+		if err := png.Encode(&buf, img); err != nil {
+			return Thumbnail{}, err
+		}
+	}
+	thumbnail.Data = buf.Bytes()
+	return thumbnail, nil
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
+
+// orient applies an EXIF orientation, turning the stored pixels into the
+// image as it should be displayed.
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored
+				dx, dy = width-1-x, y
+			case 3: // rotated 180°
+				dx, dy = width-1-x, height-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, height-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90° clockwise
+				dx, dy = height-1-y, x
+			case 7: // transversed
+				dx, dy = height-1-y, width-1-x
+			case 8: // rotated 90° counter-clockwise
+				dx, dy = y, width-1-x
+			}
+			copy(dst.Pix[dy*dst.Stride+dx*4:dy*dst.Stride+dx*4+4], src.Pix[y*src.Stride+x*4:y*src.Stride+x*4+4])
+		}
+	}
+	return dst
+}
+
+// fitWithin scales width and height so the longest edge is at most size.
+func fitWithin(width int, height int, size int) (int, int) {
+	if width <= size && height <= size {
+		return width, height
+	}
+	if width >= height {
+		return size, max(1, height*size/width)
+	}
+	return max(1, width*size/height), size
+}
+
+// resize downscales with a box filter, averaging every source pixel that
+// falls into each destination pixel.
+func resize(src *image.RGBA, width int, height int) *image.RGBA {
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for dy := 0; dy < height; dy++ {
+		sy0 := dy * srcHeight / height
+		sy1 := max(sy0+1, (dy+1)*srcHeight/height)
+		for dx := 0; dx < width; dx++ {
+			sx0 := dx * srcWidth / width
+			sx1 := max(sx0+1, (dx+1)*srcWidth/width)
+
+			var r, g, b, a, n int
+			for sy := sy0; sy < sy1; sy++ {
+				offset := sy*src.Stride + sx0*4
+				for sx := sx0; sx < sx1; sx++ {
+					r += int(src.Pix[offset])
+					g += int(src.Pix[offset+1])
+					b += int(src.Pix[offset+2])
+					a += int(src.Pix[offset+3])
+					offset += 4
+					n++
+				}
+			}
+
+			i := dy*dst.Stride + dx*4
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,474 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+// Metadata is stripped by rewriting the container rather than re-encoding,
+// so pixels are untouched. EXIF, XMP, IPTC and text blocks are dropped;
+// colour profiles are kept. When a photo relies on its EXIF orientation, a
+// minimal EXIF block holding only that tag takes the old one's place so
+// viewers still rotate it.
+
+var ErrInvalidImage = errors.New("invalid image")
+
+// anything bigger is not worth reading just for the orientation tag
+const maxExifRead = 1 << 20
+
+// CanStripMetadata reports whether StripMetadata handles mimeType.
+func CanStripMetadata(mimeType string) bool {
+	return mimeType == "image/jpeg" || mimeType == "image/png" || mimeType == "image/webp"
+}
+
+// StrippedImage reads an image without its metadata. It seeks the source
+// as it goes, so the source must not be read elsewhere meanwhile.
+type StrippedImage struct {
+	Size        int64
+	Orientation int // EXIF orientation, 1 when absent
+
+	src     io.ReadSeeker
+	srcSize int64
+	pieces  []piece
+	current io.Reader
+}
+
+// a piece is either literal bytes or a range of the source
+type piece struct {
+	data   []byte
+	offset int64
+	length int64
+}
+
+func StripMetadata(r io.ReadSeeker, mimeType string) (*StrippedImage, error) {
+	srcSize, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	s := &StrippedImage{src: r, srcSize: srcSize, Orientation: 1}
+	switch mimeType {
+	case "image/jpeg":
+		err = s.planJPEG()
+	case "image/png":
+		err = s.planPNG()
+	case "image/webp":
+		err = s.planWebP()
+	default:
+		return nil, ErrNotImage
+	}
+	if err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrInvalidImage
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+// ReadOrientation returns the EXIF orientation of an image, or 1.
+func ReadOrientation(r io.ReadSeeker, mimeType string) int {
+	if !CanStripMetadata(mimeType) {
+		return 1
+	}
+	stripped, err := StripMetadata(r, mimeType)
+	if err != nil {
+		return 1
+	}
+	return stripped.Orientation
+}
+
+func (s *StrippedImage) Read(p []byte) (int, error) {
+	for {
+		if s.current == nil {
+			if len(s.pieces) == 0 {
+				return 0, io.EOF
+			}
+			next := s.pieces[0]
+			s.pieces = s.pieces[1:]
+			if next.data != nil {
+				s.current = bytes.NewReader(next.data)
+			} else {
+				if _, err := s.src.Seek(next.offset, io.SeekStart); err != nil {
+					return 0, err
+				}
+				s.current = io.LimitReader(s.src, next.length)
+			}
+		}
+
+		n, err := s.current.Read(p)
+		if err == io.EOF {
+			s.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (s *StrippedImage) keep(offset int64, length int64) {
+	if length <= 0 {
+		return
+	}
+	s.Size += length
+	if last := len(s.pieces) - 1; last >= 0 && s.pieces[last].data == nil &&
+		s.pieces[last].offset+s.pieces[last].length == offset {
+		s.pieces[last].length += length
+		return
+	}
+	s.pieces = append(s.pieces, piece{offset: offset, length: length})
+}
+
+func (s *StrippedImage) insert(data []byte) {
+	s.Size += int64(len(data))
+	s.pieces = append(s.pieces, piece{data: data})
+}
+
+// keepRest keeps everything from offset to the end of the source.
+func (s *StrippedImage) keepRest(offset int64) error {
+	s.keep(offset, s.srcSize-offset)
+	return nil
+}
+
+// fits reports whether length bytes from offset lie within the source.
+// Lengths come from the file itself, so they are checked before anything
+// is allocated or kept.
+func (s *StrippedImage) fits(offset int64, length int64) bool {
+	return offset >= 0 && length >= 0 && length <= s.srcSize-offset
+}
+
+func (s *StrippedImage) readAt(offset int64, n int64) ([]byte, error) {
+	if !s.fits(offset, n) {
+		return nil, ErrInvalidImage
+	}
+	if _, err := s.src.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(s.src, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+var (
+	exifHeader = []byte("Exif\x00\x00")
+	iccHeader  = []byte("ICC_PROFILE\x00")
+)
+
+// planJPEG walks the marker segments up to the start of scan; everything
+// after it is entropy-coded image data and kept as is.
+func (s *StrippedImage) planJPEG() error {
+	soi, err := s.readAt(0, 2)
+	if err != nil {
+		return err
+	}
+	if soi[0] != 0xFF || soi[1] != 0xD8 {
+		return ErrInvalidImage
+	}
+	s.keep(0, 2)
+
+	offset := int64(2)
+	for {
+		header, err := s.readAt(offset, 4)
+		if err != nil {
+			return err
+		}
+		if header[0] != 0xFF {
+			return ErrInvalidImage
+		}
+		marker := header[1]
+		if marker == 0xFF {
+			// fill byte before a marker
+			offset++
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			return s.keepRest(offset)
+		}
+
+		length := int64(binary.BigEndian.Uint16(header[2:4]))
+		if length < 2 {
+			return ErrInvalidImage
+		}
+		segmentLength := 2 + length
+		if !s.fits(offset, segmentLength) {
+			return ErrInvalidImage
+		}
+
+		switch {
+		case marker == 0xE1:
+			if length-2 <= maxExifRead {
+				data, err := s.readAt(offset+4, length-2)
+				if err != nil {
+					return err
+				}
+				if bytes.HasPrefix(data, exifHeader) {
+					s.keepOrientation(data[len(exifHeader):], func(tiff []byte) []byte {
+						return jpegSegment(0xE1, append(append([]byte{}, exifHeader...), tiff...))
+					})
+				}
+			}
+		case marker == 0xE2:
+			prefix, err := s.readAt(offset+4, min(length-2, int64(len(iccHeader))))
+			if err != nil {
+				return err
+			}
+			if bytes.Equal(prefix, iccHeader) {
+				s.keep(offset, segmentLength)
+			}
+		case marker >= 0xE3 && marker <= 0xEF && marker != 0xEE, marker == 0xFE:
+			// other application segments and comments
+		default:
+			// JFIF, Adobe colour transform, tables and frame headers
+			s.keep(offset, segmentLength)
+		}
+		offset += segmentLength
+	}
+}
+
+func jpegSegment(marker byte, data []byte) []byte {
+	segment := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(data)+2))
+	return append(segment, data...)
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+func (s *StrippedImage) planPNG() error {
+	signature, err := s.readAt(0, 8)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(signature, pngSignature) {
+		return ErrInvalidImage
+	}
+	s.keep(0, 8)
+
+	offset := int64(8)
+	for {
+		header, err := s.readAt(offset, 8)
+		if err != nil {
+			return err
+		}
+		length := int64(binary.BigEndian.Uint32(header[:4]))
+		chunkType := string(header[4:8])
+		chunkLength := 12 + length
+		if !s.fits(offset, chunkLength) {
+			return ErrInvalidImage
+		}
+
+		if chunkType == "eXIf" && length <= maxExifRead {
+			data, err := s.readAt(offset+8, length)
+			if err != nil {
+				return err
+			}
+			s.keepOrientation(data, func(tiff []byte) []byte {
+				return pngChunk("eXIf", tiff)
+			})
+		}
+		if !pngMetadataChunks[chunkType] {
+			s.keep(offset, chunkLength)
+		}
+		if chunkType == "IEND" {
+			return nil
+		}
+		offset += chunkLength
+	}
+}
+
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(chunk[:4], uint32(len(data)))
+	copy(chunk[4:8], chunkType)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+const (
+	webpFlagEXIF   = 0x08
+	webpFlagXMP    = 0x04
+	webpVP8XLength = 10
+)
+
+// planWebP drops the EXIF and XMP chunks of an extended WebP and clears
+// their VP8X flags. Simple WebP files carry no metadata.
+func (s *StrippedImage) planWebP() error {
+	header, err := s.readAt(0, 12)
+	if err != nil {
+		return err
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WEBP" {
+		return ErrInvalidImage
+	}
+	end := 8 + int64(binary.LittleEndian.Uint32(header[4:8]))
+
+	var pieces []piece
+	var vp8x []byte
+	vp8xIndex := -1
+	exifKept := false
+
+	offset := int64(12)
+	for offset+8 <= end {
+		chunkHeader, err := s.readAt(offset, 8)
+		if err != nil {
+			return err
+		}
+		fourCC := string(chunkHeader[:4])
+		length := int64(binary.LittleEndian.Uint32(chunkHeader[4:8]))
+		chunkLength := 8 + length + length%2
+		// the padding byte of a final odd-sized chunk is often left out
+		if !s.fits(offset, 8+length) {
+			return ErrInvalidImage
+		}
+		chunkLength = min(chunkLength, s.srcSize-offset)
+
+		switch fourCC {
+		case "VP8X":
+			if length != webpVP8XLength {
+				return ErrInvalidImage
+			}
+			if vp8x, err = s.readAt(offset+8, length); err != nil {
+				return err
+			}
+			// rewritten once we know whether EXIF stays
+			vp8xIndex = len(pieces)
+			pieces = append(pieces, piece{})
+		case "EXIF":
+			if length > maxExifRead {
+				break
+			}
+			data, err := s.readAt(offset+8, length)
+			if err != nil {
+				return err
+			}
+			if orientation := parseOrientation(bytes.TrimPrefix(data, exifHeader)); orientation > 1 {
+				s.Orientation = orientation
+				exifKept = true
+				pieces = append(pieces, piece{data: webpChunk("EXIF", orientationTIFF(orientation))})
+			}
+		case "XMP ":
+		default:
+			pieces = append(pieces, piece{offset: offset, length: chunkLength})
+		}
+		offset += chunkLength
+	}
+
+	if vp8xIndex >= 0 {
+		flags := vp8x[0] &^ (webpFlagEXIF | webpFlagXMP)
+		if exifKept {
+			flags |= webpFlagEXIF
+		}
+		pieces[vp8xIndex] = piece{data: webpChunk("VP8X", append([]byte{flags}, vp8x[1:]...))}
+	}
+
+	size := int64(4) // "WEBP"
+	for _, p := range pieces {
+		if p.data != nil {
+			size += int64(len(p.data))
+		} else {
+			size += p.length
+		}
+	}
+
+	riff := make([]byte, 12)
+	copy(riff, "RIFF")
+	binary.LittleEndian.PutUint32(riff[4:8], uint32(size))
+	copy(riff[8:], "WEBP")
+	s.insert(riff)
+	for _, p := range pieces {
+		if p.data != nil {
+			s.insert(p.data)
+		} else {
+			s.keep(p.offset, p.length)
+		}
+	}
+	return nil
+}
+
+func webpChunk(fourCC string, data []byte) []byte {
+	chunk := make([]byte, 8, 8+len(data)+1)
+	copy(chunk[:4], fourCC)
+	binary.LittleEndian.PutUint32(chunk[4:8], uint32(len(data)))
+	chunk = append(chunk, data...)
+	if len(data)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// keepOrientation records the orientation found in an EXIF TIFF block and,
+// when the image needs rotating, inserts the wrapped minimal replacement.
+func (s *StrippedImage) keepOrientation(tiff []byte, wrap func(tiff []byte) []byte) {
+	orientation := parseOrientation(tiff)
+	if orientation <= 1 {
+		return
+	}
+	s.Orientation = orientation
+	s.insert(wrap(orientationTIFF(orientation)))
+}
+
+// parseOrientation reads tag 0x0112 from IFD0 of a TIFF block.
+func parseOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd : ifd+2]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// orientationTIFF builds a little-endian TIFF block whose only entry is the
+// orientation tag.
+func orientationTIFF(orientation int) []byte {
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	tiff = binary.LittleEndian.AppendUint16(tiff, 1) // entry count
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3) // SHORT
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, uint16(orientation))
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0)
+	return binary.LittleEndian.AppendUint32(tiff, 0) // no next IFD
+}
@@ -0,0 +1,238 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+)
+
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 4; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 60), G: uint8(y * 80), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+// exifTIFF is a TIFF block with the orientation tag and a camera model,
+// which stripping must not keep.
+func exifTIFF(orientation int) []byte {
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	tiff = binary.LittleEndian.AppendUint16(tiff, 2) // entry count
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3) // SHORT
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, uint16(orientation))
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x0110) // camera model
+	tiff = binary.LittleEndian.AppendUint16(tiff, 2)      // ASCII
+	tiff = binary.LittleEndian.AppendUint32(tiff, 4)
+	tiff = append(tiff, "Cam\x00"...)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 0) // no next IFD
+	return append(tiff, "GPS 51.5N 0.1W"...)
+}
+
+// testJPEG is an encoded JPEG with EXIF, an ICC profile, IPTC and a
+// comment inserted after SOI.
+func testJPEG(t testing.TB, orientation int) []byte {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, testImage(), nil); err != nil {
+		t.Fatalf("encode JPEG: %v", err)
+	}
+	data := encoded.Bytes()
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, jpegSegment(0xE1, append(append([]byte{}, exifHeader...), exifTIFF(orientation)...))...)
+	out = append(out, jpegSegment(0xE2, append(append([]byte{}, iccHeader...), "\x01\x01profile"...))...)
+	out = append(out, jpegSegment(0xED, []byte("Photoshop 3.0\x00secret caption"))...)
+	out = append(out, jpegSegment(0xFE, []byte("secret comment"))...)
+	return append(out, data[2:]...)
+}
+
+// testPNG is an encoded PNG with eXIf and tEXt chunks after IHDR.
+func testPNG(t testing.TB, orientation int) []byte {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, testImage()); err != nil {
+		t.Fatalf("encode PNG: %v", err)
+	}
+	data := encoded.Bytes()
+
+	ihdrEnd := 8 + 12 + 13
+	out := append([]byte{}, data[:ihdrEnd]...)
+	out = append(out, pngChunk("eXIf", exifTIFF(orientation))...)
+	out = append(out, pngChunk("tEXt", []byte("Comment\x00secret comment"))...)
+	return append(out, data[ihdrEnd:]...)
+}
+
+// testWebP is an extended WebP with EXIF and XMP. The image data is not
+// real VP8L, as stripping never decodes it.
+func testWebP(orientation int) []byte {
+	vp8x := make([]byte, webpVP8XLength)
+	vp8x[0] = webpFlagEXIF | webpFlagXMP
+	var body []byte
+	body = append(body, "WEBP"...)
+	body = append(body, webpChunk("VP8X", vp8x)...)
+	body = append(body, webpChunk("VP8L", []byte("pixels"))...)
+	body = append(body, webpChunk("EXIF", exifTIFF(orientation))...)
+	body = append(body, webpChunk("XMP ", []byte("<x:xmpmeta>secret</x:xmpmeta>"))...)
+	return riff(body)
+}
+
+func riff(body []byte) []byte {
+	out := []byte("RIFF")
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(body)))
+	return append(out, body...)
+}
+
+func strip(t testing.TB, data []byte, mimeType string) ([]byte, *StrippedImage, error) {
+	stripped, err := StripMetadata(bytes.NewReader(data), mimeType)
+	if err != nil {
+		return nil, nil, err
+	}
+	out, err := io.ReadAll(stripped)
+	if err != nil {
+		t.Fatalf("read stripped image: %v", err)
+	}
+	if int64(len(out)) != stripped.Size {
+		t.Fatalf("read %d bytes, Size says %d", len(out), stripped.Size)
+	}
+	return out, stripped, nil
+}
+
+func TestStripMetadata(t *testing.T) {
+	tests := []struct {
+		name            string
+		data            []byte
+		mimeType        string
+		wantOrientation int
+		keep            []string // content that must survive
+	}{
+		{name: "jpeg", data: testJPEG(t, 1), mimeType: "image/jpeg", wantOrientation: 1, keep: []string{"ICC_PROFILE"}},
+		{name: "jpeg rotated", data: testJPEG(t, 6), mimeType: "image/jpeg", wantOrientation: 6, keep: []string{"ICC_PROFILE", "Exif"}},
+		{name: "png", data: testPNG(t, 1), mimeType: "image/png", wantOrientation: 1},
+		{name: "png rotated", data: testPNG(t, 8), mimeType: "image/png", wantOrientation: 8, keep: []string{"eXIf"}},
+		{name: "webp", data: testWebP(1), mimeType: "image/webp", wantOrientation: 1, keep: []string{"VP8L"}},
+		{name: "webp rotated", data: testWebP(3), mimeType: "image/webp", wantOrientation: 3, keep: []string{"VP8L", "EXIF"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, stripped, err := strip(t, tt.data, tt.mimeType)
+			if err != nil {
+				t.Fatalf("StripMetadata: %v", err)
+			}
+			if stripped.Orientation != tt.wantOrientation {
+				t.Errorf("Orientation = %d, want %d", stripped.Orientation, tt.wantOrientation)
+			}
+			for _, secret := range []string{"secret", "GPS", "Cam"} {
+				if bytes.Contains(out, []byte(secret)) {
+					t.Errorf("stripped image still contains %q", secret)
+				}
+			}
+			for _, kept := range tt.keep {
+				if !bytes.Contains(out, []byte(kept)) {
+					t.Errorf("stripped image lost %q", kept)
+				}
+			}
+
+			if tt.mimeType != "image/webp" {
+				if _, _, err := image.Decode(bytes.NewReader(out)); err != nil {
+					t.Errorf("stripped image doesn't decode: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestStripMetadataWebPHeader(t *testing.T) {
+	out, _, err := strip(t, testWebP(6), "image/webp")
+	if err != nil {
+		t.Fatalf("StripMetadata: %v", err)
+	}
+	if size := binary.LittleEndian.Uint32(out[4:8]); int(size) != len(out)-8 {
+		t.Errorf("RIFF size = %d, want %d", size, len(out)-8)
+	}
+	// VP8X comes first, with only the EXIF flag left
+	if string(out[12:16]) != "VP8X" {
+		t.Fatalf("first chunk = %q, want VP8X", out[12:16])
+	}
+	if flags := out[20]; flags != webpFlagEXIF {
+		t.Errorf("VP8X flags = %#x, want %#x", flags, webpFlagEXIF)
+	}
+}
+
+func TestStripMetadataInvalid(t *testing.T) {
+	png := testPNG(t, 1)
+	hugePNG := append([]byte{}, png[:8]...)
+	hugePNG = binary.BigEndian.AppendUint32(hugePNG, 0xFFFFFFFF)
+	hugePNG = append(hugePNG, "tEXt"...)
+
+	vp8x := func(length uint32) []byte {
+		body := []byte("WEBPVP8X")
+		body = binary.LittleEndian.AppendUint32(body, length)
+		return riff(append(body, make([]byte, 10)...))
+	}
+	overrun := []byte("WEBPVP8L")
+	overrun = binary.LittleEndian.AppendUint32(overrun, 1<<20)
+	overrun = riff(append(overrun, "pixels"...))
+
+	jpegOverrun := []byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF}
+	jpegOverrun = append(jpegOverrun, exifHeader...)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		mimeType string
+	}{
+		{name: "empty jpeg", data: nil, mimeType: "image/jpeg"},
+		{name: "jpeg signature", data: []byte("GIF89a"), mimeType: "image/jpeg"},
+		{name: "jpeg truncated", data: testJPEG(t, 1)[:40], mimeType: "image/jpeg"},
+		{name: "jpeg segment overrun", data: jpegOverrun, mimeType: "image/jpeg"},
+		{name: "png signature", data: []byte("not a png at all"), mimeType: "image/png"},
+		{name: "png truncated", data: png[:len(png)-20], mimeType: "image/png"},
+		{name: "png huge chunk", data: hugePNG, mimeType: "image/png"},
+		{name: "webp signature", data: []byte("RIFF\x04\x00\x00\x00WAVE"), mimeType: "image/webp"},
+		{name: "webp huge vp8x", data: vp8x(0xFFFFFFFF), mimeType: "image/webp"},
+		{name: "webp empty vp8x", data: vp8x(0), mimeType: "image/webp"},
+		{name: "webp short vp8x", data: vp8x(4), mimeType: "image/webp"},
+		{name: "webp chunk overrun", data: overrun, mimeType: "image/webp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := strip(t, tt.data, tt.mimeType); !errors.Is(err, ErrInvalidImage) {
+				t.Errorf("StripMetadata error = %v, want %v", err, ErrInvalidImage)
+			}
+		})
+	}
+}
+
+func FuzzStripMetadata(f *testing.F) {
+	f.Add(testJPEG(f, 6), "image/jpeg")
+	f.Add(testPNG(f, 6), "image/png")
+	f.Add(testWebP(6), "image/webp")
+	f.Add([]byte("RIFF\x16\x00\x00\x00WEBPVP8X\xff\xff\xff\xff"), "image/webp")
+
+	f.Fuzz(func(t *testing.T, data []byte, mimeType string) {
+		if !CanStripMetadata(mimeType) {
+			return
+		}
+		// nothing read may be larger than the upload, plus the small
+		// orientation block put in place of EXIF
+		out, _, err := strip(t, data, mimeType)
+		if err != nil {
+			return
+		}
+		if len(out) > len(data)+64 {
+			t.Errorf("stripped %d bytes into %d", len(data), len(out))
+		}
+	})
+}
@@ -1,17 +1,38 @@
 package model
 
 import (
+	"database/sql"
+
 	"github.com/Anything-That-Works/GoPath/internal/cache"
+	"github.com/Anything-That-Works/GoPath/internal/clamav"
 	"github.com/Anything-That-Works/GoPath/internal/database"
+	"github.com/Anything-That-Works/GoPath/internal/mailer"
 	"github.com/Anything-That-Works/GoPath/internal/storage"
 	"github.com/Anything-That-Works/GoPath/internal/ws"
 )
 
 type ApiConfig struct {
 	DB           *database.Queries
+	Conn         *sql.DB // for transactions; queries go through DB
 	JWTSecretKey []byte
 	Storage      storage.FileStorage
+	URLSigner    *storage.URLSigner
 	Hub          *ws.Hub
 	TrustedProxy string
 	Cache        cache.Cache
+	MaxPins      int32 // per conversation
+
+	KeepOriginalImages bool // keep uploads with metadata intact, for the uploader only
+
+	// default byte quotas, 0 for unlimited; overrides live in the database
+	UserStorageQuota         int64
+	ConversationStorageQuota int64
+
+	Scanner *clamav.Scanner // nil when uploads aren't scanned
+
+	Mailer mailer.Mailer
+	// verification links are this URL with ?token=... appended
+	EmailVerificationURL string
+	// reset emails link here with ?token=...; without it they carry the bare token
+	PasswordResetURL string
 }
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"time"
+
+	"github.com/Anything-That-Works/GoPath/internal/database"
+	"github.com/google/uuid"
+)
+
+type MessagePreview struct {
+	ID        uuid.UUID `json:"id"`
+	SenderID  uuid.UUID `json:"sender_id"`
+	Snippet   *string   `json:"snippet"`
+	FileType  *string   `json:"file_type"` // mime type when the message carries a file
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ConversationListItem struct {
+	database.Conversation
+	LastMessage *MessagePreview `json:"last_message"`
+	UnreadCount int64           `json:"unread_count"`
+	OtherUser   *UserSummary    `json:"other_user,omitempty"` // direct chats only
+}
+
+func DatabaseConversationRowsToListItems(rows []database.GetUserConversationsRow) []ConversationListItem {
+	items := make([]ConversationListItem, 0, len(rows))
+	for _, row := range rows {
+		item := ConversationListItem{
+			Conversation: row.Conversation,
+			UnreadCount:  row.UnreadCount,
+		}
+
+		if row.LastMessageID.Valid {
+			preview := &MessagePreview{
+				ID:        row.LastMessageID.UUID,
+				SenderID:  row.LastMessageSenderID.UUID,
+				CreatedAt: row.LastMessageAt.Time,
+			}
+			if row.LastMessageSnippet.Valid {
+				preview.Snippet = &row.LastMessageSnippet.String
+			}
+			if row.LastMessageFileType.Valid {
+				preview.FileType = &row.LastMessageFileType.String
+			}
+			item.LastMessage = preview
+		}
+
+		if row.OtherUserID.Valid {
+			item.OtherUser = &UserSummary{
+				ID:            row.OtherUserID.UUID.String(),
+				Email:         row.OtherUserEmail.String,
+				EmailVerified: row.OtherUserEmailVerifiedAt.Valid,
+			}
+			if row.OtherUserName.Valid {
+				item.OtherUser.Name = &row.OtherUserName.String
+			}
+		}
+
+		items = append(items, item)
+	}
+	return items
+}
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"time"
+
+	"github.com/Anything-That-Works/GoPath/internal/database"
+	"github.com/google/uuid"
+)
+
+type ReactionSummary struct {
+	Emoji       string `json:"emoji"`
+	Count       int64  `json:"count"`
+	ReactedByMe bool   `json:"reacted_by_me"`
+}
+
+type ThreadSummary struct {
+	ReplyCount     int64       `json:"reply_count"`
+	LastReplyAt    *time.Time  `json:"last_reply_at"`
+	ParticipantIDs []uuid.UUID `json:"participant_ids"`
+}
+
+type Message struct {
+	database.Message
+	Reactions []ReactionSummary `json:"reactions"`
+	Thread    *ThreadSummary    `json:"thread,omitempty"` // set on roots with replies
+}
+
+func ConversationRowsToMessages(rows []database.GetMessagesByConversationRow, reactions []database.GetMessageReactionsRow) []Message {
+	dbMessages := make([]database.Message, 0, len(rows))
+	for _, row := range rows {
+		dbMessages = append(dbMessages, row.Message)
+	}
+
+	messages := DatabaseMessagesToMessages(dbMessages, reactions)
+	for i, row := range rows {
+		if row.ReplyCount == 0 {
+			continue
+		}
+		thread := &ThreadSummary{
+			ReplyCount:     row.ReplyCount,
+			ParticipantIDs: row.ThreadParticipantIds,
+		}
+		if row.LastReplyAt.Valid {
+			thread.LastReplyAt = &row.LastReplyAt.Time
+		}
+		messages[i].Thread = thread
+	}
+	return messages
+}
+
+func DatabaseMessagesToMessages(dbMessages []database.Message, reactions []database.GetMessageReactionsRow) []Message {
+	byMessage := make(map[uuid.UUID][]ReactionSummary)
+	for _, reaction := range reactions {
+		byMessage[reaction.MessageID] = append(byMessage[reaction.MessageID], ReactionSummary{
+			Emoji:       reaction.Emoji,
+			Count:       reaction.Count,
+			ReactedByMe: reaction.ReactedByMe,
+		})
+	}
+
+	messages := make([]Message, 0, len(dbMessages))
+	for _, dbMessage := range dbMessages {
+		messageReactions := byMessage[dbMessage.ID]
+		if messageReactions == nil {
+			messageReactions = []ReactionSummary{}
+		}
+		messages = append(messages, Message{
+			Message:   dbMessage,
+			Reactions: messageReactions,
+		})
+	}
+	return messages
+}
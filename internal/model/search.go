@@ -0,0 +1,38 @@
+package model
+
+import (
+	"html"
+	"strings"
+
+	"github.com/Anything-That-Works/GoPath/internal/database"
+)
+
+type SearchResult struct {
+	database.Message
+	ConversationName *string `json:"conversation_name"`
+	IsGroup          bool    `json:"is_group"`
+	Rank             float32 `json:"rank"`
+	Highlight        string  `json:"highlight"` // HTML-escaped, matches wrapped in <mark>
+}
+
+// highlightMarkup turns the \x02/\x03 match delimiters of an already escaped
+// ts_headline snippet into <mark> tags, so user content can't inject markup.
+var highlightMarkup = strings.NewReplacer("\x02", "<mark>", "\x03", "</mark>")
+
+func DatabaseSearchRowsToResults(rows []database.SearchMessagesRow) []SearchResult {
+	results := make([]SearchResult, 0, len(rows))
+	for _, row := range rows {
+		var name *string
+		if row.ConversationName.Valid {
+			name = &row.ConversationName.String
+		}
+		results = append(results, SearchResult{
+			Message:          row.Message,
+			ConversationName: name,
+			IsGroup:          row.IsGroup,
+			Rank:             row.Rank,
+			Highlight:        highlightMarkup.Replace(html.EscapeString(row.Highlight)),
+		})
+	}
+	return results
+}
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Anything-That-Works/GoPath/internal/database"
+	"github.com/google/uuid"
+)
+
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	Device     string    `json:"device"` // e.g. "Firefox on Windows"
+	UserAgent  *string   `json:"user_agent"`
+	IPAddress  *string   `json:"ip_address"` // as of the last refresh
+	StartedAt  time.Time `json:"started_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"` // the session making the request
+}
+
+func DatabaseSessionRowsToSessions(rows []database.ListActiveSessionsRow, currentSessionID uuid.UUID) []Session {
+	sessions := make([]Session, 0, len(rows))
+	for _, row := range rows {
+		session := Session{
+			ID:         row.SessionID,
+			Device:     describeDevice(row.UserAgent.String),
+			StartedAt:  row.StartedAt,
+			LastUsedAt: row.LastUsedAt,
+			ExpiresAt:  row.ExpiresAt,
+			Current:    row.SessionID == currentSessionID,
+		}
+		if row.UserAgent.Valid && row.UserAgent.String != "" {
+			session.UserAgent = &row.UserAgent.String
+		}
+		if row.IpAddress.Valid {
+			ip := row.IpAddress.IPNet.IP.String()
+			session.IPAddress = &ip
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+// describeDevice names the client and platform in a User-Agent well enough
+// for a user to recognise their devices. Order matters: Edge and Opera
+// claim to be Chrome, and Chrome claims to be Safari.
+func describeDevice(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	client := ""
+	for _, candidate := range []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"CriOS/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"okhttp/", "Android app"},
+		{"Dart/", "Flutter app"},
+		{"CFNetwork/", "iOS app"},
+		{"curl/", "curl"},
+		{"PostmanRuntime/", "Postman"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			client = candidate.name
+			break
+		}
+	}
+
+	platform := ""
+	for _, candidate := range []struct{ token, name string }{
+		{"iPhone", "iPhone"},
+		{"iPad", "iPad"},
+		{"Android", "Android"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Macintosh", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			platform = candidate.name
+			break
+		}
+	}
+
+	switch {
+	case client != "" && platform != "":
+		return client + " on " + platform
+	case client != "":
+		return client
+	case platform != "":
+		return platform
+	default:
+		return "Unknown device"
+	}
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// TwoFactorChallenge is what login returns instead of a TokenResponse when
+// the user has 2FA on. The token is traded for one with a code.
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	ChallengeToken    string    `json:"challenge_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"` // base32, for typing in by hand
+	URI    string `json:"otpauth_uri"`
+}
+
+type TwoFactorStatus struct {
+	Enabled                bool  `json:"enabled"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
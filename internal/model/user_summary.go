@@ -5,9 +5,10 @@ import (
 )
 
 type UserSummary struct {
-	ID    string  `json:"id"`
-	Name  *string `json:"name"`
-	Email string  `json:"email"`
+	ID            string  `json:"id"`
+	Name          *string `json:"name"`
+	Email         string  `json:"email"`
+	EmailVerified bool    `json:"email_verified"`
 }
 
 func DatabaseUserToUserSummary(dbUser database.User) UserSummary {
@@ -16,8 +17,9 @@ func DatabaseUserToUserSummary(dbUser database.User) UserSummary {
 		name = &dbUser.Name.String
 	}
 	return UserSummary{
-		ID:    dbUser.ID.String(),
-		Name:  name,
-		Email: dbUser.Email,
+		ID:            dbUser.ID.String(),
+		Name:          name,
+		Email:         dbUser.Email,
+		EmailVerified: dbUser.EmailVerifiedAt.Valid,
 	}
 }
@@ -1,31 +1,31 @@
 package storage
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
+	"net/url"
 	"os"
 	"path/filepath"
 	"time"
-
-	"github.com/google/uuid"
 )
 
 type LocalStorage struct {
 	BasePath string
 	BaseURL  string
+	Signer   *URLSigner
 }
 
-func NewLocalStorage(basePath string, baseURL string) *LocalStorage {
+func NewLocalStorage(basePath string, baseURL string, signer *URLSigner) *LocalStorage {
 	if err := os.MkdirAll(basePath, os.ModePerm); err != nil {
 		fmt.Println("Error creating directory:", err)
 	}
-	return &LocalStorage{BasePath: basePath, BaseURL: baseURL}
+	return &LocalStorage{BasePath: basePath, BaseURL: baseURL, Signer: signer}
 }
 
-func (s *LocalStorage) Save(file multipart.File, filename string, mimeType string) (string, error) {
-	ext := filepath.Ext(filename)
-	newFilename := fmt.Sprintf("%d_%s%s", time.Now().UnixNano(), uuid.New().String(), ext)
+func (s *LocalStorage) Save(r io.Reader, size int64, filename string, mimeType string) (string, error) {
+	newFilename := objectName(filename)
 	destPath := filepath.Join(s.BasePath, newFilename)
 
 	dest, err := os.Create(destPath)
@@ -38,7 +38,14 @@ func (s *LocalStorage) Save(file multipart.File, filename string, mimeType strin
 		}
 	}()
 
-	if _, err = io.Copy(dest, file); err != nil {
+	written, err := io.Copy(dest, io.LimitReader(r, size))
+	if err == nil && written != size {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		if removeErr := os.Remove(destPath); removeErr != nil {
+			fmt.Println("Error removing partial file:", removeErr)
+		}
 		return "", err
 	}
 
@@ -49,6 +56,43 @@ func (s *LocalStorage) Delete(path string) error {
 	return os.Remove(filepath.Join(s.BasePath, path))
 }
 
-func (s *LocalStorage) URL(path string) string {
-	return fmt.Sprintf("%s/v1/files/%s", s.BaseURL, path)
+func (s *LocalStorage) Open(path string) (io.ReadSeekCloser, error) {
+	// paths are generated by Save, but never let one escape BasePath
+	if filepath.Base(path) != path {
+		return nil, os.ErrNotExist
+	}
+	return os.Open(filepath.Join(s.BasePath, path))
+}
+
+func (s *LocalStorage) List(ctx context.Context, fn func(path string, modTime time.Time) error) error {
+	entries, err := os.ReadDir(s.BasePath)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(entry.Name(), info.ModTime()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// URL returns a signed link that expires after the signer's TTL.
+// The server looks the file up again when serving it, so only its ID goes
+// into the link.
+func (s *LocalStorage) URL(path string, file ServedFile) string {
+	return fmt.Sprintf("%s/v1/files/%s?%s", s.BaseURL, url.PathEscape(path), s.Signer.Sign(path, file.ID).Encode())
 }
@@ -0,0 +1,320 @@
+package storage
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type S3Config struct {
+	Endpoint       string // e.g. https://s3.eu-west-1.amazonaws.com or http://minio:9000
+	PublicEndpoint string // endpoint browsers reach for presigned URLs; defaults to Endpoint
+	Region         string
+	Bucket         string
+	AccessKey      string
+	SecretKey      string
+	UsePathStyle   bool // required by MinIO and most self-hosted S3 servers
+	URLTTL         time.Duration
+}
+
+// S3Storage keeps files in an S3-compatible bucket. Uploads are streamed
+// with a known Content-Length, so nothing is buffered in memory or on disk.
+type S3Storage struct {
+	endpoint       *url.URL
+	publicEndpoint *url.URL
+	bucket         string
+	pathStyle      bool
+	urlTTL         time.Duration
+	signer         sigV4Signer
+	client         *http.Client
+}
+
+func NewS3Storage(cfg S3Config) (*S3Storage, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("s3 bucket is required")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("s3 credentials are required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", cfg.Endpoint)
+	}
+	publicEndpoint := endpoint
+	if cfg.PublicEndpoint != "" {
+		publicEndpoint, err = url.Parse(cfg.PublicEndpoint)
+		if err != nil || publicEndpoint.Scheme == "" || publicEndpoint.Host == "" {
+			return nil, fmt.Errorf("invalid s3 public endpoint %q", cfg.PublicEndpoint)
+		}
+	}
+
+	return &S3Storage{
+		endpoint:       endpoint,
+		publicEndpoint: publicEndpoint,
+		bucket:         cfg.Bucket,
+		pathStyle:      cfg.UsePathStyle,
+		urlTTL:         cfg.URLTTL,
+		signer: sigV4Signer{
+			accessKey: cfg.AccessKey,
+			secretKey: cfg.SecretKey,
+			region:    cfg.Region,
+		},
+		client: &http.Client{},
+	}, nil
+}
+
+func (s *S3Storage) Save(r io.Reader, size int64, filename string, mimeType string) (string, error) {
+	key := objectName(filename)
+	if err := s.Put(context.Background(), key, r, size, mimeType); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// Put uploads size bytes from body under key.
+func (s *S3Storage) Put(ctx context.Context, key string, body io.Reader, size int64, mimeType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(s.endpoint, key).String(), nil)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if size > 0 {
+		req.Body = io.NopCloser(body)
+	} else {
+		req.Body = http.NoBody
+	}
+	if mimeType != "" {
+		req.Header.Set("Content-Type", mimeType)
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Stat returns the size of the object at key, or os.ErrNotExist.
+func (s *S3Storage) Stat(ctx context.Context, key string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, s.objectURL(s.endpoint, key).String(), nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.ContentLength, nil
+}
+
+func (s *S3Storage) Open(path string) (io.ReadSeekCloser, error) {
+	size, err := s.Stat(context.Background(), path)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Object{storage: s, key: path, size: size}, nil
+}
+
+func (s *S3Storage) Delete(path string) error {
+	req, err := http.NewRequest(http.MethodDelete, s.objectURL(s.endpoint, path).String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// List pages through the bucket with ListObjectsV2.
+func (s *S3Storage) List(ctx context.Context, fn func(path string, modTime time.Time) error) error {
+	u := s.objectURL(s.endpoint, "")
+	if s.pathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/")
+	}
+
+	var token string
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		u.RawQuery = canonicalQuery(query)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return err
+		}
+		resp, err := s.do(req)
+		if err != nil {
+			return err
+		}
+		var page struct {
+			Contents []struct {
+				Key          string    `xml:"Key"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, object := range page.Contents {
+			if err := fn(object.Key, object.LastModified); err != nil {
+				return err
+			}
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// URL returns a presigned GET that expires after URLTTL, so clients fetch
+// straight from the bucket. The signed response overrides pin the file's
+// own type and disposition, whatever the object was stored with.
+func (s *S3Storage) URL(path string, file ServedFile) string {
+	u := s.objectURL(s.publicEndpoint, path)
+	query := url.Values{}
+	query.Set("response-content-type", file.MimeType)
+	query.Set("response-content-disposition", ContentDisposition(file.MimeType, file.Name))
+	u.RawQuery = query.Encode()
+	return s.signer.presign(http.MethodGet, u, s.urlTTL, time.Now())
+}
+
+func (s *S3Storage) objectURL(endpoint *url.URL, key string) *url.URL {
+	u := *endpoint
+	basePath := strings.TrimSuffix(u.Path, "/")
+	if s.pathStyle {
+		u.Path = basePath + "/" + s.bucket + "/" + key
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = basePath + "/" + key
+	}
+	u.RawPath = ""
+	u.RawQuery = ""
+	return &u
+}
+
+// do signs and sends req, turning non-2xx responses into errors. A 404 is
+// reported as os.ErrNotExist.
+func (s *S3Storage) do(req *http.Request) (*http.Response, error) {
+	s.signer.sign(req, time.Now())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, os.ErrNotExist
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, fmt.Errorf("s3 %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(detail)))
+}
+
+// s3Object reads an object lazily with ranged GETs so http.ServeContent
+// can seek without downloading the whole file.
+type s3Object struct {
+	storage *S3Storage
+	key     string
+	size    int64
+	offset  int64
+	body    io.ReadCloser
+}
+
+func (o *s3Object) Read(p []byte) (int, error) {
+	if o.offset >= o.size {
+		return 0, io.EOF
+	}
+	if o.body == nil {
+		req, err := http.NewRequest(http.MethodGet, o.storage.objectURL(o.storage.endpoint, o.key).String(), nil)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(o.offset, 10)+"-")
+		resp, err := o.storage.do(req)
+		if err != nil {
+			return 0, err
+		}
+		o.body = resp.Body
+	}
+
+	n, err := o.body.Read(p)
+	o.offset += int64(n)
+	return n, err
+}
+
+func (o *s3Object) Seek(offset int64, whence int) (int64, error) {
+	var next int64
+	switch whence {
+	case io.SeekStart:
+		next = offset
+	case io.SeekCurrent:
+		next = o.offset + offset
+	case io.SeekEnd:
+		next = o.size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if next < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	if next != o.offset && o.body != nil {
+		o.body.Close()
+		o.body = nil
+	}
+	o.offset = next
+	return next, nil
+}
+
+func (o *s3Object) Close() error {
+	if o.body == nil {
+		return nil
+	}
+	return o.body.Close()
+}
+
+// S3ConfigFromEnv reads the S3_* variables shared by the server and the
+// migrate-files command.
+func S3ConfigFromEnv() (S3Config, error) {
+	cfg := S3Config{
+		Endpoint:       os.Getenv("S3_ENDPOINT"),
+		PublicEndpoint: os.Getenv("S3_PUBLIC_ENDPOINT"),
+		Region:         os.Getenv("S3_REGION"),
+		Bucket:         os.Getenv("S3_BUCKET"),
+		AccessKey:      os.Getenv("S3_ACCESS_KEY_ID"),
+		SecretKey:      os.Getenv("S3_SECRET_ACCESS_KEY"),
+	}
+	if val := os.Getenv("S3_USE_PATH_STYLE"); val != "" {
+		pathStyle, err := strconv.ParseBool(val)
+		if err != nil {
+			return cfg, fmt.Errorf("S3_USE_PATH_STYLE must be a boolean, got %q", val)
+		}
+		cfg.UsePathStyle = pathStyle
+	}
+	return cfg, nil
+}
@@ -1,9 +1,110 @@
 package storage
 
-import "mime/multipart"
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type FileStorage interface {
-	Save(file multipart.File, filename string, mimeType string) (path string, err error)
+	// Save streams exactly size bytes from r into a new object named after
+	// filename. A short read fails with io.ErrUnexpectedEOF.
+	Save(r io.Reader, size int64, filename string, mimeType string) (path string, err error)
+	Open(path string) (io.ReadSeekCloser, error)
 	Delete(path string) error
-	URL(path string) string
+	// URL links to the object at path as served for file, as deduplicated
+	// files share their objects.
+	URL(path string, file ServedFile) string
+}
+
+// ServedFile is what a link serves an object as: the name and type come
+// from the file it was issued for, never from whoever stored the object.
+type ServedFile struct {
+	ID       uuid.UUID
+	Name     string
+	MimeType string
+}
+
+// inlineMimeTypes can be rendered by browsers without running anything;
+// every other allowed type is served as an attachment.
+var inlineMimeTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"video/mp4":       true,
+	"video/quicktime": true,
+	"audio/mpeg":      true,
+	"audio/ogg":       true,
+}
+
+// ContentDisposition lets only inline-safe types open in the browser.
+func ContentDisposition(mimeType string, filename string) string {
+	disposition := "attachment"
+	if inlineMimeTypes[mimeType] {
+		disposition = "inline"
+	}
+	return mime.FormatMediaType(disposition, map[string]string{"filename": filename})
+}
+
+// Lister is implemented by backends that can enumerate their objects, so
+// objects no row points at can be found and removed.
+type Lister interface {
+	List(ctx context.Context, fn func(path string, modTime time.Time) error) error
+}
+
+// objectName returns a unique storage path that keeps the original extension.
+func objectName(filename string) string {
+	return fmt.Sprintf("%d_%s%s", time.Now().UnixNano(), uuid.New().String(), filepath.Ext(filename))
+}
+
+// Concat reads the objects at paths back to back, opening each one only
+// when the previous is exhausted.
+func Concat(fs FileStorage, paths []string) io.ReadCloser {
+	return &concatReader{storage: fs, paths: paths}
+}
+
+type concatReader struct {
+	storage FileStorage
+	paths   []string
+	current io.ReadCloser
+}
+
+func (c *concatReader) Read(p []byte) (int, error) {
+	for {
+		if c.current == nil {
+			if len(c.paths) == 0 {
+				return 0, io.EOF
+			}
+			next, err := c.storage.Open(c.paths[0])
+			if err != nil {
+				return 0, err
+			}
+			c.current = next
+			c.paths = c.paths[1:]
+		}
+
+		n, err := c.current.Read(p)
+		if err == io.EOF {
+			err = c.current.Close()
+			c.current = nil
+			if n > 0 || err != nil {
+				return n, err
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (c *concatReader) Close() error {
+	if c.current == nil {
+		return nil
+	}
+	return c.current.Close()
 }
@@ -0,0 +1,295 @@
+// Package sweeper garbage-collects stored files nothing refers to any more:
+// uploads never attached to a message, files of deleted messages, blobs
+// whose last file is gone, and objects in storage that no row points at.
+package sweeper
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Anything-That-Works/GoPath/internal/database"
+	"github.com/Anything-That-Works/GoPath/internal/storage"
+	"github.com/google/uuid"
+)
+
+const batchSize = 100
+
+type Sweeper struct {
+	DB      *database.Queries
+	Storage storage.FileStorage
+	// GracePeriod keeps anything younger than it, so files still on their
+	// way into a message and objects still being saved are left alone.
+	GracePeriod time.Duration
+	// DryRun reports what would be removed without changing anything.
+	DryRun bool
+}
+
+// Report summarizes one sweep. Bytes are file sizes as recorded in the
+// database, so deduplicated content is counted once per file.
+type Report struct {
+	Files        int
+	FileBytes    int64
+	Blobs        int
+	BlobBytes    int64
+	RefCounts    int // blobs whose reference count was corrected
+	StrayObjects int // objects in storage without a row
+	Failed       int
+	DryRun       bool
+}
+
+func (report Report) String() string {
+	return fmt.Sprintf("removed %d files (%d bytes), %d blobs (%d bytes), %d stray objects; fixed %d reference counts; %d failed (dry run: %t)",
+		report.Files, report.FileBytes, report.Blobs, report.BlobBytes, report.StrayObjects, report.RefCounts, report.Failed, report.DryRun)
+}
+
+// Run sweeps every interval until ctx is done, logging each report.
+func (s *Sweeper) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := s.Sweep(ctx)
+			if err != nil {
+				log.Printf("file sweep stopped early: %v", err)
+			}
+			log.Printf("file sweep: %s", report)
+		}
+	}
+}
+
+// Sweep makes one pass. Failures on single items are counted and logged;
+// the error is only for failures that stop the pass.
+func (s *Sweeper) Sweep(ctx context.Context) (Report, error) {
+	report := Report{DryRun: s.DryRun}
+	cutoff := time.Now().Add(-s.GracePeriod)
+
+	// in a dry run nothing is released, so track what would be
+	released := map[string]int32{}
+
+	if err := s.sweepFiles(ctx, cutoff, &report, released); err != nil {
+		return report, err
+	}
+	if err := s.fixRefCounts(ctx, cutoff, &report); err != nil {
+		return report, err
+	}
+	if err := s.sweepBlobs(ctx, cutoff, &report, released); err != nil {
+		return report, err
+	}
+	if err := s.sweepObjects(ctx, cutoff, &report); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+func (s *Sweeper) sweepFiles(ctx context.Context, cutoff time.Time, report *Report, released map[string]int32) error {
+	after := uuid.Nil
+	for {
+		files, err := s.DB.ListOrphanedFiles(ctx, database.ListOrphanedFilesParams{
+			Cutoff:  cutoff,
+			AfterID: after,
+			MaxRows: batchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("list orphaned files: %w", err)
+		}
+		if len(files) == 0 {
+			return nil
+		}
+		after = files[len(files)-1].ID
+
+		for _, file := range files {
+			if err := s.removeFile(ctx, cutoff, file, report, released); err != nil {
+				report.Failed++
+				log.Printf("failed to remove file %s: %v", file.ID, err)
+			}
+		}
+	}
+}
+
+// removeFile deletes a file row and whatever in storage only it used.
+func (s *Sweeper) removeFile(ctx context.Context, cutoff time.Time, file database.File, report *Report, released map[string]int32) error {
+	thumbnails, err := s.DB.GetFileThumbnails(ctx, file.ID)
+	if err != nil {
+		return err
+	}
+
+	if s.DryRun {
+		report.Files++
+		report.FileBytes += file.Size
+		if file.Sha256.Valid {
+			released[file.Sha256.String]++
+		}
+		return nil
+	}
+
+	if _, err := s.DB.DeleteOrphanedFile(ctx, database.DeleteOrphanedFileParams{ID: file.ID, Cutoff: cutoff}); err != nil {
+		if err == sql.ErrNoRows {
+			return nil // attached since it was listed
+		}
+		return err
+	}
+	report.Files++
+	report.FileBytes += file.Size
+
+	// thumbnails of deduplicated content are shared with other files
+	paths := []string{file.OriginalPath.String}
+	for _, thumbnail := range thumbnails {
+		paths = append(paths, thumbnail.Path)
+	}
+	if file.Sha256.Valid {
+		blob, err := s.DB.ReleaseBlob(ctx, file.Sha256.String)
+		if err != nil {
+			return err
+		}
+		if blob.RefCount <= 0 {
+			s.removeBlob(ctx, blob.Sha256, report)
+		}
+	} else {
+		paths = append(paths, file.Path)
+	}
+
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if err := s.deleteUnreferenced(ctx, path); err != nil {
+			report.Failed++
+			log.Printf("failed to delete %s of file %s: %v", path, file.ID, err)
+		}
+	}
+	return nil
+}
+
+// fixRefCounts brings reference counts back in line with the files that
+// hold each blob. Blobs referenced again within the grace period may have an
+// upload between storing its content and creating its file, so they are
+// skipped.
+func (s *Sweeper) fixRefCounts(ctx context.Context, cutoff time.Time, report *Report) error {
+	drifted, err := s.DB.ListBlobRefCountDrift(ctx, cutoff)
+	if err != nil {
+		return fmt.Errorf("list reference count drift: %w", err)
+	}
+	for _, blob := range drifted {
+		report.RefCounts++
+		if s.DryRun {
+			continue
+		}
+		if err := s.DB.SetBlobRefCount(ctx, database.SetBlobRefCountParams{
+			FileCount: blob.FileCount,
+			Sha256:    blob.Sha256,
+			RefCount:  blob.RefCount,
+		}); err != nil {
+			report.Failed++
+			log.Printf("failed to fix reference count of blob %s: %v", blob.Sha256, err)
+		}
+	}
+	return nil
+}
+
+// sweepBlobs removes blobs no file refers to, e.g. left by uploads whose
+// file row couldn't be created.
+func (s *Sweeper) sweepBlobs(ctx context.Context, cutoff time.Time, report *Report, released map[string]int32) error {
+	blobs, err := s.DB.ListUnreferencedBlobs(ctx, cutoff)
+	if err != nil {
+		return fmt.Errorf("list unreferenced blobs: %w", err)
+	}
+	for _, blob := range blobs {
+		if s.DryRun {
+			report.Blobs++
+			report.BlobBytes += blob.Size
+			delete(released, blob.Sha256)
+			continue
+		}
+		s.removeBlob(ctx, blob.Sha256, report)
+	}
+
+	// blobs whose every file would have gone in this sweep
+	for sha256, count := range released {
+		blob, err := s.DB.GetBlob(ctx, sha256)
+		if err != nil {
+			report.Failed++
+			log.Printf("failed to look up blob %s: %v", sha256, err)
+			continue
+		}
+		if blob.RefCount-count <= 0 {
+			report.Blobs++
+			report.BlobBytes += blob.Size
+		}
+	}
+	return nil
+}
+
+// removeBlob deletes a blob row and its content, provided nothing claimed
+// it in the meantime.
+func (s *Sweeper) removeBlob(ctx context.Context, sha256 string, report *Report) {
+	blob, err := s.DB.DeleteUnreferencedBlob(ctx, sha256)
+	if err == sql.ErrNoRows {
+		return
+	}
+	if err != nil {
+		report.Failed++
+		log.Printf("failed to remove blob %s: %v", sha256, err)
+		return
+	}
+	report.Blobs++
+	report.BlobBytes += blob.Size
+
+	if err := s.deleteUnreferenced(ctx, blob.Path); err != nil {
+		report.Failed++
+		log.Printf("failed to delete content of blob %s: %v", sha256, err)
+	}
+}
+
+// sweepObjects removes objects in storage that no row points at, for
+// backends that can list their objects.
+func (s *Sweeper) sweepObjects(ctx context.Context, cutoff time.Time, report *Report) error {
+	lister, ok := s.Storage.(storage.Lister)
+	if !ok {
+		return nil
+	}
+
+	err := lister.List(ctx, func(path string, modTime time.Time) error {
+		if !modTime.Before(cutoff) {
+			return nil
+		}
+		referenced, err := s.DB.IsStoragePathReferenced(ctx, path)
+		if err != nil {
+			return err
+		}
+		if referenced {
+			return nil
+		}
+
+		report.StrayObjects++
+		if s.DryRun {
+			log.Printf("would delete stray object %s", path)
+			return nil
+		}
+		if err := s.Storage.Delete(path); err != nil {
+			report.Failed++
+			log.Printf("failed to delete stray object %s: %v", path, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("list stored objects: %w", err)
+	}
+	return nil
+}
+
+// deleteUnreferenced deletes the object at path unless some row still
+// points at it.
+func (s *Sweeper) deleteUnreferenced(ctx context.Context, path string) error {
+	referenced, err := s.DB.IsStoragePathReferenced(ctx, path)
+	if err != nil || referenced {
+		return err
+	}
+	return s.Storage.Delete(path)
+}
@@ -0,0 +1,102 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of RFC 6238 appendix B, "12345678901234567890",
+// base32-encoded.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// The appendix lists 8-digit codes; 6-digit codes are their last six digits.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{unix: 59, code: "287082"},
+	{unix: 1111111109, code: "081804"},
+	{unix: 1111111111, code: "050471"},
+	{unix: 1234567890, code: "005924"},
+	{unix: 2000000000, code: "279037"},
+	{unix: 20000000000, code: "353130"},
+}
+
+func TestGenerate(t *testing.T) {
+	key, err := encoding.DecodeString(rfcSecret)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+	for _, tt := range rfcVectors {
+		if got := generate(key, tt.unix/period); got != tt.code {
+			t.Errorf("generate at %d = %s, want %s", tt.unix, got, tt.code)
+		}
+	}
+}
+
+func TestValidateVectors(t *testing.T) {
+	for _, tt := range rfcVectors {
+		step, ok := Validate(rfcSecret, tt.code, time.Unix(tt.unix, 0))
+		if !ok {
+			t.Errorf("Validate rejected %s at %d", tt.code, tt.unix)
+			continue
+		}
+		if want := tt.unix / period; step != want {
+			t.Errorf("Validate at %d matched step %d, want %d", tt.unix, step, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	// 287082 is the code for step 1, 30s to 59s; 050471 for step 37037037,
+	// 1111111110s to 1111111139s
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		unix     int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current step", secret: rfcSecret, code: "287082", unix: 59, wantStep: 1, wantOK: true},
+		{name: "one step late", secret: rfcSecret, code: "287082", unix: 89, wantStep: 1, wantOK: true},
+		{name: "one step early", secret: rfcSecret, code: "050471", unix: 1111111080, wantStep: 37037037, wantOK: true},
+		{name: "two steps late", secret: rfcSecret, code: "287082", unix: 119},
+		{name: "two steps early", secret: rfcSecret, code: "050471", unix: 1111111079},
+		{name: "spaces", secret: rfcSecret, code: "287 082", unix: 59, wantStep: 1, wantOK: true},
+		{name: "lowercase secret", secret: "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", code: "287082", unix: 59, wantStep: 1, wantOK: true},
+		{name: "wrong code", secret: rfcSecret, code: "287083", unix: 59},
+		{name: "too short", secret: rfcSecret, code: "28708", unix: 59},
+		{name: "too long", secret: rfcSecret, code: "94287082", unix: 59},
+		{name: "empty", secret: rfcSecret, code: "", unix: 59},
+		{name: "bad secret", secret: "not base32!", code: "287082", unix: 59},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(tt.secret, tt.code, time.Unix(tt.unix, 0))
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("Validate = (%d, %v), want (%d, %v)", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret %q isn't base32: %v", secret, err)
+	}
+	if len(key) != 20 {
+		t.Errorf("secret is %d bytes, want 20", len(key))
+	}
+
+	now := time.Now()
+	if _, ok := Validate(secret, generate(key, now.Unix()/period), now); !ok {
+		t.Error("Validate rejected the current code of a generated secret")
+	}
+}
@@ -3,6 +3,7 @@ package ws
 import (
 	"encoding/json"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,20 +15,145 @@ const (
 	pongWait       = 60 * time.Second
 	pingPeriod     = (pongWait * 9) / 10
 	maxMessageSize = 10 * 1024 // 10KB
+	sendBufferSize = 256
 )
 
 type Client struct {
-	ID             uuid.UUID
-	UserID         uuid.UUID
-	ConversationID uuid.UUID
-	Hub            *Hub
-	Conn           *websocket.Conn
-	Send           chan []byte
+	ID     uuid.UUID
+	UserID uuid.UUID
+	// DefaultConversationID is used for messages without a conversation_id,
+	// so clients that still open one socket per conversation keep working.
+	DefaultConversationID uuid.UUID
+	Hub                   *Hub
+	Conn                  *websocket.Conn
+	Send                  chan []byte
+
+	mu            sync.Mutex
+	conversations map[uuid.UUID]bool
+	replaying     map[uuid.UUID][][]byte
+	closed        bool
+}
+
+func NewClient(hub *Hub, conn *websocket.Conn, userID uuid.UUID) *Client {
+	return &Client{
+		ID:            uuid.New(),
+		UserID:        userID,
+		Hub:           hub,
+		Conn:          conn,
+		Send:          make(chan []byte, sendBufferSize),
+		conversations: make(map[uuid.UUID]bool),
+		replaying:     make(map[uuid.UUID][][]byte),
+	}
+}
+
+func (c *Client) IsSubscribed(conversationID uuid.UUID) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conversations[conversationID]
+}
+
+func (c *Client) Conversations() []uuid.UUID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	conversationIDs := make([]uuid.UUID, 0, len(c.conversations))
+	for conversationID := range c.conversations {
+		conversationIDs = append(conversationIDs, conversationID)
+	}
+	return conversationIDs
+}
+
+func (c *Client) setSubscribed(conversationID uuid.UUID, subscribed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if subscribed {
+		c.conversations[conversationID] = true
+	} else {
+		delete(c.conversations, conversationID)
+	}
+}
+
+// trySend queues data without blocking. A client whose buffer is full is
+// too slow to keep up, so its Send channel is closed and false is returned.
+func (c *Client) trySend(data []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sendLocked(data)
+}
+
+func (c *Client) sendLocked(data []byte) bool {
+	if c.closed {
+		return false
+	}
+	select {
+	case c.Send <- data:
+		return true
+	default:
+		c.closed = true
+		close(c.Send)
+		return false
+	}
+}
+
+// deliver queues a live event, holding it back while the conversation's
+// history is being replayed.
+func (c *Client) deliver(conversationID uuid.UUID, data []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if pending, ok := c.replaying[conversationID]; ok {
+		c.replaying[conversationID] = append(pending, data)
+		return true
+	}
+	return c.sendLocked(data)
+}
+
+func (c *Client) beginReplay(conversationID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.replaying[conversationID] = [][]byte{}
+}
+
+// endReplay flushes the live events held back during replay, skipping new
+// messages the replay already covered.
+func (c *Client) endReplay(conversationID uuid.UUID, replayedSeq int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pending := c.replaying[conversationID]
+	delete(c.replaying, conversationID)
+
+	for _, data := range pending {
+		var event struct {
+			Type MessageType `json:"type"`
+			Seq  int64       `json:"seq"`
+		}
+		if err := json.Unmarshal(data, &event); err == nil &&
+			(event.Type == TypeText || event.Type == TypeFile) && event.Seq != 0 && event.Seq <= replayedSeq {
+			continue
+		}
+		c.sendLocked(data)
+	}
+}
+
+func (c *Client) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		close(c.Send)
+	}
 }
 
 func (c *Client) ReadPump(handleMessage func(client *Client, data []byte)) {
 	defer func() {
-		c.Hub.Unregister <- c
+		// the hub no longer reads Unregister once it has stopped
+		select {
+		case c.Hub.Unregister <- c:
+		case <-c.Hub.stop:
+		}
 		_ = c.Conn.Close()
 	}()
 
@@ -82,9 +208,5 @@ func (c *Client) SendMessage(msg OutgoingMessage) {
 	if err != nil {
 		return
 	}
-	select {
-	case c.Send <- data:
-	default:
-		close(c.Send)
-	}
+	c.trySend(data)
 }
@@ -4,33 +4,54 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"time"
+	"unicode/utf8"
 
+	"github.com/Anything-That-Works/GoPath/internal/cache"
 	"github.com/Anything-That-Works/GoPath/internal/database"
 	"github.com/Anything-That-Works/GoPath/internal/ratelimit"
+	"github.com/Anything-That-Works/GoPath/internal/storage"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"golang.org/x/time/rate"
 )
 
+// maxReplayMessages caps a single resume so the replay fits in the client's
+// send buffer with room for live events; clients page through the rest over HTTP
+const maxReplayMessages = sendBufferSize / 2
+
+// maxEmojiLength allows multi-codepoint emoji such as flags and ZWJ sequences
+const maxEmojiLength = 16
+
 type MessageHandler struct {
 	Hub     *Hub
 	DB      *database.Queries
+	Conn    *sql.DB // for transactions; queries go through DB
 	Storage StorageProvider
+	Cache   cache.Cache
 	limiter *ratelimit.Limiter
+
+	// default byte quota of a conversation without an override, 0 for unlimited
+	conversationQuota int64
 }
 
 type StorageProvider interface {
-	URL(path string) string
+	URL(path string, file storage.ServedFile) string
 }
 
-func NewMessageHandler(hub *Hub, db *database.Queries, storage StorageProvider) *MessageHandler {
+func NewMessageHandler(hub *Hub, db *database.Queries, conn *sql.DB, storage StorageProvider, cache cache.Cache, conversationQuota int64) *MessageHandler {
 	return &MessageHandler{
 		Hub:     hub,
 		DB:      db,
+		Conn:    conn,
 		Storage: storage,
+		Cache:   cache,
 		// 5 messages per second, burst of 10
 		limiter: ratelimit.NewLimiter(rate.Limit(5), 10),
+
+		conversationQuota: conversationQuota,
 	}
 }
 
@@ -52,6 +73,26 @@ func (h *MessageHandler) Handle(client *Client, data []byte) {
 		return
 	}
 
+	switch msg.Type {
+	case TypeSubscribe:
+		h.handleSubscribe(client, msg)
+		return
+	case TypeUnsubscribe:
+		h.handleUnsubscribe(client, msg)
+		return
+	}
+
+	if msg.ConversationID == uuid.Nil {
+		msg.ConversationID = client.DefaultConversationID
+	}
+	if !client.IsSubscribed(msg.ConversationID) {
+		client.SendMessage(OutgoingMessage{
+			Type:  TypeError,
+			Error: "Not subscribed to this conversation",
+		})
+		return
+	}
+
 	switch msg.Type {
 	case TypeText, TypeFile:
 		h.handleSendMessage(client, msg)
@@ -63,6 +104,10 @@ func (h *MessageHandler) Handle(client *Client, data []byte) {
 		h.handleReadMessage(client, msg)
 	case TypeTyping, TypeStopTyping:
 		h.handleTyping(client, msg)
+	case TypeReact, TypeUnreact:
+		h.handleReaction(client, msg)
+	case TypeFollow, TypeUnfollow:
+		h.handleFollowThread(client, msg)
 	default:
 		client.SendMessage(OutgoingMessage{
 			Type:  TypeError,
@@ -71,6 +116,175 @@ func (h *MessageHandler) Handle(client *Client, data []byte) {
 	}
 }
 
+func (h *MessageHandler) handleSubscribe(client *Client, msg IncomingMessage) {
+	if msg.ConversationID == uuid.Nil {
+		client.SendMessage(OutgoingMessage{Type: TypeError, Error: "conversation_id required"})
+		return
+	}
+
+	_, err := h.DB.GetConversationMember(context.Background(), database.GetConversationMemberParams{
+		ConversationID: msg.ConversationID,
+		UserID:         client.UserID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			client.SendMessage(OutgoingMessage{
+				Type:           TypeError,
+				ConversationID: &msg.ConversationID,
+				Error:          "Not a member of this conversation",
+			})
+			return
+		}
+		client.SendMessage(OutgoingMessage{
+			Type:           TypeError,
+			ConversationID: &msg.ConversationID,
+			Error:          "Failed to verify membership",
+		})
+		return
+	}
+
+	h.Subscribe(client, msg.ConversationID, msg.LastSeq)
+}
+
+// Subscribe joins client to the conversation. With lastSeq set, messages,
+// edits and deletes the client missed are replayed first and live events are
+// held back until the replay is done. Callers must have verified membership.
+func (h *MessageHandler) Subscribe(client *Client, conversationID uuid.UUID, lastSeq *int64) {
+	if lastSeq == nil {
+		h.Hub.Subscribe(client, conversationID)
+		client.SendMessage(OutgoingMessage{Type: TypeSubscribe, ConversationID: &conversationID})
+		return
+	}
+
+	client.beginReplay(conversationID)
+	h.Hub.Subscribe(client, conversationID)
+
+	messages, err := h.DB.GetMessagesForReplay(context.Background(), database.GetMessagesForReplayParams{
+		ConversationID: conversationID,
+		Seq:            *lastSeq,
+		Limit:          maxReplayMessages + 1,
+	})
+	if err != nil {
+		log.Printf("failed to load messages for replay: %v", err)
+		client.SendMessage(OutgoingMessage{
+			Type:           TypeError,
+			ConversationID: &conversationID,
+			Error:          "Failed to replay messages",
+		})
+		client.endReplay(conversationID, 0)
+		return
+	}
+
+	hasMore := len(messages) > maxReplayMessages
+	if hasMore {
+		messages = messages[:maxReplayMessages]
+	}
+
+	// thread replies are only replayed for threads the client follows
+	followed := make(map[uuid.UUID]bool)
+	threadIDs, err := h.DB.GetFollowedThreads(context.Background(), database.GetFollowedThreadsParams{
+		UserID:         client.UserID,
+		ConversationID: conversationID,
+	})
+	if err != nil {
+		log.Printf("failed to load followed threads: %v", err)
+	}
+	for _, threadID := range threadIDs {
+		followed[threadID] = true
+	}
+
+	replayedSeq := *lastSeq
+	for _, message := range messages {
+		if message.Seq > replayedSeq {
+			replayedSeq = message.Seq
+		}
+		if message.ThreadID.Valid && !followed[message.ThreadID.UUID] {
+			continue
+		}
+		client.SendMessage(h.replayEvent(message, *lastSeq))
+	}
+
+	client.SendMessage(OutgoingMessage{
+		Type:           TypeSubscribe,
+		ConversationID: &conversationID,
+		Seq:            replayedSeq,
+		HasMore:        hasMore,
+	})
+	client.endReplay(conversationID, replayedSeq)
+}
+
+// replayEvent turns a stored message into the event the client missed: a
+// delete, an edit of a message it already has, or a new message.
+func (h *MessageHandler) replayEvent(message database.Message, lastSeq int64) OutgoingMessage {
+	var threadID *uuid.UUID
+	if message.ThreadID.Valid {
+		threadID = &message.ThreadID.UUID
+	}
+
+	if message.DeletedAt.Valid {
+		return OutgoingMessage{
+			Type:           TypeDelete,
+			MessageID:      &message.ID,
+			ConversationID: &message.ConversationID,
+			ThreadID:       threadID,
+			Seq:            message.Seq,
+		}
+	}
+
+	if message.Seq <= lastSeq {
+		return OutgoingMessage{
+			Type:           TypeEdit,
+			MessageID:      &message.ID,
+			ConversationID: &message.ConversationID,
+			SenderID:       &message.SenderID,
+			Content:        message.Content.String,
+			ThreadID:       threadID,
+			IsEdited:       true,
+			CreatedAt:      message.UpdatedAt.Format(time.RFC3339),
+			Seq:            message.Seq,
+		}
+	}
+
+	outgoing := OutgoingMessage{
+		Type:           TypeText,
+		MessageID:      &message.ID,
+		ConversationID: &message.ConversationID,
+		SenderID:       &message.SenderID,
+		Content:        message.Content.String,
+		ThreadID:       threadID,
+		IsEdited:       message.IsEdited,
+		CreatedAt:      message.CreatedAt.Format(time.RFC3339),
+		Seq:            message.Seq,
+	}
+
+	if message.FileID.Valid {
+		outgoing.Type = TypeFile
+		outgoing.FileID = &message.FileID.UUID
+		file, err := h.DB.GetFileByID(context.Background(), message.FileID.UUID)
+		// a file found infected after it was posted is no longer handed out
+		if err == nil && fileServable(file) {
+			outgoing.FileURL = h.Storage.URL(file.Path, storage.ServedFile{ID: file.ID, Name: file.Name, MimeType: file.MimeType})
+			outgoing.File = h.fileInfo(file)
+		}
+	}
+
+	if message.ReplyToID.Valid {
+		outgoing.ReplyToID = &message.ReplyToID.UUID
+	}
+
+	return outgoing
+}
+
+func (h *MessageHandler) handleUnsubscribe(client *Client, msg IncomingMessage) {
+	if msg.ConversationID == uuid.Nil {
+		client.SendMessage(OutgoingMessage{Type: TypeError, Error: "conversation_id required"})
+		return
+	}
+
+	h.Hub.Unsubscribe(client, msg.ConversationID)
+	client.SendMessage(OutgoingMessage{Type: TypeUnsubscribe, ConversationID: &msg.ConversationID})
+}
+
 func (h *MessageHandler) handleSendMessage(client *Client, msg IncomingMessage) {
 	if msg.Content == "" && msg.FileID == nil {
 		client.SendMessage(OutgoingMessage{
@@ -80,6 +294,16 @@ func (h *MessageHandler) handleSendMessage(client *Client, msg IncomingMessage)
 		return
 	}
 
+	// a retry of a send that already went through gets the original ack,
+	// before any of the checks the first attempt passed run again
+	var clientMsgID uuid.NullUUID
+	if msg.ClientMsgID != nil {
+		clientMsgID = uuid.NullUUID{UUID: *msg.ClientMsgID, Valid: true}
+		if h.ackExistingMessage(client, msg.ConversationID, clientMsgID) {
+			return
+		}
+	}
+
 	// build nullable fields
 	var content sql.NullString
 	if msg.Content != "" {
@@ -88,6 +312,51 @@ func (h *MessageHandler) handleSendMessage(client *Client, msg IncomingMessage)
 
 	var fileID uuid.NullUUID
 	if msg.FileID != nil {
+		// attaching a file shares it with the conversation, so the sender
+		// must be allowed to see it in the first place
+		allowed, err := h.DB.CanAccessFile(context.Background(), database.CanAccessFileParams{
+			FileID: *msg.FileID,
+			UserID: client.UserID,
+		})
+		if err != nil || !allowed {
+			client.SendMessage(OutgoingMessage{Type: TypeError, Error: "File not found"})
+			return
+		}
+		// files uploaded for a conversation count against its quota, so
+		// they can't be posted elsewhere
+		file, err := h.DB.GetFileByID(context.Background(), *msg.FileID)
+		if err != nil {
+			client.SendMessage(OutgoingMessage{Type: TypeError, Error: "File not found"})
+			return
+		}
+		if file.ConversationID.Valid && file.ConversationID.UUID != msg.ConversationID {
+			client.SendMessage(OutgoingMessage{Type: TypeError, Error: "File belongs to another conversation"})
+			return
+		}
+		// other members must never get a file clamd hasn't cleared
+		switch file.ScanStatus {
+		case database.FileScanStatusClean, database.FileScanStatusSkipped:
+		case database.FileScanStatusPending:
+			client.SendMessage(OutgoingMessage{Type: TypeError, Error: "File is still being scanned"})
+			return
+		default:
+			client.SendMessage(OutgoingMessage{Type: TypeError, Error: "File failed its virus scan"})
+			return
+		}
+		// a file uploaded outside any conversation is charged to the first
+		// one it is posted in, and then belongs to it
+		if !file.ConversationID.Valid {
+			err := h.attachFile(file.ID, msg.ConversationID)
+			if err == sql.ErrNoRows {
+				client.SendMessage(OutgoingMessage{Type: TypeError, Error: "Conversation storage quota exceeded"})
+				return
+			}
+			if err != nil {
+				log.Printf("failed to attach file: %v", err)
+				client.SendMessage(OutgoingMessage{Type: TypeError, Error: "Failed to attach file"})
+				return
+			}
+		}
 		fileID = uuid.NullUUID{UUID: *msg.FileID, Valid: true}
 	}
 
@@ -96,15 +365,36 @@ func (h *MessageHandler) handleSendMessage(client *Client, msg IncomingMessage)
 		replyToID = uuid.NullUUID{UUID: *msg.ReplyToID, Valid: true}
 	}
 
+	var threadID uuid.NullUUID
+	if msg.ThreadID != nil {
+		// replies hang off a root message; threads do not nest
+		root, err := h.DB.GetMessageByID(context.Background(), *msg.ThreadID)
+		if err != nil || root.ConversationID != msg.ConversationID || root.DeletedAt.Valid || root.ThreadID.Valid {
+			client.SendMessage(OutgoingMessage{Type: TypeError, Error: "Thread not found"})
+			return
+		}
+		threadID = uuid.NullUUID{UUID: root.ID, Valid: true}
+		h.followThread(root.ID, root.SenderID)
+	}
+
 	// save to DB
 	savedMsg, err := h.DB.CreateMessage(context.Background(), database.CreateMessageParams{
-		ConversationID: client.ConversationID,
+		ConversationID: msg.ConversationID,
 		SenderID:       client.UserID,
 		Content:        content,
 		FileID:         fileID,
 		ReplyToID:      replyToID,
+		ClientMsgID:    clientMsgID,
+		ThreadID:       threadID,
 	})
 	if err != nil {
+		// a concurrent retry inserted it first
+		var pqErr *pq.Error
+		if clientMsgID.Valid && errors.As(err, &pqErr) && pqErr.Code == "23505" &&
+			pqErr.Constraint == "idx_messages_client_msg_id" &&
+			h.ackExistingMessage(client, msg.ConversationID, clientMsgID) {
+			return
+		}
 		log.Printf("failed to save message: %v", err)
 		client.SendMessage(OutgoingMessage{
 			Type:  TypeError,
@@ -114,7 +404,8 @@ func (h *MessageHandler) handleSendMessage(client *Client, msg IncomingMessage)
 	}
 
 	// update conversation timestamp
-	_ = h.DB.UpdateConversationTimestamp(context.Background(), client.ConversationID)
+	_ = h.DB.UpdateConversationTimestamp(context.Background(), msg.ConversationID)
+	h.invalidateConversationLists(savedMsg)
 
 	// build outgoing message
 	outgoing := OutgoingMessage{
@@ -124,6 +415,8 @@ func (h *MessageHandler) handleSendMessage(client *Client, msg IncomingMessage)
 		SenderID:       &savedMsg.SenderID,
 		Content:        msg.Content,
 		CreatedAt:      savedMsg.CreatedAt.Format(time.RFC3339),
+		Seq:            savedMsg.Seq,
+		ClientMsgID:    msg.ClientMsgID,
 	}
 
 	if msg.FileID != nil {
@@ -131,7 +424,8 @@ func (h *MessageHandler) handleSendMessage(client *Client, msg IncomingMessage)
 		// fetch file URL
 		file, err := h.DB.GetFileByID(context.Background(), *msg.FileID)
 		if err == nil {
-			outgoing.FileURL = h.Storage.URL(file.Path)
+			outgoing.FileURL = h.Storage.URL(file.Path, storage.ServedFile{ID: file.ID, Name: file.Name, MimeType: file.MimeType})
+			outgoing.File = h.fileInfo(file)
 		}
 	}
 
@@ -139,18 +433,176 @@ func (h *MessageHandler) handleSendMessage(client *Client, msg IncomingMessage)
 		outgoing.ReplyToID = msg.ReplyToID
 	}
 
+	if threadID.Valid {
+		outgoing.ThreadID = &threadID.UUID
+		h.followThread(threadID.UUID, client.UserID)
+	}
+
 	// send ack back to sender with message ID
 	client.SendMessage(OutgoingMessage{
-		Type:      TypeAck,
-		MessageID: &savedMsg.ID,
-		CreatedAt: savedMsg.CreatedAt.Format(time.RFC3339),
+		Type:           TypeAck,
+		MessageID:      &savedMsg.ID,
+		ConversationID: &savedMsg.ConversationID,
+		CreatedAt:      savedMsg.CreatedAt.Format(time.RFC3339),
+		Seq:            savedMsg.Seq,
+		ClientMsgID:    msg.ClientMsgID,
 	})
 
-	// broadcast to other members
-	h.Hub.BroadcastToConversation(client.ConversationID, client.UserID, outgoing)
+	// broadcast to other members, or to the thread's followers
+	recipients := h.broadcastMessageEvent(savedMsg, client.UserID, outgoing)
 
-	// mark as delivered for online members
-	h.markDeliveredForOnlineMembers(savedMsg.ID, client.ConversationID, client.UserID)
+	// mark as delivered for online recipients
+	h.markDeliveredForOnlineMembers(savedMsg.ID, msg.ConversationID, client.UserID, recipients)
+}
+
+// attachFile charges a file to a conversation under the conversation's
+// storage lock, which uploads into it take as well, so the two can't both
+// fit in the space left for one. sql.ErrNoRows means it doesn't fit.
+func (h *MessageHandler) attachFile(fileID, conversationID uuid.UUID) error {
+	ctx := context.Background()
+	tx, err := h.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to roll back file attach: %v", err)
+		}
+	}()
+
+	queries := h.DB.WithTx(tx)
+	if err := queries.LockConversationStorage(ctx, conversationID); err != nil {
+		return err
+	}
+	if _, err := queries.AttachFileToConversation(ctx, database.AttachFileToConversationParams{
+		DefaultQuota:   h.conversationQuota,
+		ConversationID: conversationID,
+		ID:             fileID,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (h *MessageHandler) fileInfo(file database.File) *FileInfo {
+	info := &FileInfo{
+		Name:     file.Name,
+		MimeType: file.MimeType,
+		Size:     file.Size,
+		SHA256:   file.Sha256.String,
+		Blurhash: file.Blurhash.String,
+	}
+	if file.Width.Valid && file.Height.Valid {
+		info.Width = &file.Width.Int32
+		info.Height = &file.Height.Int32
+	}
+	if !file.Width.Valid {
+		return info
+	}
+
+	thumbnails, err := h.DB.GetFileThumbnails(context.Background(), file.ID)
+	if err != nil {
+		log.Printf("failed to load thumbnails: %v", err)
+		return info
+	}
+	for _, thumbnail := range thumbnails {
+		info.Thumbnails = append(info.Thumbnails, Thumbnail{
+			Size:   thumbnail.Size,
+			Width:  thumbnail.Width,
+			Height: thumbnail.Height,
+			URL:    h.Storage.URL(thumbnail.Path, storage.ServedFile{ID: file.ID, Name: file.Name, MimeType: thumbnail.MimeType}),
+		})
+	}
+	return info
+}
+
+// fileServable reports whether a file may be handed out: scanned clean, or
+// from before scanning.
+func fileServable(file database.File) bool {
+	return file.ScanStatus == database.FileScanStatusClean || file.ScanStatus == database.FileScanStatusSkipped
+}
+
+// invalidateConversationLists drops the cached inbox of every member after a
+// timeline message changes, as it may be the preview shown there. Thread
+// replies are never previewed.
+func (h *MessageHandler) invalidateConversationLists(message database.Message) {
+	if message.ThreadID.Valid {
+		return
+	}
+
+	members, err := h.DB.GetConversationMembers(context.Background(), message.ConversationID)
+	if err != nil {
+		log.Printf("failed to fetch members for cache invalidation: %v", err)
+		return
+	}
+	for _, member := range members {
+		if err := h.Cache.Delete(context.Background(), cache.KeyConversationsList(member.UserID.String())); err != nil {
+			log.Printf("failed to invalidate conversations cache: %v", err)
+		}
+	}
+}
+
+// broadcastMessageEvent sends an event about message to the other members of
+// its conversation or, for a thread reply, to the thread's followers only. It
+// returns the user IDs the event was addressed to, nil meaning every member.
+func (h *MessageHandler) broadcastMessageEvent(message database.Message, senderID uuid.UUID, outgoing OutgoingMessage) []uuid.UUID {
+	if !message.ThreadID.Valid {
+		h.Hub.BroadcastToConversation(message.ConversationID, senderID, outgoing)
+		return nil
+	}
+
+	followers, err := h.DB.GetThreadFollowers(context.Background(), message.ThreadID.UUID)
+	if err != nil {
+		log.Printf("failed to load thread followers: %v", err)
+		return []uuid.UUID{}
+	}
+
+	recipients := make([]uuid.UUID, 0, len(followers))
+	for _, userID := range followers {
+		if userID != senderID {
+			recipients = append(recipients, userID)
+		}
+	}
+	h.Hub.SendToUsers(message.ConversationID, recipients, outgoing)
+	return recipients
+}
+
+// followThread subscribes userID to a thread's events. Repliers and the root's
+// author are followed automatically.
+func (h *MessageHandler) followThread(threadID uuid.UUID, userID uuid.UUID) {
+	err := h.DB.FollowThread(context.Background(), database.FollowThreadParams{
+		ThreadID: threadID,
+		UserID:   userID,
+	})
+	if err != nil {
+		log.Printf("failed to follow thread: %v", err)
+	}
+}
+
+// ackExistingMessage acks a message the sender already created with the same
+// client_msg_id. It reports whether such a message exists.
+func (h *MessageHandler) ackExistingMessage(client *Client, conversationID uuid.UUID, clientMsgID uuid.NullUUID) bool {
+	existing, err := h.DB.GetMessageByClientMsgID(context.Background(), database.GetMessageByClientMsgIDParams{
+		ConversationID: conversationID,
+		SenderID:       client.UserID,
+		ClientMsgID:    clientMsgID,
+	})
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("failed to look up message by client_msg_id: %v", err)
+		}
+		return false
+	}
+
+	client.SendMessage(OutgoingMessage{
+		Type:           TypeAck,
+		MessageID:      &existing.ID,
+		ConversationID: &existing.ConversationID,
+		CreatedAt:      existing.CreatedAt.Format(time.RFC3339),
+		Seq:            existing.Seq,
+		ClientMsgID:    &existing.ClientMsgID.UUID,
+	})
+	return true
 }
 
 func (h *MessageHandler) handleEditMessage(client *Client, msg IncomingMessage) {
@@ -164,9 +616,10 @@ func (h *MessageHandler) handleEditMessage(client *Client, msg IncomingMessage)
 	}
 
 	edited, err := h.DB.EditMessage(context.Background(), database.EditMessageParams{
-		ID:       *msg.MessageID,
-		Content:  sql.NullString{String: msg.Content, Valid: true},
-		SenderID: client.UserID,
+		ID:             *msg.MessageID,
+		Content:        sql.NullString{String: msg.Content, Valid: true},
+		SenderID:       client.UserID,
+		ConversationID: msg.ConversationID,
 	})
 	if err != nil {
 		client.SendMessage(OutgoingMessage{Type: TypeError, Error: "Failed to edit message"})
@@ -181,10 +634,15 @@ func (h *MessageHandler) handleEditMessage(client *Client, msg IncomingMessage)
 		Content:        edited.Content.String,
 		IsEdited:       true,
 		CreatedAt:      edited.UpdatedAt.Format(time.RFC3339),
+		Seq:            edited.Seq,
+	}
+	if edited.ThreadID.Valid {
+		outgoing.ThreadID = &edited.ThreadID.UUID
 	}
 
-	client.SendMessage(OutgoingMessage{Type: TypeAck, MessageID: &edited.ID})
-	h.Hub.BroadcastToConversation(client.ConversationID, client.UserID, outgoing)
+	h.invalidateConversationLists(edited)
+	client.SendMessage(OutgoingMessage{Type: TypeAck, MessageID: &edited.ID, ConversationID: &edited.ConversationID})
+	h.broadcastMessageEvent(edited, client.UserID, outgoing)
 }
 
 func (h *MessageHandler) handleDeleteMessage(client *Client, msg IncomingMessage) {
@@ -193,9 +651,10 @@ func (h *MessageHandler) handleDeleteMessage(client *Client, msg IncomingMessage
 		return
 	}
 
-	err := h.DB.SoftDeleteMessage(context.Background(), database.SoftDeleteMessageParams{
-		ID:       *msg.MessageID,
-		SenderID: client.UserID,
+	deleted, err := h.DB.SoftDeleteMessage(context.Background(), database.SoftDeleteMessageParams{
+		ID:             *msg.MessageID,
+		SenderID:       client.UserID,
+		ConversationID: msg.ConversationID,
 	})
 	if err != nil {
 		client.SendMessage(OutgoingMessage{Type: TypeError, Error: "Failed to delete message"})
@@ -204,12 +663,17 @@ func (h *MessageHandler) handleDeleteMessage(client *Client, msg IncomingMessage
 
 	outgoing := OutgoingMessage{
 		Type:           TypeDelete,
-		MessageID:      msg.MessageID,
-		ConversationID: &client.ConversationID,
+		MessageID:      &deleted.ID,
+		ConversationID: &deleted.ConversationID,
+		Seq:            deleted.Seq,
+	}
+	if deleted.ThreadID.Valid {
+		outgoing.ThreadID = &deleted.ThreadID.UUID
 	}
 
-	client.SendMessage(OutgoingMessage{Type: TypeAck, MessageID: msg.MessageID})
-	h.Hub.BroadcastToConversation(client.ConversationID, client.UserID, outgoing)
+	h.invalidateConversationLists(deleted)
+	client.SendMessage(OutgoingMessage{Type: TypeAck, MessageID: &deleted.ID, ConversationID: &deleted.ConversationID})
+	h.broadcastMessageEvent(deleted, client.UserID, outgoing)
 }
 
 func (h *MessageHandler) handleReadMessage(client *Client, msg IncomingMessage) {
@@ -224,48 +688,157 @@ func (h *MessageHandler) handleReadMessage(client *Client, msg IncomingMessage)
 	})
 
 	_ = h.DB.UpdateLastRead(context.Background(), database.UpdateLastReadParams{
-		ConversationID: client.ConversationID,
+		ConversationID: msg.ConversationID,
 		UserID:         client.UserID,
 	})
+	if err := h.Cache.Delete(context.Background(), cache.KeyConversationsList(client.UserID.String())); err != nil {
+		log.Printf("failed to invalidate conversations cache: %v", err)
+	}
 
 	outgoing := OutgoingMessage{
 		Type:           TypeRead,
 		MessageID:      msg.MessageID,
-		ConversationID: &client.ConversationID,
+		ConversationID: &msg.ConversationID,
 		SenderID:       &client.UserID,
 	}
 
-	h.Hub.BroadcastToConversation(client.ConversationID, client.UserID, outgoing)
+	h.Hub.BroadcastToConversation(msg.ConversationID, client.UserID, outgoing)
 }
 
-func (h *MessageHandler) handleTyping(client *Client, msg IncomingMessage) {
-	h.Hub.BroadcastToConversation(client.ConversationID, client.UserID, OutgoingMessage{
+func (h *MessageHandler) handleReaction(client *Client, msg IncomingMessage) {
+	if msg.MessageID == nil {
+		client.SendMessage(OutgoingMessage{Type: TypeError, Error: "message_id required"})
+		return
+	}
+	if msg.Emoji == "" || utf8.RuneCountInString(msg.Emoji) > maxEmojiLength {
+		client.SendMessage(OutgoingMessage{Type: TypeError, Error: "Invalid emoji"})
+		return
+	}
+
+	// subscribing checked membership, so only verify the message is in this conversation
+	message, err := h.DB.GetMessageByID(context.Background(), *msg.MessageID)
+	if err != nil || message.ConversationID != msg.ConversationID || message.DeletedAt.Valid {
+		client.SendMessage(OutgoingMessage{Type: TypeError, Error: "Message not found"})
+		return
+	}
+
+	var changed int64
+	if msg.Type == TypeReact {
+		changed, err = h.DB.AddMessageReaction(context.Background(), database.AddMessageReactionParams{
+			MessageID: message.ID,
+			UserID:    client.UserID,
+			Emoji:     msg.Emoji,
+		})
+	} else {
+		changed, err = h.DB.RemoveMessageReaction(context.Background(), database.RemoveMessageReactionParams{
+			MessageID: message.ID,
+			UserID:    client.UserID,
+			Emoji:     msg.Emoji,
+		})
+	}
+	if err != nil {
+		log.Printf("failed to update reaction: %v", err)
+		client.SendMessage(OutgoingMessage{Type: TypeError, Error: "Failed to update reaction"})
+		return
+	}
+
+	client.SendMessage(OutgoingMessage{Type: TypeAck, MessageID: &message.ID, ConversationID: &message.ConversationID})
+
+	// nothing to broadcast if the user had already reacted, or had not reacted
+	if changed == 0 {
+		return
+	}
+
+	outgoing := OutgoingMessage{
 		Type:           msg.Type,
-		ConversationID: &client.ConversationID,
+		MessageID:      &message.ID,
+		ConversationID: &message.ConversationID,
 		SenderID:       &client.UserID,
-	})
+		Emoji:          msg.Emoji,
+	}
+	if message.ThreadID.Valid {
+		outgoing.ThreadID = &message.ThreadID.UUID
+	}
+	h.broadcastMessageEvent(message, client.UserID, outgoing)
 }
 
-func (h *MessageHandler) markDeliveredForOnlineMembers(messageID uuid.UUID, conversationID uuid.UUID, senderID uuid.UUID) {
-	members, err := h.DB.GetConversationMembers(context.Background(), conversationID)
+func (h *MessageHandler) handleFollowThread(client *Client, msg IncomingMessage) {
+	if msg.ThreadID == nil {
+		client.SendMessage(OutgoingMessage{Type: TypeError, Error: "thread_id required"})
+		return
+	}
+
+	root, err := h.DB.GetMessageByID(context.Background(), *msg.ThreadID)
+	if err != nil || root.ConversationID != msg.ConversationID || root.ThreadID.Valid {
+		client.SendMessage(OutgoingMessage{Type: TypeError, Error: "Thread not found"})
+		return
+	}
+
+	if msg.Type == TypeFollow {
+		err = h.DB.FollowThread(context.Background(), database.FollowThreadParams{
+			ThreadID: root.ID,
+			UserID:   client.UserID,
+		})
+	} else {
+		err = h.DB.UnfollowThread(context.Background(), database.UnfollowThreadParams{
+			ThreadID: root.ID,
+			UserID:   client.UserID,
+		})
+	}
 	if err != nil {
+		log.Printf("failed to update thread follow: %v", err)
+		client.SendMessage(OutgoingMessage{Type: TypeError, Error: "Failed to update thread follow"})
 		return
 	}
 
-	for _, member := range members {
-		if member.UserID == senderID {
+	client.SendMessage(OutgoingMessage{
+		Type:           msg.Type,
+		ConversationID: &root.ConversationID,
+		ThreadID:       &root.ID,
+	})
+}
+
+func (h *MessageHandler) handleTyping(client *Client, msg IncomingMessage) {
+	h.Hub.BroadcastToConversation(msg.ConversationID, client.UserID, OutgoingMessage{
+		Type:           msg.Type,
+		ConversationID: &msg.ConversationID,
+		SenderID:       &client.UserID,
+	})
+}
+
+// markDeliveredForOnlineMembers records delivery to the online recipients of
+// a message. A nil recipients means every member of the conversation.
+func (h *MessageHandler) markDeliveredForOnlineMembers(messageID uuid.UUID, conversationID uuid.UUID, senderID uuid.UUID, recipients []uuid.UUID) {
+	if recipients == nil {
+		members, err := h.DB.GetConversationMembers(context.Background(), conversationID)
+		if err != nil {
+			return
+		}
+		for _, member := range members {
+			recipients = append(recipients, member.UserID)
+		}
+	}
+
+	online := make(map[uuid.UUID]bool)
+	for _, userID := range h.Hub.GetOnlineUsers(conversationID) {
+		online[userID] = true
+	}
+
+	for _, userID := range recipients {
+		if userID == senderID {
 			continue
 		}
-		if h.Hub.IsUserOnline(conversationID, member.UserID) {
+		if online[userID] {
 			_ = h.DB.UpsertMessageReceipt(context.Background(), database.UpsertMessageReceiptParams{
 				MessageID: messageID,
-				UserID:    member.UserID,
+				UserID:    userID,
 			})
 			// notify sender of delivery
 			h.Hub.SendToUser(conversationID, senderID, OutgoingMessage{
-				Type:      TypeDelivered,
-				MessageID: &messageID,
-				SenderID:  &member.UserID,
+				Type:           TypeDelivered,
+				MessageID:      &messageID,
+				ConversationID: &conversationID,
+				SenderID:       &userID,
 			})
 		}
 	}
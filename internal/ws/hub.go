@@ -1,184 +1,362 @@
 package ws
 
 import (
+	"context"
 	"encoding/json"
+	"log"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
 )
 
 type Hub struct {
 	Rooms      map[uuid.UUID]map[uuid.UUID]map[*Client]bool
 	Register   chan *Client
 	Unregister chan *Client
+	clients    map[*Client]bool
+	joins      chan subscription
+	leaves     chan subscription
 	stop       chan struct{}
+	redisOps   chan func() // channel and presence updates, run in order off the Run loop
 	mu         sync.RWMutex
+	redis      *redis.Client
+	pubsub     *redis.PubSub
+	instanceID string
 }
 
-func NewHub() *Hub {
+type subscription struct {
+	client         *Client
+	conversationID uuid.UUID
+	done           chan struct{}
+}
+
+func NewHub(redisClient *redis.Client) *Hub {
 	return &Hub{
 		Rooms:      make(map[uuid.UUID]map[uuid.UUID]map[*Client]bool),
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
+		clients:    make(map[*Client]bool),
+		joins:      make(chan subscription),
+		leaves:     make(chan subscription),
 		stop:       make(chan struct{}),
+		redisOps:   make(chan func(), redisOpsBufferSize),
+		redis:      redisClient,
+		pubsub:     redisClient.Subscribe(context.Background()),
+		instanceID: uuid.New().String(),
+	}
+}
+
+// Subscribe starts delivering the conversation's events to client and
+// returns once the client is in the room. Callers must have verified
+// membership.
+func (h *Hub) Subscribe(client *Client, conversationID uuid.UUID) {
+	done := make(chan struct{})
+	select {
+	case h.joins <- subscription{client: client, conversationID: conversationID, done: done}:
+	case <-h.stop:
+		return
+	}
+	select {
+	case <-done:
+	case <-h.stop:
 	}
 }
 
+func (h *Hub) Unsubscribe(client *Client, conversationID uuid.UUID) {
+	select {
+	case h.leaves <- subscription{client: client, conversationID: conversationID}:
+	case <-h.stop:
+	}
+}
+
+// RemoveUsers unsubscribes the given users' connections on every instance
+// from the conversation, telling each with an unsubscribe event, once they
+// are no longer members. With no users, everyone is removed, as when the
+// conversation is deleted.
+func (h *Hub) RemoveUsers(conversationID uuid.UUID, userIDs []uuid.UUID) {
+	data, err := json.Marshal(OutgoingMessage{Type: TypeUnsubscribe, ConversationID: &conversationID})
+	if err != nil {
+		return
+	}
+
+	h.publish(envelope{
+		ConversationID: conversationID,
+		TargetUserIDs:  userIDs,
+		Remove:         true,
+		Payload:        data,
+	})
+}
+
 func (h *Hub) Stop() {
 	close(h.stop)
 
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	if err := h.pubsub.Close(); err != nil {
+		log.Printf("Error closing pub/sub: %v", err)
+	}
+
+	for conversationID, users := range h.Rooms {
+		for userID := range users {
+			h.clearPresence(conversationID, userID)
+		}
+	}
+
 	// close all client connections concurrently
 	var wg sync.WaitGroup
-	for _, users := range h.Rooms {
-		for _, clients := range users {
-			for client := range clients {
-				wg.Add(1)
-				go func(c *Client) {
-					defer wg.Done()
-					_ = c.Conn.WriteMessage(
-						websocket.CloseMessage,
-						websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
-					)
-					_ = c.Conn.Close()
-				}(client)
-			}
-		}
+	for client := range h.clients {
+		wg.Add(1)
+		go func(c *Client) {
+			defer wg.Done()
+			_ = c.Conn.WriteMessage(
+				websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+			)
+			_ = c.Conn.Close()
+		}(client)
 	}
 	wg.Wait()
 	h.Rooms = make(map[uuid.UUID]map[uuid.UUID]map[*Client]bool)
+	h.clients = make(map[*Client]bool)
 }
 
 func (h *Hub) Run() {
+	go h.listen()
+	go h.runRedisOps()
+
+	ticker := time.NewTicker(presenceRefreshInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case <-h.stop:
 			return
+		case <-ticker.C:
+			go h.refreshPresence()
 		case client := <-h.Register:
 			h.mu.Lock()
-			if _, ok := h.Rooms[client.ConversationID]; !ok {
-				h.Rooms[client.ConversationID] = make(map[uuid.UUID]map[*Client]bool)
-			}
-			if _, ok := h.Rooms[client.ConversationID][client.UserID]; !ok {
-				h.Rooms[client.ConversationID][client.UserID] = make(map[*Client]bool)
-			}
-			isFirstConnection := len(h.Rooms[client.ConversationID][client.UserID]) == 0
-			h.Rooms[client.ConversationID][client.UserID][client] = true
+			h.clients[client] = true
 			h.mu.Unlock()
-
-			if isFirstConnection {
-				go h.BroadcastToConversation(client.ConversationID, client.UserID, OutgoingMessage{
-					Type:           TypeOnline,
-					ConversationID: &client.ConversationID,
-					SenderID:       &client.UserID,
-				})
-			}
-
+		case sub := <-h.joins:
+			h.join(sub.client, sub.conversationID, sub.done)
+		case sub := <-h.leaves:
+			h.leave(sub.client, sub.conversationID)
 		case client := <-h.Unregister:
-			h.mu.Lock()
-			isLastConnection := false
-			if users, ok := h.Rooms[client.ConversationID]; ok {
-				if clients, ok := users[client.UserID]; ok {
-					delete(clients, client)
-					if len(clients) == 0 {
-						isLastConnection = true
-						delete(users, client.UserID)
-					}
-				}
-				if len(users) == 0 {
-					delete(h.Rooms, client.ConversationID)
-				}
-			}
-			select {
-			case _, ok := <-client.Send:
-				if ok {
-					close(client.Send)
-				}
-			default:
-				close(client.Send)
+			for _, conversationID := range client.Conversations() {
+				h.leave(client, conversationID)
 			}
+			h.mu.Lock()
+			delete(h.clients, client)
 			h.mu.Unlock()
-
-			if isLastConnection {
-				go h.BroadcastToConversation(client.ConversationID, client.UserID, OutgoingMessage{
-					Type:           TypeOffline,
-					ConversationID: &client.ConversationID,
-					SenderID:       &client.UserID,
-				})
-			}
+			client.close()
 		}
 	}
 }
 
-func (h *Hub) BroadcastToConversation(conversationID uuid.UUID, senderID uuid.UUID, msg OutgoingMessage) {
-	data, err := json.Marshal(msg)
-	if err != nil {
+// join adds client to the room. Only the maps change here; subscribing to
+// the channel and announcing presence go to runRedisOps, in order with every
+// other join and leave, and done is closed once the channel is subscribed.
+func (h *Hub) join(client *Client, conversationID uuid.UUID, done chan struct{}) {
+	h.mu.Lock()
+	if !h.clients[client] {
+		// already unregistered
+		h.mu.Unlock()
+		close(done)
 		return
 	}
+	isNewRoom := false
+	if _, ok := h.Rooms[conversationID]; !ok {
+		h.Rooms[conversationID] = make(map[uuid.UUID]map[*Client]bool)
+		isNewRoom = true
+	}
+	if _, ok := h.Rooms[conversationID][client.UserID]; !ok {
+		h.Rooms[conversationID][client.UserID] = make(map[*Client]bool)
+	}
+	isFirstConnection := len(h.Rooms[conversationID][client.UserID]) == 0
+	h.Rooms[conversationID][client.UserID][client] = true
+	client.setSubscribed(conversationID, true)
+	h.mu.Unlock()
 
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+	h.queueRedisOp(func() {
+		if isNewRoom {
+			h.subscribeChannel(conversationID)
+		}
+		close(done)
+		if isFirstConnection {
+			h.announceOnline(conversationID, client.UserID)
+		}
+	})
+}
 
+func (h *Hub) leave(client *Client, conversationID uuid.UUID) {
+	h.mu.Lock()
+	isLastConnection := false
+	isRoomEmpty := false
 	if users, ok := h.Rooms[conversationID]; ok {
-		for userID, clients := range users {
-			if userID == senderID {
-				continue
-			}
-			for client := range clients {
-				select {
-				case client.Send <- data:
-				default:
-					close(client.Send)
-					delete(clients, client)
-				}
+		if clients, ok := users[client.UserID]; ok {
+			delete(clients, client)
+			if len(clients) == 0 {
+				isLastConnection = true
+				delete(users, client.UserID)
 			}
 		}
+		if len(users) == 0 {
+			delete(h.Rooms, conversationID)
+			isRoomEmpty = true
+		}
+	}
+	client.setSubscribed(conversationID, false)
+	h.mu.Unlock()
+
+	if !isLastConnection && !isRoomEmpty {
+		return
+	}
+	h.queueRedisOp(func() {
+		if isRoomEmpty {
+			h.unsubscribeChannel(conversationID)
+		}
+		if isLastConnection {
+			h.announceOffline(conversationID, client.UserID)
+		}
+	})
+}
+
+// announceOnline records the user's presence, telling the conversation
+// unless another instance already has them online. Whether they were is
+// read before this instance's entry is added; if Redis can't say, the user
+// had no connection here, so they count as offline.
+func (h *Hub) announceOnline(conversationID uuid.UUID, userID uuid.UUID) {
+	onlineUsers, err := h.onlineUsers(conversationID)
+	if err != nil {
+		log.Printf("failed to fetch presence: %v", err)
+	}
+	wasOnline := slices.Contains(onlineUsers, userID)
+
+	h.setPresence(conversationID, userID)
+	if !wasOnline {
+		go h.BroadcastToConversation(conversationID, userID, OutgoingMessage{
+			Type:           TypeOnline,
+			ConversationID: &conversationID,
+			SenderID:       &userID,
+		})
+	}
+}
+
+func (h *Hub) announceOffline(conversationID uuid.UUID, userID uuid.UUID) {
+	h.clearPresence(conversationID, userID)
+	// the user may still be connected through another instance
+	if !h.IsUserOnline(conversationID, userID) {
+		go h.BroadcastToConversation(conversationID, userID, OutgoingMessage{
+			Type:           TypeOffline,
+			ConversationID: &conversationID,
+			SenderID:       &userID,
+		})
+	}
+}
+
+// BroadcastToConversation delivers msg to every member of the conversation
+// connected to any instance, except the sender.
+func (h *Hub) BroadcastToConversation(conversationID uuid.UUID, senderID uuid.UUID, msg OutgoingMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
 	}
+
+	h.publish(envelope{
+		ConversationID: conversationID,
+		ExcludeUserID:  &senderID,
+		Payload:        data,
+	})
 }
 
+// SendToUser delivers msg to a single member's connections on any instance.
 func (h *Hub) SendToUser(conversationID uuid.UUID, userID uuid.UUID, msg OutgoingMessage) {
+	h.SendToUsers(conversationID, []uuid.UUID{userID}, msg)
+}
+
+// SendToUsers delivers msg to the connections of the given members, such as
+// the followers of a thread, on any instance.
+func (h *Hub) SendToUsers(conversationID uuid.UUID, userIDs []uuid.UUID, msg OutgoingMessage) {
+	if len(userIDs) == 0 {
+		return
+	}
+
 	data, err := json.Marshal(msg)
 	if err != nil {
 		return
 	}
 
+	h.publish(envelope{
+		ConversationID: conversationID,
+		TargetUserIDs:  userIDs,
+		Payload:        data,
+	})
+}
+
+func (h *Hub) deliverLocal(env envelope) {
 	h.mu.RLock()
-	defer h.mu.RUnlock()
 
-	if users, ok := h.Rooms[conversationID]; ok {
-		if clients, ok := users[userID]; ok {
+	var targets map[uuid.UUID]bool
+	if len(env.TargetUserIDs) > 0 {
+		targets = make(map[uuid.UUID]bool, len(env.TargetUserIDs))
+		for _, userID := range env.TargetUserIDs {
+			targets[userID] = true
+		}
+	}
+
+	var removed []*Client
+	if users, ok := h.Rooms[env.ConversationID]; ok {
+		for userID, clients := range users {
+			if env.ExcludeUserID != nil && userID == *env.ExcludeUserID {
+				continue
+			}
+			if targets != nil && !targets[userID] {
+				continue
+			}
 			for client := range clients {
-				select {
-				case client.Send <- data:
-				default:
-					close(client.Send)
-					delete(clients, client)
+				// slow clients are closed on send and cleaned up on unregister
+				client.deliver(env.ConversationID, env.Payload)
+				if env.Remove {
+					removed = append(removed, client)
 				}
 			}
 		}
 	}
+	h.mu.RUnlock()
+
+	// leaving takes the write lock, through Run
+	for _, client := range removed {
+		h.Unsubscribe(client, env.ConversationID)
+	}
 }
 
 func (h *Hub) IsUserOnline(conversationID uuid.UUID, userID uuid.UUID) bool {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-
-	if users, ok := h.Rooms[conversationID]; ok {
-		if clients, ok := users[userID]; ok {
-			return len(clients) > 0
+	for _, onlineUserID := range h.GetOnlineUsers(conversationID) {
+		if onlineUserID == userID {
+			return true
 		}
 	}
 	return false
 }
 
+// GetOnlineUsers returns the members connected to any instance. It falls back
+// to this instance's clients if Redis cannot be reached.
 func (h *Hub) GetOnlineUsers(conversationID uuid.UUID) []uuid.UUID {
+	onlineUsers, err := h.onlineUsers(conversationID)
+	if err == nil {
+		return onlineUsers
+	}
+	log.Printf("failed to fetch presence: %v", err)
+
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	onlineUsers := []uuid.UUID{}
+	onlineUsers = []uuid.UUID{}
 	if users, ok := h.Rooms[conversationID]; ok {
 		for userID := range users {
 			onlineUsers = append(onlineUsers, userID)
@@ -5,28 +5,40 @@ import "github.com/google/uuid"
 type MessageType string
 
 const (
-	TypeText       MessageType = "text"
-	TypeFile       MessageType = "file"
-	TypeEdit       MessageType = "edit"
-	TypeDelete     MessageType = "delete"
-	TypeRead       MessageType = "read"
-	TypeTyping     MessageType = "typing"
-	TypeStopTyping MessageType = "stop_typing"
-	TypeAck        MessageType = "ack"
-	TypeDelivered  MessageType = "delivered"
-	TypeError      MessageType = "error"
-	TypeOnline     MessageType = "online"
-	TypeOffline    MessageType = "offline"
+	TypeText        MessageType = "text"
+	TypeFile        MessageType = "file"
+	TypeEdit        MessageType = "edit"
+	TypeDelete      MessageType = "delete"
+	TypeRead        MessageType = "read"
+	TypeTyping      MessageType = "typing"
+	TypeStopTyping  MessageType = "stop_typing"
+	TypeAck         MessageType = "ack"
+	TypeDelivered   MessageType = "delivered"
+	TypeError       MessageType = "error"
+	TypeOnline      MessageType = "online"
+	TypeOffline     MessageType = "offline"
+	TypeSubscribe   MessageType = "subscribe"
+	TypeUnsubscribe MessageType = "unsubscribe"
+	TypeReact       MessageType = "react"
+	TypeUnreact     MessageType = "unreact"
+	TypeFollow      MessageType = "follow_thread"
+	TypeUnfollow    MessageType = "unfollow_thread"
+	TypePin         MessageType = "pin"
+	TypeUnpin       MessageType = "unpin"
 )
 
-// incoming from client
+// incoming from client, routed by ConversationID
 type IncomingMessage struct {
 	Type           MessageType `json:"type"`
 	ConversationID uuid.UUID   `json:"conversation_id"`
 	Content        string      `json:"content,omitempty"`
 	FileID         *uuid.UUID  `json:"file_id,omitempty"`
 	ReplyToID      *uuid.UUID  `json:"reply_to_id,omitempty"`
-	MessageID      *uuid.UUID  `json:"message_id,omitempty"` // for edit/delete/read
+	MessageID      *uuid.UUID  `json:"message_id,omitempty"`    // for edit/delete/read
+	LastSeq        *int64      `json:"last_seq,omitempty"`      // for subscribe, replays newer events
+	ClientMsgID    *uuid.UUID  `json:"client_msg_id,omitempty"` // makes text/file sends safe to retry
+	Emoji          string      `json:"emoji,omitempty"`         // for react/unreact
+	ThreadID       *uuid.UUID  `json:"thread_id,omitempty"`     // root message, for thread replies and follow/unfollow
 }
 
 // outgoing to client
@@ -38,8 +50,34 @@ type OutgoingMessage struct {
 	Content        string      `json:"content,omitempty"`
 	FileID         *uuid.UUID  `json:"file_id,omitempty"`
 	FileURL        string      `json:"file_url,omitempty"`
+	File           *FileInfo   `json:"file,omitempty"` // for file messages
 	ReplyToID      *uuid.UUID  `json:"reply_to_id,omitempty"`
+	ThreadID       *uuid.UUID  `json:"thread_id,omitempty"`
 	IsEdited       bool        `json:"is_edited,omitempty"`
 	CreatedAt      string      `json:"created_at,omitempty"`
+	Seq            int64       `json:"seq,omitempty"`
+	ClientMsgID    *uuid.UUID  `json:"client_msg_id,omitempty"`
+	Emoji          string      `json:"emoji,omitempty"`
+	HasMore        bool        `json:"has_more,omitempty"` // replay was truncated
 	Error          string      `json:"error,omitempty"`
 }
+
+// FileInfo describes an attachment. Images also carry their dimensions,
+// blurhash and thumbnails so clients can reserve space before loading.
+type FileInfo struct {
+	Name       string      `json:"name"`
+	MimeType   string      `json:"mime_type"`
+	Size       int64       `json:"size"`
+	SHA256     string      `json:"sha256,omitempty"` // lets clients reuse content they already have
+	Width      *int32      `json:"width,omitempty"`
+	Height     *int32      `json:"height,omitempty"`
+	Blurhash   string      `json:"blurhash,omitempty"`
+	Thumbnails []Thumbnail `json:"thumbnails,omitempty"`
+}
+
+type Thumbnail struct {
+	Size   int32  `json:"size"` // longest edge
+	Width  int32  `json:"width"`
+	Height int32  `json:"height"`
+	URL    string `json:"url"`
+}
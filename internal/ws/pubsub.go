@@ -0,0 +1,173 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// presence entries not refreshed within presenceTTL are treated as offline,
+	// so a crashed instance does not keep its users online forever
+	presenceTTL             = 2 * pongWait
+	presenceRefreshInterval = pongWait / 2
+
+	// joins and leaves queued for Redis before the Run loop waits on them
+	redisOpsBufferSize = 256
+)
+
+// envelope is what instances exchange over Redis. Every instance, including
+// the publisher, delivers the payload to its own local clients.
+type envelope struct {
+	ConversationID uuid.UUID       `json:"conversation_id"`
+	ExcludeUserID  *uuid.UUID      `json:"exclude_user_id,omitempty"`
+	TargetUserIDs  []uuid.UUID     `json:"target_user_ids,omitempty"`
+	Remove         bool            `json:"remove,omitempty"` // unsubscribe the recipients after delivery
+	Payload        json.RawMessage `json:"payload"`
+}
+
+func conversationChannel(conversationID uuid.UUID) string {
+	return fmt.Sprintf("ws:conversation:%s", conversationID)
+}
+
+func presenceKey(conversationID uuid.UUID) string {
+	return fmt.Sprintf("ws:presence:%s", conversationID)
+}
+
+func (h *Hub) presenceMember(userID uuid.UUID) string {
+	return fmt.Sprintf("%s:%s", userID, h.instanceID)
+}
+
+func (h *Hub) publish(env envelope) {
+	data, err := json.Marshal(env)
+	if err != nil {
+		return
+	}
+
+	err = h.redis.Publish(context.Background(), conversationChannel(env.ConversationID), data).Err()
+	if err != nil {
+		// still reach clients on this instance if Redis is unavailable
+		log.Printf("failed to publish to conversation %s: %v", env.ConversationID, err)
+		h.deliverLocal(env)
+	}
+}
+
+func (h *Hub) listen() {
+	for msg := range h.pubsub.Channel() {
+		var env envelope
+		if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+			log.Printf("invalid pub/sub message on %s: %v", msg.Channel, err)
+			continue
+		}
+		h.deliverLocal(env)
+	}
+}
+
+// queueRedisOp hands op to runRedisOps, keeping Redis round trips out of
+// the Run loop while leaving them in the order the rooms changed.
+func (h *Hub) queueRedisOp(op func()) {
+	select {
+	case h.redisOps <- op:
+	case <-h.stop:
+	}
+}
+
+func (h *Hub) runRedisOps() {
+	for {
+		select {
+		case op := <-h.redisOps:
+			op()
+		case <-h.stop:
+			return
+		}
+	}
+}
+
+func (h *Hub) subscribeChannel(conversationID uuid.UUID) {
+	if err := h.pubsub.Subscribe(context.Background(), conversationChannel(conversationID)); err != nil {
+		log.Printf("failed to subscribe to conversation %s: %v", conversationID, err)
+	}
+}
+
+func (h *Hub) unsubscribeChannel(conversationID uuid.UUID) {
+	if err := h.pubsub.Unsubscribe(context.Background(), conversationChannel(conversationID)); err != nil {
+		log.Printf("failed to unsubscribe from conversation %s: %v", conversationID, err)
+	}
+}
+
+func (h *Hub) setPresence(conversationID uuid.UUID, userID uuid.UUID) {
+	err := h.redis.ZAdd(context.Background(), presenceKey(conversationID), redis.Z{
+		Score:  float64(time.Now().Unix()),
+		Member: h.presenceMember(userID),
+	}).Err()
+	if err != nil {
+		log.Printf("failed to set presence: %v", err)
+	}
+}
+
+func (h *Hub) clearPresence(conversationID uuid.UUID, userID uuid.UUID) {
+	if err := h.redis.ZRem(context.Background(), presenceKey(conversationID), h.presenceMember(userID)).Err(); err != nil {
+		log.Printf("failed to clear presence: %v", err)
+	}
+}
+
+// refreshPresence bumps the score of every local (conversation, user) pair and
+// drops entries left behind by instances that stopped refreshing.
+func (h *Hub) refreshPresence() {
+	h.mu.RLock()
+	local := make(map[uuid.UUID][]uuid.UUID, len(h.Rooms))
+	for conversationID, users := range h.Rooms {
+		for userID := range users {
+			local[conversationID] = append(local[conversationID], userID)
+		}
+	}
+	h.mu.RUnlock()
+
+	ctx := context.Background()
+	now := time.Now()
+	stale := strconv.FormatInt(now.Add(-presenceTTL).Unix(), 10)
+
+	pipe := h.redis.Pipeline()
+	for conversationID, userIDs := range local {
+		for _, userID := range userIDs {
+			pipe.ZAdd(ctx, presenceKey(conversationID), redis.Z{
+				Score:  float64(now.Unix()),
+				Member: h.presenceMember(userID),
+			})
+		}
+		pipe.ZRemRangeByScore(ctx, presenceKey(conversationID), "-inf", "("+stale)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("failed to refresh presence: %v", err)
+	}
+}
+
+func (h *Hub) onlineUsers(conversationID uuid.UUID) ([]uuid.UUID, error) {
+	members, err := h.redis.ZRangeByScore(context.Background(), presenceKey(conversationID), &redis.ZRangeBy{
+		Min: strconv.FormatInt(time.Now().Add(-presenceTTL).Unix(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	onlineUsers := []uuid.UUID{}
+	for _, member := range members {
+		userIDStr, _, _ := strings.Cut(member, ":")
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil || seen[userID] {
+			continue
+		}
+		seen[userID] = true
+		onlineUsers = append(onlineUsers, userID)
+	}
+	return onlineUsers, nil
+}
@@ -7,15 +7,20 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/Anything-That-Works/GoPath/internal/auth"
 	"github.com/Anything-That-Works/GoPath/internal/cache"
+	"github.com/Anything-That-Works/GoPath/internal/clamav"
 	"github.com/Anything-That-Works/GoPath/internal/database"
 	"github.com/Anything-That-Works/GoPath/internal/handler"
+	"github.com/Anything-That-Works/GoPath/internal/mailer"
 	"github.com/Anything-That-Works/GoPath/internal/model"
 	"github.com/Anything-That-Works/GoPath/internal/storage"
+	"github.com/Anything-That-Works/GoPath/internal/sweeper"
 	"github.com/Anything-That-Works/GoPath/internal/ws"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
@@ -31,6 +36,19 @@ func requireEnv(key string) string {
 	return val
 }
 
+// parseStorageQuota reads a byte quota from key, falling back to def.
+func parseStorageQuota(key string, def int64) int64 {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	quota, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || quota < 0 {
+		log.Fatalf("%s must be a non-negative number of bytes, got %q", key, val)
+	}
+	return quota
+}
+
 func main() {
 	if err := godotenv.Load(".env"); err != nil {
 		log.Println("No .env file found, using environment variables")
@@ -40,11 +58,122 @@ func main() {
 	dbURL := requireEnv("DB_URL")
 	jwtSecretKey := requireEnv("JWT_SECRET_KEY")
 	allowedOrigins := requireEnv("ALLOWED_ORIGINS")
-	uploadsPath := requireEnv("UPLOADS_PATH")
 	baseURL := requireEnv("BASE_URL")
 	trustedProxy := os.Getenv("TRUSTED_PROXY")
 	redisURL := requireEnv("REDIS_URL")
 
+	// signed file URLs get their own key when one is configured, and
+	// otherwise one derived from the JWT key, never the JWT key itself
+	fileURLKey := []byte(os.Getenv("FILE_URL_SECRET"))
+	if len(fileURLKey) == 0 {
+		fileURLKey = auth.PurposeKey([]byte(jwtSecretKey), "file-url")
+	}
+	fileURLTTL := 15 * time.Minute
+	if val := os.Getenv("FILE_URL_TTL"); val != "" {
+		ttl, err := time.ParseDuration(val)
+		if err != nil || ttl <= 0 {
+			log.Fatalf("FILE_URL_TTL must be a positive duration, got %q", val)
+		}
+		fileURLTTL = ttl
+	}
+
+	maxPins := 50
+	if val := os.Getenv("MAX_PINS_PER_CONVERSATION"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 1 {
+			log.Fatalf("MAX_PINS_PER_CONVERSATION must be a positive integer, got %q", val)
+		}
+		maxPins = n
+	}
+
+	keepOriginalImages := false
+	if val := os.Getenv("KEEP_ORIGINAL_IMAGES"); val != "" {
+		keep, err := strconv.ParseBool(val)
+		if err != nil {
+			log.Fatalf("KEEP_ORIGINAL_IMAGES must be a boolean, got %q", val)
+		}
+		keepOriginalImages = keep
+	}
+
+	// byte quotas, 0 for unlimited; set-quota overrides them per user or conversation
+	userStorageQuota := parseStorageQuota("USER_STORAGE_QUOTA", 1<<30)
+	conversationStorageQuota := parseStorageQuota("CONVERSATION_STORAGE_QUOTA", 0)
+
+	// unreferenced files are swept every FILE_SWEEP_INTERVAL; 0 turns it off
+	sweepInterval := 6 * time.Hour
+	if val := os.Getenv("FILE_SWEEP_INTERVAL"); val != "" {
+		interval, err := time.ParseDuration(val)
+		if err != nil || interval < 0 {
+			log.Fatalf("FILE_SWEEP_INTERVAL must be a non-negative duration, got %q", val)
+		}
+		sweepInterval = interval
+	}
+	sweepGrace := 24 * time.Hour
+	if val := os.Getenv("FILE_SWEEP_GRACE"); val != "" {
+		grace, err := time.ParseDuration(val)
+		if err != nil || grace <= 0 {
+			log.Fatalf("FILE_SWEEP_GRACE must be a positive duration, got %q", val)
+		}
+		sweepGrace = grace
+	}
+	// uploads are scanned when a clamd daemon is configured
+	var scanner *clamav.Scanner
+	if address := os.Getenv("CLAMD_ADDRESS"); address != "" {
+		timeout := 2 * time.Minute
+		if val := os.Getenv("CLAMD_TIMEOUT"); val != "" {
+			parsed, err := time.ParseDuration(val)
+			if err != nil || parsed <= 0 {
+				log.Fatalf("CLAMD_TIMEOUT must be a positive duration, got %q", val)
+			}
+			timeout = parsed
+		}
+		clamd, err := clamav.NewScanner(address, timeout)
+		if err != nil {
+			log.Fatal("Cannot configure clamd: ", err)
+		}
+		scanner = clamd
+		if err := scanner.Ping(context.Background()); err != nil {
+			log.Printf("clamd is not reachable yet, uploads stay pending until it is: %v", err)
+		}
+	}
+
+	// without an SMTP server, mail such as verification links is only logged
+	var emailSender mailer.Mailer = mailer.LogMailer{}
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		port := 587
+		if val := os.Getenv("SMTP_PORT"); val != "" {
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 || n > 65535 {
+				log.Fatalf("SMTP_PORT must be a port number, got %q", val)
+			}
+			port = n
+		}
+		from := os.Getenv("MAIL_FROM")
+		if from == "" {
+			from = "GoPath <no-reply@localhost>"
+		}
+		smtpMailer, err := mailer.NewSMTPMailer(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from, 30*time.Second)
+		if err != nil {
+			log.Fatal("Cannot configure SMTP: ", err)
+		}
+		emailSender = smtpMailer
+	}
+	emailVerificationURL := os.Getenv("EMAIL_VERIFICATION_URL")
+	if emailVerificationURL == "" {
+		emailVerificationURL = strings.TrimSuffix(baseURL, "/") + "/v1/user/verify-email"
+	}
+	// the page of the client app that takes a reset token and a new password
+	passwordResetURL := os.Getenv("PASSWORD_RESET_URL")
+
+	sweepDryRun := false
+	if val := os.Getenv("FILE_SWEEP_DRY_RUN"); val != "" {
+		dryRun, err := strconv.ParseBool(val)
+		if err != nil {
+			log.Fatalf("FILE_SWEEP_DRY_RUN must be a boolean, got %q", val)
+		}
+		sweepDryRun = dryRun
+	}
+
 	redisCache, err := cache.NewRedisCache(redisURL)
 	if err != nil {
 		log.Fatal("Cannot connect to Redis: ", err)
@@ -74,27 +203,75 @@ func main() {
 		log.Fatal("Cannot ping database: ", err)
 	}
 
-	hub := ws.NewHub()
+	hub := ws.NewHub(redisCache.Client())
 	go hub.Run()
 
+	urlSigner := storage.NewURLSigner(fileURLKey, fileURLTTL)
+
+	var fileStorage storage.FileStorage
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "local":
+		fileStorage = storage.NewLocalStorage(requireEnv("UPLOADS_PATH"), baseURL, urlSigner)
+	case "s3":
+		s3Config, err := storage.S3ConfigFromEnv()
+		if err != nil {
+			log.Fatal(err)
+		}
+		s3Config.URLTTL = fileURLTTL
+		s3Storage, err := storage.NewS3Storage(s3Config)
+		if err != nil {
+			log.Fatal("Cannot configure S3 storage: ", err)
+		}
+		fileStorage = s3Storage
+	default:
+		log.Fatalf("STORAGE_BACKEND must be \"local\" or \"s3\", got %q", backend)
+	}
+
 	apiConfig := model.ApiConfig{
 		DB:           database.New(con),
+		Conn:         con,
 		JWTSecretKey: []byte(jwtSecretKey),
-		Storage:      storage.NewLocalStorage(uploadsPath, baseURL),
+		Storage:      fileStorage,
+		URLSigner:    urlSigner,
 		Hub:          hub,
 		TrustedProxy: trustedProxy,
 		Cache:        redisCache,
+		MaxPins:      int32(maxPins),
+
+		KeepOriginalImages: keepOriginalImages,
+
+		UserStorageQuota:         userStorageQuota,
+		ConversationStorageQuota: conversationStorageQuota,
+
+		Scanner: scanner,
+
+		Mailer:               emailSender,
+		EmailVerificationURL: emailVerificationURL,
+		PasswordResetURL:     passwordResetURL,
 	}
 	h := handler.New(&apiConfig)
-	msgHandler := ws.NewMessageHandler(hub, h.ApiConfig.DB, h.ApiConfig.Storage)
+	go h.ExpireUploads(15 * time.Minute)
+	if scanner != nil {
+		go h.RescanPendingFiles(time.Minute)
+	}
+	if sweepInterval > 0 {
+		fileSweeper := &sweeper.Sweeper{
+			DB:          apiConfig.DB,
+			Storage:     fileStorage,
+			GracePeriod: sweepGrace,
+			DryRun:      sweepDryRun,
+		}
+		go fileSweeper.Run(context.Background(), sweepInterval)
+	}
+	msgHandler := ws.NewMessageHandler(hub, h.ApiConfig.DB, h.ApiConfig.Conn, h.ApiConfig.Storage, h.ApiConfig.Cache, h.ApiConfig.ConversationStorageQuota)
 
 	router := chi.NewRouter()
 
 	router.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   strings.Split(allowedOrigins, ","),
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Authorization", "Content-Type"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", "Tus-Resumable", "Upload-Length", "Upload-Metadata", "Upload-Offset"},
+		ExposedHeaders:   []string{"Link", "Location", "Tus-Resumable", "Upload-Expires", "Upload-Length", "Upload-Offset"},
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
@@ -149,13 +326,30 @@ func main() {
 
 		r.Post("/user", h.HandlerCreateUser)
 		r.Post("/user/login", h.HandlerLogin)
+		r.Post("/user/login/2fa", h.HandlerLoginTwoFactor)
 		r.Post("/user/lookup", h.HandlerLookupUser)
 		r.Post("/user/exists", h.HandlerEmailExists)
 		r.Put("/user", h.MiddlewareAuth(h.HandlerUpdateUser))
 		r.Get("/user/me", h.MiddlewareAuth(h.HandlerGetProfile))
+		r.Get("/user/verify-email", h.HandlerVerifyEmail)
+		r.Post("/user/verify-email/resend", h.MiddlewareAuth(h.HandlerResendVerificationEmail))
+		r.Post("/user/forgot-password", h.HandlerForgotPassword)
+		r.Post("/user/reset-password", h.HandlerResetPassword)
+		r.Get("/user/2fa", h.MiddlewareAuth(h.HandlerGetTwoFactorStatus))
+		r.Post("/user/2fa/enroll", h.MiddlewareAuth(h.HandlerEnrollTwoFactor))
+		r.Post("/user/2fa/confirm", h.MiddlewareAuth(h.HandlerConfirmTwoFactor))
+		r.Post("/user/2fa/recovery-codes", h.MiddlewareAuth(h.HandlerRegenerateRecoveryCodes))
+		r.Post("/user/2fa/disable", h.MiddlewareAuth(h.HandlerDisableTwoFactor))
+		r.Get("/user/storage", h.MiddlewareAuth(h.HandlerGetStorageUsage))
 		r.Post("/user/refresh", h.HandlerRefreshToken)
 		r.Post("/user/logout", h.MiddlewareAuth(h.HandlerLogout))
 		r.Post("/user/logout-all", h.MiddlewareAuth(h.HandlerLogoutAll))
+		r.Get("/user/sessions", h.MiddlewareAuth(h.HandlerGetSessions))
+		r.Delete("/user/sessions/{id}", h.MiddlewareAuth(h.HandlerRevokeSession))
+
+		r.Post("/files/url", h.MiddlewareAuth(h.HandlerGetFileURL))
+		r.Post("/files/original", h.MiddlewareAuth(h.HandlerGetOriginalFileURL))
+		r.Post("/files/by-hash", h.MiddlewareAuth(h.HandlerCreateFileFromHash))
 
 		r.Post("/conversations/create", h.MiddlewareAuth(h.HandlerCreateConversation))
 		r.Post("/conversations", h.MiddlewareAuth(h.HandlerGetConversations))
@@ -167,13 +361,25 @@ func main() {
 		r.Put("/conversations/name", h.MiddlewareAuth(h.HandlerRenameGroup))
 		r.Post("/conversations/messages", h.MiddlewareAuth(h.HandlerGetMessages))
 		r.Post("/conversations/messages/search", h.MiddlewareAuth(h.HandlerSearchMessages))
+		r.Post("/messages/search", h.MiddlewareAuth(h.HandlerSearchAllMessages))
+		r.Post("/conversations/messages/thread", h.MiddlewareAuth(h.HandlerGetThreadReplies))
+		r.Post("/conversations/pins", h.MiddlewareAuth(h.HandlerGetPinnedMessages))
+		r.Post("/conversations/pins/add", h.MiddlewareAuth(h.HandlerPinMessage))
+		r.Post("/conversations/pins/remove", h.MiddlewareAuth(h.HandlerUnpinMessage))
 		r.Post("/conversations/online", h.MiddlewareAuth(h.HandlerGetOnlineMembers))
+		r.Post("/conversations/storage", h.MiddlewareAuth(h.HandlerGetConversationStorageUsage))
 		r.Delete("/conversations", h.MiddlewareAuth(h.HandlerDeleteConversation))
 	})
 
 	// routes without body limit
 	v1Router.Post("/files", h.MiddlewareAuth(h.HandlerUploadFile))
-	v1Router.Get("/files/{filename}", h.MiddlewareAuth(h.HandlerServeFile))
+	// resumable uploads; chunks stream straight to storage
+	v1Router.Post("/uploads", h.MiddlewareAuth(h.HandlerCreateUpload))
+	v1Router.Head("/uploads/{id}", h.MiddlewareAuth(h.HandlerGetUploadOffset))
+	v1Router.Patch("/uploads/{id}", h.MiddlewareAuth(h.HandlerPatchUpload))
+	v1Router.Delete("/uploads/{id}", h.MiddlewareAuth(h.HandlerDeleteUpload))
+	// authenticates itself: signed URLs work without an Authorization header
+	v1Router.Get("/files/{filename}", h.HandlerServeFile)
 	v1Router.Get("/ws", h.HandlerWebSocket(hub, msgHandler))
 
 	router.Mount("/v1", v1Router)
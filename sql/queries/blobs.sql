@@ -0,0 +1,47 @@
+-- name: AcquireBlob :one
+-- records freshly stored content, or takes another reference on the copy
+-- that is already there; callers compare the returned path with their own
+INSERT INTO blobs (sha256, path, size)
+VALUES ($1, $2, $3)
+ON CONFLICT (sha256) DO UPDATE SET ref_count = blobs.ref_count + 1, last_acquired_at = NOW()
+RETURNING *;
+
+-- name: GetBlob :one
+SELECT * FROM blobs WHERE sha256 = $1;
+
+-- name: AddBlobRef :one
+UPDATE blobs SET ref_count = ref_count + 1, last_acquired_at = NOW()
+WHERE sha256 = $1
+RETURNING *;
+
+-- name: ReleaseBlob :one
+UPDATE blobs SET ref_count = ref_count - 1
+WHERE sha256 = $1
+RETURNING *;
+
+-- name: ListUnreferencedBlobs :many
+SELECT * FROM blobs
+WHERE ref_count <= 0 AND created_at < $1
+ORDER BY sha256;
+
+-- name: DeleteUnreferencedBlob :one
+-- only while still unreferenced; an upload of the same content may have
+-- claimed it meanwhile
+DELETE FROM blobs
+WHERE sha256 = $1 AND ref_count <= 0
+RETURNING *;
+
+-- name: ListBlobRefCountDrift :many
+-- blobs whose count disagrees with the files holding them, e.g. after a
+-- crash between storing content and creating its file; a blob taken again
+-- lately may have an upload whose file doesn't exist yet, so it waits
+SELECT b.sha256, b.ref_count, COUNT(f.id)::int AS file_count
+FROM blobs b
+LEFT JOIN files f ON f.sha256 = b.sha256
+WHERE b.last_acquired_at < $1
+GROUP BY b.sha256
+HAVING b.ref_count <> COUNT(f.id);
+
+-- name: SetBlobRefCount :exec
+UPDATE blobs SET ref_count = sqlc.arg(file_count)
+WHERE sha256 = sqlc.arg(sha256) AND ref_count = sqlc.arg(ref_count);
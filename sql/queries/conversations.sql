@@ -15,12 +15,154 @@ AND c.deleted_at IS NULL
 LIMIT 1;
 
 -- name: GetUserConversations :many
-SELECT c.* FROM conversations c
-JOIN conversation_members cm ON cm.conversation_id = c.id
-WHERE cm.user_id = $1
-AND c.deleted_at IS NULL
-ORDER BY c.updated_at DESC
-LIMIT $2 OFFSET $3;
+-- one row per conversation with everything the inbox renders: the latest
+-- timeline message, the caller's unread count and, for direct chats, the
+-- other participant. The page is picked first, so the summaries are only
+-- worked out for the conversations on it.
+WITH page AS (
+    SELECT c.id FROM conversations c
+    JOIN conversation_members cm ON cm.conversation_id = c.id
+    WHERE cm.user_id = $1
+    AND c.deleted_at IS NULL
+    ORDER BY c.updated_at DESC, c.id DESC
+    LIMIT $2 OFFSET $3
+)
+SELECT
+    sqlc.embed(c),
+    lm.id AS last_message_id,
+    lm.sender_id AS last_message_sender_id,
+    LEFT(lm.content, 120)::text AS last_message_snippet,
+    f.mime_type AS last_message_file_type,
+    lm.created_at AS last_message_at,
+    COALESCE(uc.unread_count, 0)::bigint AS unread_count,
+    ou.id AS other_user_id,
+    ou.name AS other_user_name,
+    ou.email AS other_user_email,
+    ou.email_verified_at AS other_user_email_verified_at
+FROM page
+JOIN conversations c ON c.id = page.id
+JOIN conversation_members cm ON cm.conversation_id = c.id AND cm.user_id = $1
+LEFT JOIN LATERAL (
+    SELECT m.id, m.sender_id, m.content, m.file_id, m.created_at
+    FROM messages m
+    WHERE m.conversation_id = c.id AND m.deleted_at IS NULL AND m.thread_id IS NULL
+    ORDER BY m.seq DESC
+    LIMIT 1
+) lm ON TRUE
+LEFT JOIN files f ON f.id = lm.file_id
+LEFT JOIN LATERAL (
+    SELECT COUNT(*) AS unread_count
+    FROM messages m
+    WHERE m.conversation_id = c.id AND m.deleted_at IS NULL AND m.thread_id IS NULL
+    AND m.sender_id != cm.user_id
+    AND (cm.last_read_at IS NULL OR m.created_at > cm.last_read_at)
+) uc ON TRUE
+LEFT JOIN LATERAL (
+    SELECT u.id, u.name, u.email, u.email_verified_at
+    FROM conversation_members om
+    JOIN users u ON u.id = om.user_id
+    WHERE om.conversation_id = c.id AND om.user_id != cm.user_id
+    LIMIT 1
+) ou ON NOT c.is_group
+ORDER BY c.updated_at DESC, c.id DESC;
+
+-- name: GetUserConversationsBefore :many
+WITH page AS (
+    SELECT c.id FROM conversations c
+    JOIN conversation_members cm ON cm.conversation_id = c.id
+    WHERE cm.user_id = $1
+    AND c.deleted_at IS NULL
+    AND (c.updated_at, c.id) < (sqlc.arg(cursor_updated_at)::timestamptz, sqlc.arg(cursor_id)::uuid)
+    ORDER BY c.updated_at DESC, c.id DESC
+    LIMIT $2
+)
+SELECT
+    sqlc.embed(c),
+    lm.id AS last_message_id,
+    lm.sender_id AS last_message_sender_id,
+    LEFT(lm.content, 120)::text AS last_message_snippet,
+    f.mime_type AS last_message_file_type,
+    lm.created_at AS last_message_at,
+    COALESCE(uc.unread_count, 0)::bigint AS unread_count,
+    ou.id AS other_user_id,
+    ou.name AS other_user_name,
+    ou.email AS other_user_email,
+    ou.email_verified_at AS other_user_email_verified_at
+FROM page
+JOIN conversations c ON c.id = page.id
+JOIN conversation_members cm ON cm.conversation_id = c.id AND cm.user_id = $1
+LEFT JOIN LATERAL (
+    SELECT m.id, m.sender_id, m.content, m.file_id, m.created_at
+    FROM messages m
+    WHERE m.conversation_id = c.id AND m.deleted_at IS NULL AND m.thread_id IS NULL
+    ORDER BY m.seq DESC
+    LIMIT 1
+) lm ON TRUE
+LEFT JOIN files f ON f.id = lm.file_id
+LEFT JOIN LATERAL (
+    SELECT COUNT(*) AS unread_count
+    FROM messages m
+    WHERE m.conversation_id = c.id AND m.deleted_at IS NULL AND m.thread_id IS NULL
+    AND m.sender_id != cm.user_id
+    AND (cm.last_read_at IS NULL OR m.created_at > cm.last_read_at)
+) uc ON TRUE
+LEFT JOIN LATERAL (
+    SELECT u.id, u.name, u.email, u.email_verified_at
+    FROM conversation_members om
+    JOIN users u ON u.id = om.user_id
+    WHERE om.conversation_id = c.id AND om.user_id != cm.user_id
+    LIMIT 1
+) ou ON NOT c.is_group
+ORDER BY c.updated_at DESC, c.id DESC;
+
+-- name: GetUserConversationsAfter :many
+WITH page AS (
+    SELECT c.id FROM conversations c
+    JOIN conversation_members cm ON cm.conversation_id = c.id
+    WHERE cm.user_id = $1
+    AND c.deleted_at IS NULL
+    AND (c.updated_at, c.id) > (sqlc.arg(cursor_updated_at)::timestamptz, sqlc.arg(cursor_id)::uuid)
+    ORDER BY c.updated_at ASC, c.id ASC
+    LIMIT $2
+)
+SELECT
+    sqlc.embed(c),
+    lm.id AS last_message_id,
+    lm.sender_id AS last_message_sender_id,
+    LEFT(lm.content, 120)::text AS last_message_snippet,
+    f.mime_type AS last_message_file_type,
+    lm.created_at AS last_message_at,
+    COALESCE(uc.unread_count, 0)::bigint AS unread_count,
+    ou.id AS other_user_id,
+    ou.name AS other_user_name,
+    ou.email AS other_user_email,
+    ou.email_verified_at AS other_user_email_verified_at
+FROM page
+JOIN conversations c ON c.id = page.id
+JOIN conversation_members cm ON cm.conversation_id = c.id AND cm.user_id = $1
+LEFT JOIN LATERAL (
+    SELECT m.id, m.sender_id, m.content, m.file_id, m.created_at
+    FROM messages m
+    WHERE m.conversation_id = c.id AND m.deleted_at IS NULL AND m.thread_id IS NULL
+    ORDER BY m.seq DESC
+    LIMIT 1
+) lm ON TRUE
+LEFT JOIN files f ON f.id = lm.file_id
+LEFT JOIN LATERAL (
+    SELECT COUNT(*) AS unread_count
+    FROM messages m
+    WHERE m.conversation_id = c.id AND m.deleted_at IS NULL AND m.thread_id IS NULL
+    AND m.sender_id != cm.user_id
+    AND (cm.last_read_at IS NULL OR m.created_at > cm.last_read_at)
+) uc ON TRUE
+LEFT JOIN LATERAL (
+    SELECT u.id, u.name, u.email, u.email_verified_at
+    FROM conversation_members om
+    JOIN users u ON u.id = om.user_id
+    WHERE om.conversation_id = c.id AND om.user_id != cm.user_id
+    LIMIT 1
+) ou ON NOT c.is_group
+ORDER BY c.updated_at ASC, c.id ASC;
 
 -- name: AddConversationMember :exec
 INSERT INTO conversation_members (conversation_id, user_id, role)
@@ -1,10 +1,157 @@
 -- name: CreateFile :one
-INSERT INTO files (uploader_id, name, mime_type, size, path)
-VALUES ($1, $2, $3, $4, $5)
+INSERT INTO files (uploader_id, name, mime_type, size, path, original_path, sha256, conversation_id, scan_status, upload_sha256)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 RETURNING *;
 
 -- name: GetFileByID :one
 SELECT * FROM files WHERE id = $1;
 
 -- name: DeleteFile :exec
-DELETE FROM files WHERE id = $1 AND uploader_id = $2;
\ No newline at end of file
+DELETE FROM files WHERE id = $1 AND uploader_id = $2;
+-- name: GetAccessibleFileByPath :one
+-- deduplicated files share paths, so of the files holding path as their
+-- content, original or a thumbnail, the caller's own comes first, then the
+-- earliest shared with them
+SELECT f.* FROM files f
+WHERE (
+    f.path = sqlc.arg(path) OR f.original_path = sqlc.arg(path) OR EXISTS (
+        SELECT 1 FROM file_thumbnails t
+        WHERE t.file_id = f.id AND t.path = sqlc.arg(path)
+    )
+) AND (
+    f.uploader_id = sqlc.arg(user_id) OR EXISTS (
+        SELECT 1 FROM messages m
+        JOIN conversation_members cm ON cm.conversation_id = m.conversation_id
+        WHERE m.file_id = f.id AND cm.user_id = sqlc.arg(user_id) AND m.deleted_at IS NULL
+    )
+)
+ORDER BY f.uploader_id = sqlc.arg(user_id) DESC, f.created_at
+LIMIT 1;
+
+-- name: CanAccessFile :one
+-- the uploader, and members of any conversation with a message carrying the file
+SELECT (
+    EXISTS (
+        SELECT 1 FROM files f
+        WHERE f.id = sqlc.arg(file_id) AND f.uploader_id = sqlc.arg(user_id)
+    ) OR EXISTS (
+        SELECT 1 FROM messages m
+        JOIN conversation_members cm ON cm.conversation_id = m.conversation_id
+        WHERE m.file_id = sqlc.arg(file_id) AND cm.user_id = sqlc.arg(user_id) AND m.deleted_at IS NULL
+    )
+)::boolean AS can_access;
+
+-- name: ListFilesAfter :many
+SELECT * FROM files
+WHERE id > $1
+ORDER BY id
+LIMIT $2;
+
+-- name: UpdateFilePath :exec
+UPDATE files SET path = sqlc.arg(new_path) WHERE id = sqlc.arg(id);
+
+-- name: SetFileImageInfo :one
+UPDATE files
+SET width = $2, height = $3, blurhash = $4
+WHERE id = $1
+RETURNING *;
+
+-- name: CreateFileThumbnail :one
+INSERT INTO file_thumbnails (file_id, size, width, height, mime_type, path)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetFileThumbnails :many
+SELECT * FROM file_thumbnails
+WHERE file_id = $1
+ORDER BY size;
+
+-- name: CanAccessContent :one
+-- like CanAccessFile, but through any file holding the same bytes
+SELECT EXISTS (
+    SELECT 1 FROM files f
+    WHERE f.sha256 = sqlc.arg(sha256) AND (
+        f.uploader_id = sqlc.arg(user_id) OR EXISTS (
+            SELECT 1 FROM messages m
+            JOIN conversation_members cm ON cm.conversation_id = m.conversation_id
+            WHERE m.file_id = f.id AND cm.user_id = sqlc.arg(user_id) AND m.deleted_at IS NULL
+        )
+    )
+)::boolean AS can_access;
+
+-- name: GetAccessibleFileBySHA256 :one
+-- an image matches the hash of its stored content or of the bytes uploaded
+SELECT f.* FROM files f
+WHERE (f.sha256 = sqlc.arg(sha256) OR f.upload_sha256 = sqlc.arg(sha256)) AND (
+    f.uploader_id = sqlc.arg(user_id) OR EXISTS (
+        SELECT 1 FROM messages m
+        JOIN conversation_members cm ON cm.conversation_id = m.conversation_id
+        WHERE m.file_id = f.id AND cm.user_id = sqlc.arg(user_id) AND m.deleted_at IS NULL
+    )
+)
+ORDER BY f.created_at
+LIMIT 1;
+
+-- name: GetProcessedFileBySHA256 :one
+-- another file with the same content whose image info is already worked out
+SELECT * FROM files
+WHERE sha256 = $1 AND id <> $2 AND width IS NOT NULL
+ORDER BY created_at
+LIMIT 1;
+
+-- name: CopyFileThumbnails :many
+INSERT INTO file_thumbnails (file_id, size, width, height, mime_type, path)
+SELECT sqlc.arg(to_file_id), size, width, height, mime_type, path
+FROM file_thumbnails
+WHERE file_id = sqlc.arg(from_file_id)
+RETURNING *;
+
+-- name: ListOrphanedFiles :many
+-- files older than cutoff that no message carries, apart from messages
+-- deleted before cutoff
+SELECT f.* FROM files f
+WHERE f.created_at < sqlc.arg(cutoff) AND f.id > sqlc.arg(after_id)
+  AND NOT EXISTS (
+      SELECT 1 FROM messages m
+      WHERE m.file_id = f.id AND (m.deleted_at IS NULL OR m.deleted_at >= sqlc.arg(cutoff))
+  )
+ORDER BY f.id
+LIMIT sqlc.arg(max_rows);
+
+-- name: DeleteOrphanedFile :one
+-- rechecks, as the file may have been attached since it was listed
+DELETE FROM files f
+WHERE f.id = sqlc.arg(id)
+  AND NOT EXISTS (
+      SELECT 1 FROM messages m
+      WHERE m.file_id = f.id AND (m.deleted_at IS NULL OR m.deleted_at >= sqlc.arg(cutoff))
+  )
+RETURNING *;
+
+-- name: IsStoragePathReferenced :one
+-- whether any row still points at a stored object
+SELECT (
+    EXISTS (SELECT 1 FROM files WHERE path = $1 OR original_path = $1)
+    OR EXISTS (SELECT 1 FROM file_thumbnails WHERE path = $1)
+    OR EXISTS (SELECT 1 FROM blobs WHERE path = $1)
+    OR EXISTS (SELECT 1 FROM uploads WHERE $1 = ANY(part_paths))
+)::boolean AS referenced;
+
+-- name: SetFileScanResult :one
+UPDATE files
+SET scan_status = $2, scan_signature = $3, scanned_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ListPendingScans :many
+SELECT * FROM files
+WHERE scan_status = 'pending' AND created_at < $1
+ORDER BY created_at
+LIMIT $2;
+
+-- name: GetScanVerdictBySHA256 :one
+-- identical content already scanned under another file
+SELECT scan_status, scan_signature FROM files
+WHERE sha256 = $1 AND id <> $2 AND scan_status IN ('clean', 'infected')
+ORDER BY scanned_at DESC
+LIMIT 1;
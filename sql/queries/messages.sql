@@ -1,27 +1,126 @@
 -- name: CreateMessage :one
-INSERT INTO messages (conversation_id, sender_id, content, file_id, reply_to_id)
-VALUES ($1, $2, $3, $4, $5)
+WITH next_seq AS (
+    UPDATE conversations
+    SET last_seq = last_seq + 1
+    WHERE id = sqlc.arg(conversation_id)
+    RETURNING last_seq
+)
+INSERT INTO messages (conversation_id, sender_id, content, file_id, reply_to_id, client_msg_id, thread_id, seq)
+SELECT sqlc.arg(conversation_id)::uuid, sqlc.arg(sender_id)::uuid, sqlc.narg(content)::text,
+       sqlc.narg(file_id)::uuid, sqlc.narg(reply_to_id)::uuid, sqlc.narg(client_msg_id)::uuid,
+       sqlc.narg(thread_id)::uuid, next_seq.last_seq
+FROM next_seq
 RETURNING *;
 
 -- name: GetMessagesByConversation :many
+-- thread replies are left out of the timeline and summarised on their root;
+-- the page is picked first, so only the roots on it are summarised
+WITH page AS (
+    SELECT id FROM messages
+    WHERE conversation_id = $1 AND deleted_at IS NULL AND thread_id IS NULL
+    ORDER BY created_at DESC, id DESC
+    LIMIT $2 OFFSET $3
+)
+SELECT
+    sqlc.embed(m),
+    COALESCE(t.reply_count, 0)::bigint AS reply_count,
+    t.last_reply_at,
+    COALESCE(t.participant_ids, '{}')::uuid[] AS thread_participant_ids
+FROM page
+JOIN messages m ON m.id = page.id
+LEFT JOIN LATERAL (
+    SELECT
+        COUNT(*) AS reply_count,
+        MAX(r.created_at) AS last_reply_at,
+        ARRAY_AGG(DISTINCT r.sender_id) AS participant_ids
+    FROM messages r
+    WHERE r.thread_id = m.id AND r.deleted_at IS NULL
+) t ON TRUE
+ORDER BY m.created_at DESC, m.id DESC;
+
+-- name: GetMessagesByConversationBefore :many
+WITH page AS (
+    SELECT id FROM messages
+    WHERE conversation_id = $1 AND deleted_at IS NULL AND thread_id IS NULL
+    AND (created_at, id) < (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::uuid)
+    ORDER BY created_at DESC, id DESC
+    LIMIT $2
+)
+SELECT
+    sqlc.embed(m),
+    COALESCE(t.reply_count, 0)::bigint AS reply_count,
+    t.last_reply_at,
+    COALESCE(t.participant_ids, '{}')::uuid[] AS thread_participant_ids
+FROM page
+JOIN messages m ON m.id = page.id
+LEFT JOIN LATERAL (
+    SELECT
+        COUNT(*) AS reply_count,
+        MAX(r.created_at) AS last_reply_at,
+        ARRAY_AGG(DISTINCT r.sender_id) AS participant_ids
+    FROM messages r
+    WHERE r.thread_id = m.id AND r.deleted_at IS NULL
+) t ON TRUE
+ORDER BY m.created_at DESC, m.id DESC;
+
+-- name: GetMessagesByConversationAfter :many
+WITH page AS (
+    SELECT id FROM messages
+    WHERE conversation_id = $1 AND deleted_at IS NULL AND thread_id IS NULL
+    AND (created_at, id) > (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::uuid)
+    ORDER BY created_at ASC, id ASC
+    LIMIT $2
+)
+SELECT
+    sqlc.embed(m),
+    COALESCE(t.reply_count, 0)::bigint AS reply_count,
+    t.last_reply_at,
+    COALESCE(t.participant_ids, '{}')::uuid[] AS thread_participant_ids
+FROM page
+JOIN messages m ON m.id = page.id
+LEFT JOIN LATERAL (
+    SELECT
+        COUNT(*) AS reply_count,
+        MAX(r.created_at) AS last_reply_at,
+        ARRAY_AGG(DISTINCT r.sender_id) AS participant_ids
+    FROM messages r
+    WHERE r.thread_id = m.id AND r.deleted_at IS NULL
+) t ON TRUE
+ORDER BY m.created_at ASC, m.id ASC;
+
+-- name: GetThreadReplies :many
 SELECT * FROM messages
-WHERE conversation_id = $1 AND deleted_at IS NULL
-ORDER BY created_at DESC
+WHERE thread_id = $1 AND deleted_at IS NULL
+ORDER BY created_at ASC
 LIMIT $2 OFFSET $3;
 
+-- name: GetMessagesForReplay :many
+SELECT * FROM messages
+WHERE conversation_id = $1
+AND (seq > $2 OR modified_seq >= $2)
+ORDER BY seq ASC
+LIMIT $3;
+
+-- name: GetMessageByClientMsgID :one
+SELECT * FROM messages
+WHERE conversation_id = $1 AND sender_id = $2 AND client_msg_id = $3;
+
 -- name: GetMessageByID :one
 SELECT * FROM messages WHERE id = $1;
 
 -- name: EditMessage :one
 UPDATE messages
-SET content = $2, is_edited = TRUE, updated_at = NOW()
-WHERE id = $1 AND sender_id = $3 AND deleted_at IS NULL
+SET content = $2, is_edited = TRUE, updated_at = NOW(),
+    modified_seq = (SELECT last_seq FROM conversations WHERE id = messages.conversation_id)
+WHERE id = $1 AND sender_id = $3 AND conversation_id = $4 AND deleted_at IS NULL
 RETURNING *;
 
--- name: SoftDeleteMessage :exec
+-- name: SoftDeleteMessage :one
 UPDATE messages
-SET deleted_at = NOW()
-WHERE id = $1 AND sender_id = $2;
+SET deleted_at = NOW(),
+    modified_seq = (SELECT last_seq FROM conversations WHERE id = messages.conversation_id)
+WHERE id = $1 AND sender_id = $2 AND conversation_id = $3 AND deleted_at IS NULL
+RETURNING *;
 
 -- name: UpsertMessageReceipt :exec
 INSERT INTO message_receipts (message_id, user_id, delivered_at)
@@ -36,12 +135,4 @@ ON CONFLICT (message_id, user_id) DO UPDATE
 SET read_at = NOW();
 
 -- name: GetMessageReceipts :many
-SELECT * FROM message_receipts WHERE message_id = $1;
-
--- name: SearchMessages :many
-SELECT * FROM messages
-WHERE conversation_id = $1
-AND deleted_at IS NULL
-AND content ILIKE '%' || $2 || '%'
-ORDER BY created_at DESC
-LIMIT $3 OFFSET $4;
\ No newline at end of file
+SELECT * FROM message_receipts WHERE message_id = $1;
\ No newline at end of file
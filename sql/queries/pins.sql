@@ -0,0 +1,31 @@
+-- name: LockConversationPins :exec
+-- serialises pinning in a conversation until the transaction ends, so two
+-- pins can't both pass the cap; an advisory lock rather than a row lock, as
+-- every new message updates the conversation row
+SELECT pg_advisory_xact_lock(hashtextextended('conversation_pins:' || sqlc.arg(conversation_id)::uuid::text, 0));
+
+-- name: PinMessage :execrows
+-- deleted messages do not count towards the cap
+INSERT INTO pinned_messages (conversation_id, message_id, pinned_by)
+SELECT $1, $2, $3
+WHERE (
+    SELECT COUNT(*) FROM pinned_messages p
+    JOIN messages m ON m.id = p.message_id
+    WHERE p.conversation_id = $1 AND m.deleted_at IS NULL
+) < sqlc.arg(max_pins)::int
+ON CONFLICT DO NOTHING;
+
+-- name: UnpinMessage :execrows
+DELETE FROM pinned_messages
+WHERE conversation_id = $1 AND message_id = $2;
+
+-- name: GetPinnedMessage :one
+SELECT * FROM pinned_messages
+WHERE conversation_id = $1 AND message_id = $2;
+
+-- name: GetPinnedMessages :many
+SELECT sqlc.embed(m), p.pinned_by, p.pinned_at
+FROM pinned_messages p
+JOIN messages m ON m.id = p.message_id
+WHERE p.conversation_id = $1 AND m.deleted_at IS NULL
+ORDER BY p.pinned_at DESC;
@@ -0,0 +1,64 @@
+-- name: GetUserStorageUsage :one
+SELECT COALESCE(SUM(size), 0)::bigint AS used
+FROM files
+WHERE uploader_id = $1;
+
+-- name: GetConversationStorageUsage :one
+SELECT COALESCE(SUM(size), 0)::bigint AS used
+FROM files
+WHERE conversation_id = $1;
+
+-- name: GetUserStorageQuota :one
+SELECT * FROM user_storage_quotas WHERE user_id = $1;
+
+-- name: SetUserStorageQuota :one
+INSERT INTO user_storage_quotas (user_id, quota_bytes)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE SET quota_bytes = EXCLUDED.quota_bytes, updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteUserStorageQuota :exec
+DELETE FROM user_storage_quotas WHERE user_id = $1;
+
+-- name: GetConversationStorageQuota :one
+SELECT * FROM conversation_storage_quotas WHERE conversation_id = $1;
+
+-- name: SetConversationStorageQuota :one
+INSERT INTO conversation_storage_quotas (conversation_id, quota_bytes)
+VALUES ($1, $2)
+ON CONFLICT (conversation_id) DO UPDATE SET quota_bytes = EXCLUDED.quota_bytes, updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteConversationStorageQuota :exec
+DELETE FROM conversation_storage_quotas WHERE conversation_id = $1;
+
+-- name: AttachFileToConversation :one
+-- binds a file uploaded outside any conversation to the first one it is
+-- posted in, so it counts against that conversation's quota; nothing comes
+-- back if it doesn't fit. Without an override the default applies, 0 for
+-- unlimited.
+WITH quota AS (
+    SELECT CASE
+        WHEN q.conversation_id IS NULL THEN NULLIF(sqlc.arg(default_quota)::bigint, 0)
+        ELSE q.quota_bytes
+    END AS quota_bytes
+    FROM (SELECT 1) AS defaults
+    LEFT JOIN conversation_storage_quotas q ON q.conversation_id = sqlc.arg(conversation_id)
+)
+UPDATE files f
+SET conversation_id = sqlc.arg(conversation_id)
+FROM quota
+WHERE f.id = sqlc.arg(id) AND f.conversation_id IS NULL
+  AND (quota.quota_bytes IS NULL OR f.size + (
+      SELECT COALESCE(SUM(size), 0) FROM files WHERE conversation_id = sqlc.arg(conversation_id)
+  ) <= quota.quota_bytes)
+RETURNING f.*;
+
+-- name: LockUserStorage :exec
+-- held until the transaction ends, so quota checks for one uploader and the
+-- files they let in happen one at a time
+SELECT pg_advisory_xact_lock(hashtextextended('user_storage:' || sqlc.arg(user_id)::uuid::text, 0));
+
+-- name: LockConversationStorage :exec
+-- the same for the files counted against a conversation
+SELECT pg_advisory_xact_lock(hashtextextended('conversation_storage:' || sqlc.arg(conversation_id)::uuid::text, 0));
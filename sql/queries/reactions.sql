@@ -0,0 +1,19 @@
+-- name: AddMessageReaction :execrows
+INSERT INTO message_reactions (message_id, user_id, emoji)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveMessageReaction :execrows
+DELETE FROM message_reactions
+WHERE message_id = $1 AND user_id = $2 AND emoji = $3;
+
+-- name: GetMessageReactions :many
+SELECT
+    message_id,
+    emoji,
+    COUNT(*) AS count,
+    BOOL_OR(user_id = sqlc.arg(user_id))::boolean AS reacted_by_me
+FROM message_reactions
+WHERE message_id = ANY(sqlc.arg(message_ids)::uuid[])
+GROUP BY message_id, emoji
+ORDER BY message_id, MIN(created_at);
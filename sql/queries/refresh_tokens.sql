@@ -1,6 +1,6 @@
 -- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (id, user_id, token_hash, expires_at, user_agent, ip_address)
-VALUES (gen_random_uuid(), $1, $2, $3, $4, $5)
+INSERT INTO refresh_tokens (id, user_id, token_hash, expires_at, user_agent, ip_address, session_id)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6)
 RETURNING *;
 
 -- name: GetRefreshTokenByHash :one
@@ -9,8 +9,43 @@ SELECT * FROM refresh_tokens WHERE token_hash = $1;
 -- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens SET revoked_at = NOW() WHERE id = $1;
 
--- name: RotateRefreshToken :exec
-UPDATE refresh_tokens SET revoked_at = NOW(), replaced_by_token_id = $2 WHERE id = $1;
+-- name: RotateRefreshToken :execrows
+-- affects no row when the token was rotated or revoked meanwhile, so only
+-- one of two concurrent refreshes wins
+UPDATE refresh_tokens SET revoked_at = NOW(), replaced_by_token_id = $2 WHERE id = $1 AND revoked_at IS NULL;
 
 -- name: RevokeAllUserRefreshTokens :exec
-UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL;
\ No newline at end of file
+UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeOtherSessions :exec
+UPDATE refresh_tokens SET revoked_at = NOW()
+WHERE user_id = $1 AND session_id <> $2 AND revoked_at IS NULL;
+
+-- name: ListActiveSessions :many
+-- one row per session, from its newest live refresh token; that token was
+-- issued by the session's last refresh
+SELECT s.session_id, s.user_agent, s.ip_address, s.last_used_at, s.expires_at, s.started_at
+FROM (
+    SELECT DISTINCT ON (rt.session_id)
+        rt.session_id,
+        rt.user_agent,
+        rt.ip_address,
+        rt.created_at AS last_used_at,
+        rt.expires_at,
+        (SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.session_id = rt.session_id)::timestamptz AS started_at
+    FROM refresh_tokens rt
+    WHERE rt.user_id = $1 AND rt.revoked_at IS NULL AND rt.expires_at > NOW()
+    ORDER BY rt.session_id, rt.created_at DESC
+) s
+ORDER BY s.last_used_at DESC;
+
+-- name: IsSessionActive :one
+-- a session lasts as long as it has a live refresh token
+SELECT EXISTS (
+    SELECT 1 FROM refresh_tokens
+    WHERE session_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+)::boolean AS active;
+
+-- name: RevokeSession :execrows
+UPDATE refresh_tokens SET revoked_at = NOW()
+WHERE user_id = $1 AND session_id = $2 AND revoked_at IS NULL;
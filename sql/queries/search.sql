@@ -0,0 +1,78 @@
+-- name: SearchMessages :many
+-- ranked full-text search over every conversation the user belongs to; the
+-- optional filters narrow it down. Highlights mark matches with \x02 and \x03
+-- so the caller can escape the content before turning them into markup.
+-- Ranks are worked out once per match, and highlights only for the page.
+WITH q AS (
+    SELECT websearch_to_tsquery('english', sqlc.arg(query)::text) AS query
+), matches AS (
+    SELECT m.id, ts_rank(s.search_vector, q.query)::real AS rank
+    FROM q
+    JOIN message_search s ON s.search_vector @@ q.query
+    JOIN messages m ON m.id = s.message_id
+    JOIN conversation_members cm ON cm.conversation_id = m.conversation_id AND cm.user_id = sqlc.arg(user_id)::uuid
+    JOIN conversations c ON c.id = m.conversation_id AND c.deleted_at IS NULL
+    WHERE m.deleted_at IS NULL
+    AND (sqlc.narg(conversation_id)::uuid IS NULL OR m.conversation_id = sqlc.narg(conversation_id)::uuid)
+    AND (sqlc.narg(sender_id)::uuid IS NULL OR m.sender_id = sqlc.narg(sender_id)::uuid)
+    AND (sqlc.narg(sent_after)::timestamptz IS NULL OR m.created_at >= sqlc.narg(sent_after)::timestamptz)
+    AND (sqlc.narg(sent_before)::timestamptz IS NULL OR m.created_at < sqlc.narg(sent_before)::timestamptz)
+    AND (sqlc.narg(has_file)::boolean IS NULL OR (m.file_id IS NOT NULL) = sqlc.narg(has_file)::boolean)
+    AND (sqlc.narg(is_group)::boolean IS NULL OR c.is_group = sqlc.narg(is_group)::boolean)
+), page AS (
+    SELECT id, rank FROM matches
+    WHERE sqlc.narg(cursor_rank)::real IS NULL
+        OR (rank, id) < (sqlc.narg(cursor_rank)::real, sqlc.narg(cursor_id)::uuid)
+    ORDER BY rank DESC, id DESC
+    LIMIT sqlc.arg(result_limit)::int OFFSET sqlc.arg(result_offset)::int
+)
+SELECT
+    sqlc.embed(m),
+    c.is_group,
+    c.name AS conversation_name,
+    page.rank,
+    ts_headline('english', m.content, q.query,
+        'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxFragments=2, MaxWords=24, MinWords=8')::text AS highlight
+FROM page
+CROSS JOIN q
+JOIN messages m ON m.id = page.id
+JOIN conversations c ON c.id = m.conversation_id
+ORDER BY page.rank DESC, m.id DESC;
+
+
+-- name: SearchMessagesAfter :many
+-- the page ranked just above a cursor, lowest rank first
+WITH q AS (
+    SELECT websearch_to_tsquery('english', sqlc.arg(query)::text) AS query
+), matches AS (
+    SELECT m.id, ts_rank(s.search_vector, q.query)::real AS rank
+    FROM q
+    JOIN message_search s ON s.search_vector @@ q.query
+    JOIN messages m ON m.id = s.message_id
+    JOIN conversation_members cm ON cm.conversation_id = m.conversation_id AND cm.user_id = sqlc.arg(user_id)::uuid
+    JOIN conversations c ON c.id = m.conversation_id AND c.deleted_at IS NULL
+    WHERE m.deleted_at IS NULL
+    AND (sqlc.narg(conversation_id)::uuid IS NULL OR m.conversation_id = sqlc.narg(conversation_id)::uuid)
+    AND (sqlc.narg(sender_id)::uuid IS NULL OR m.sender_id = sqlc.narg(sender_id)::uuid)
+    AND (sqlc.narg(sent_after)::timestamptz IS NULL OR m.created_at >= sqlc.narg(sent_after)::timestamptz)
+    AND (sqlc.narg(sent_before)::timestamptz IS NULL OR m.created_at < sqlc.narg(sent_before)::timestamptz)
+    AND (sqlc.narg(has_file)::boolean IS NULL OR (m.file_id IS NOT NULL) = sqlc.narg(has_file)::boolean)
+    AND (sqlc.narg(is_group)::boolean IS NULL OR c.is_group = sqlc.narg(is_group)::boolean)
+), page AS (
+    SELECT id, rank FROM matches
+    WHERE (rank, id) > (sqlc.arg(cursor_rank)::real, sqlc.arg(cursor_id)::uuid)
+    ORDER BY rank ASC, id ASC
+    LIMIT sqlc.arg(result_limit)::int
+)
+SELECT
+    sqlc.embed(m),
+    c.is_group,
+    c.name AS conversation_name,
+    page.rank,
+    ts_headline('english', m.content, q.query,
+        'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxFragments=2, MaxWords=24, MinWords=8')::text AS highlight
+FROM page
+CROSS JOIN q
+JOIN messages m ON m.id = page.id
+JOIN conversations c ON c.id = m.conversation_id
+ORDER BY page.rank ASC, m.id ASC;
@@ -0,0 +1,3 @@
+-- name: CreateSecurityEvent :exec
+INSERT INTO security_events (user_id, event_type, refresh_token_id, ip_address, user_agent)
+VALUES ($1, $2, $3, $4, $5);
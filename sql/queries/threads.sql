@@ -0,0 +1,17 @@
+-- name: FollowThread :exec
+INSERT INTO thread_followers (thread_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowThread :exec
+DELETE FROM thread_followers
+WHERE thread_id = $1 AND user_id = $2;
+
+-- name: GetThreadFollowers :many
+SELECT user_id FROM thread_followers
+WHERE thread_id = $1;
+
+-- name: GetFollowedThreads :many
+SELECT tf.thread_id FROM thread_followers tf
+JOIN messages m ON m.id = tf.thread_id
+WHERE tf.user_id = $1 AND m.conversation_id = $2;
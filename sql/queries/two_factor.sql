@@ -0,0 +1,57 @@
+-- name: SetPendingUserTOTP :one
+-- starts or restarts enrolment; returns no row once 2FA is enabled
+INSERT INTO user_totp (user_id, secret)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+WHERE user_totp.enabled_at IS NULL
+RETURNING *;
+
+-- name: GetUserTOTP :one
+SELECT * FROM user_totp WHERE user_id = $1;
+
+-- name: EnableUserTOTP :one
+UPDATE user_totp
+SET enabled_at = NOW(), last_used_step = $2
+WHERE user_id = $1 AND enabled_at IS NULL
+RETURNING *;
+
+-- name: UseTOTPStep :execrows
+-- fails when the step or a later one was used already, so codes are single-use
+UPDATE user_totp SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2;
+
+-- name: DeleteUserTOTP :exec
+DELETE FROM user_totp WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes WHERE user_id = $1;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: CreateLoginChallenge :one
+INSERT INTO login_challenges (user_id, token_hash, expires_at)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: AttemptLoginChallenge :one
+-- counts an attempt at a live challenge; returns no row once it is used,
+-- expired or out of attempts
+UPDATE login_challenges
+SET attempts = attempts + 1
+WHERE token_hash = sqlc.arg(token_hash) AND used_at IS NULL AND expires_at > NOW()
+  AND attempts < sqlc.arg(max_attempts)::int
+RETURNING *;
+
+-- name: UseLoginChallenge :execrows
+UPDATE login_challenges SET used_at = NOW() WHERE id = $1 AND used_at IS NULL;
+
+-- name: DeleteExpiredLoginChallenges :exec
+DELETE FROM login_challenges WHERE user_id = $1 AND expires_at < NOW();
@@ -0,0 +1,43 @@
+-- name: CreateUpload :one
+INSERT INTO uploads (uploader_id, name, mime_type, size, expires_at, conversation_id)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetUpload :one
+SELECT * FROM uploads
+WHERE id = $1 AND uploader_id = $2 AND expires_at > NOW();
+
+-- name: AppendUploadPart :one
+-- only applies when no other chunk landed first
+UPDATE uploads
+SET upload_offset = upload_offset + sqlc.arg(part_size)::bigint,
+    part_paths = array_append(part_paths, sqlc.arg(part_path)::text),
+    expires_at = sqlc.arg(expires_at)
+WHERE id = sqlc.arg(id) AND uploader_id = sqlc.arg(uploader_id)
+  AND upload_offset = sqlc.arg(expected_offset)::bigint
+  AND expires_at > NOW()
+RETURNING *;
+
+-- name: DeleteUpload :one
+DELETE FROM uploads
+WHERE id = $1 AND uploader_id = $2
+RETURNING *;
+
+-- name: ClaimUpload :one
+-- marks a fully received upload as taken by the one request completing it;
+-- committed on its own so no transaction stays open while the parts are joined
+UPDATE uploads
+SET status = 'completing'
+WHERE id = $1 AND uploader_id = $2 AND upload_offset = size AND status = 'uploading'
+RETURNING *;
+
+-- name: ReleaseUpload :exec
+-- hands a claimed upload back after a completion failed, so it can be retried
+UPDATE uploads
+SET status = 'uploading'
+WHERE id = $1 AND uploader_id = $2 AND status = 'completing';
+
+-- name: DeleteExpiredUploads :many
+DELETE FROM uploads
+WHERE expires_at <= NOW()
+RETURNING *;
@@ -13,11 +13,13 @@ SELECT * FROM users WHERE email = $1;
 SELECT EXISTS ( SELECT 1 FROM users WHERE email = $1);
 
 -- name: UpdateUser :one
+-- a changed email has to be verified again
 UPDATE users 
 SET 
     name = COALESCE($2, name),
     email = COALESCE($3, email),
     password_hash = COALESCE($4, password_hash),
+    email_verified_at = CASE WHEN email = $3 THEN email_verified_at END,
     updated_at = NOW() 
 WHERE id = $1 
 RETURNING *;
@@ -25,5 +27,13 @@ RETURNING *;
 -- name: UpdateUserPassword :exec
 UPDATE users SET password_hash = $2, updated_at = NOW() WHERE id = $1;
 
+-- name: MarkEmailVerified :one
+-- the email is checked so a link for an address the user has since
+-- changed away from verifies nothing
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+WHERE id = $1 AND email = $2
+RETURNING *;
+
 -- name: DeleteUser :exec
 DELETE FROM users WHERE id = $1;
\ No newline at end of file
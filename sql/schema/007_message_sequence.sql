@@ -0,0 +1,28 @@
+-- +goose Up
+ALTER TABLE conversations ADD COLUMN last_seq BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE messages ADD COLUMN seq BIGINT;
+ALTER TABLE messages ADD COLUMN modified_seq BIGINT;
+
+-- number existing messages in the order they were sent
+UPDATE messages m
+SET seq = numbered.seq
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY conversation_id ORDER BY created_at, id) AS seq
+    FROM messages
+) numbered
+WHERE m.id = numbered.id;
+
+UPDATE conversations c
+SET last_seq = COALESCE((SELECT MAX(seq) FROM messages WHERE conversation_id = c.id), 0);
+
+ALTER TABLE messages ALTER COLUMN seq SET NOT NULL;
+ALTER TABLE messages ADD CONSTRAINT messages_conversation_seq_key UNIQUE (conversation_id, seq);
+CREATE INDEX idx_messages_modified_seq ON messages(conversation_id, modified_seq) WHERE modified_seq IS NOT NULL;
+
+-- +goose Down
+DROP INDEX idx_messages_modified_seq;
+ALTER TABLE messages DROP CONSTRAINT messages_conversation_seq_key;
+ALTER TABLE messages DROP COLUMN modified_seq;
+ALTER TABLE messages DROP COLUMN seq;
+ALTER TABLE conversations DROP COLUMN last_seq;
@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE messages ADD COLUMN client_msg_id UUID;
+
+-- a retried send carries the same client_msg_id and must not create a second row
+CREATE UNIQUE INDEX idx_messages_client_msg_id ON messages(conversation_id, sender_id, client_msg_id)
+WHERE client_msg_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX idx_messages_client_msg_id;
+ALTER TABLE messages DROP COLUMN client_msg_id;
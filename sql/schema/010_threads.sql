@@ -0,0 +1,17 @@
+-- +goose Up
+-- thread replies point at their root message; reply_to_id stays a plain quote
+ALTER TABLE messages ADD COLUMN thread_id UUID REFERENCES messages(id);
+
+CREATE INDEX idx_messages_thread_id ON messages(thread_id, created_at) WHERE thread_id IS NOT NULL;
+
+CREATE TABLE thread_followers (
+    thread_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (thread_id, user_id)
+);
+
+-- +goose Down
+DROP TABLE thread_followers;
+DROP INDEX idx_messages_thread_id;
+ALTER TABLE messages DROP COLUMN thread_id;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- keyset pagination walks these in (created_at, id) / (updated_at, id) order
+CREATE INDEX idx_messages_conversation_created ON messages(conversation_id, created_at DESC, id DESC);
+CREATE INDEX idx_conversations_updated ON conversations(updated_at DESC, id DESC);
+
+-- +goose Down
+DROP INDEX idx_conversations_updated;
+DROP INDEX idx_messages_conversation_created;
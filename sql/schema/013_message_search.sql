@@ -0,0 +1,32 @@
+-- +goose Up
+-- the search vector lives beside messages, so reading a message doesn't
+-- carry it; a trigger keeps it in step with the content
+CREATE TABLE message_search (
+    message_id UUID PRIMARY KEY REFERENCES messages(id) ON DELETE CASCADE,
+    search_vector tsvector NOT NULL
+);
+
+INSERT INTO message_search (message_id, search_vector)
+SELECT id, to_tsvector('english', COALESCE(content, '')) FROM messages;
+
+CREATE INDEX idx_message_search_vector ON message_search USING GIN (search_vector);
+
+-- +goose StatementBegin
+CREATE FUNCTION index_message_search() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO message_search (message_id, search_vector)
+    VALUES (NEW.id, to_tsvector('english', COALESCE(NEW.content, '')))
+    ON CONFLICT (message_id) DO UPDATE SET search_vector = EXCLUDED.search_vector;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER messages_index_search
+AFTER INSERT OR UPDATE OF content ON messages
+FOR EACH ROW EXECUTE FUNCTION index_message_search();
+
+-- +goose Down
+DROP TRIGGER messages_index_search ON messages;
+DROP FUNCTION index_message_search();
+DROP TABLE message_search;
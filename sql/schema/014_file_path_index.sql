@@ -0,0 +1,6 @@
+-- +goose Up
+-- files are looked up by path when served
+CREATE UNIQUE INDEX idx_files_path ON files(path);
+
+-- +goose Down
+DROP INDEX idx_files_path;
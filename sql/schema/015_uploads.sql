@@ -0,0 +1,23 @@
+-- +goose Up
+-- resumable uploads in progress; each received chunk is stored as its own
+-- object until the upload completes and the parts are joined into a file
+CREATE TYPE upload_status AS ENUM ('uploading', 'completing');
+
+CREATE TABLE uploads (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    uploader_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    mime_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    upload_offset BIGINT NOT NULL DEFAULT 0,
+    part_paths TEXT[] NOT NULL DEFAULT '{}',
+    status upload_status NOT NULL DEFAULT 'uploading', -- completing: claimed by one request
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_uploads_expires_at ON uploads(expires_at);
+
+-- +goose Down
+DROP TABLE uploads;
+DROP TYPE upload_status;
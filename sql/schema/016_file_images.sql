@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE files
+    ADD COLUMN width INT,
+    ADD COLUMN height INT,
+    ADD COLUMN blurhash TEXT;
+
+CREATE TABLE file_thumbnails (
+    file_id UUID NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    size INT NOT NULL, -- longest edge
+    width INT NOT NULL,
+    height INT NOT NULL,
+    mime_type TEXT NOT NULL,
+    path TEXT NOT NULL,
+    PRIMARY KEY (file_id, size)
+);
+
+CREATE UNIQUE INDEX idx_file_thumbnails_path ON file_thumbnails(path);
+
+-- +goose Down
+DROP TABLE file_thumbnails;
+
+ALTER TABLE files
+    DROP COLUMN blurhash,
+    DROP COLUMN height,
+    DROP COLUMN width;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- untouched upload, metadata included, kept for the uploader only when the
+-- deployment enables it
+ALTER TABLE files ADD COLUMN original_path TEXT;
+
+CREATE UNIQUE INDEX idx_files_original_path ON files(original_path);
+
+-- +goose Down
+DROP INDEX idx_files_original_path;
+
+ALTER TABLE files DROP COLUMN original_path;
@@ -0,0 +1,39 @@
+-- +goose Up
+-- stored content, shared by every files row with the same bytes
+CREATE TABLE blobs (
+    sha256 TEXT PRIMARY KEY, -- hex digest of the stored bytes
+    path TEXT NOT NULL UNIQUE,
+    size BIGINT NOT NULL,
+    ref_count INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_acquired_at TIMESTAMPTZ NOT NULL DEFAULT NOW() -- when a reference was last taken
+);
+
+-- rows from before deduplication have no hash and keep a path of their own
+ALTER TABLE files ADD COLUMN sha256 TEXT REFERENCES blobs(sha256);
+CREATE INDEX idx_files_sha256 ON files(sha256);
+
+-- images are stored without their metadata, so sha256 is not what the
+-- uploader's copy hashes to; this keeps the digest of the bytes as uploaded
+ALTER TABLE files ADD COLUMN upload_sha256 TEXT;
+CREATE INDEX idx_files_upload_sha256 ON files(upload_sha256);
+
+-- files and thumbnails of the same content now share paths
+DROP INDEX idx_files_path;
+CREATE INDEX idx_files_path ON files(path);
+DROP INDEX idx_file_thumbnails_path;
+CREATE INDEX idx_file_thumbnails_path ON file_thumbnails(path);
+
+-- +goose Down
+DROP INDEX idx_file_thumbnails_path;
+CREATE UNIQUE INDEX idx_file_thumbnails_path ON file_thumbnails(path);
+DROP INDEX idx_files_path;
+CREATE UNIQUE INDEX idx_files_path ON files(path);
+
+DROP INDEX idx_files_upload_sha256;
+ALTER TABLE files DROP COLUMN upload_sha256;
+
+DROP INDEX idx_files_sha256;
+ALTER TABLE files DROP COLUMN sha256;
+
+DROP TABLE blobs;
@@ -0,0 +1,32 @@
+-- +goose Up
+-- files uploaded for a conversation count against its quota as well as
+-- the uploader's
+ALTER TABLE files ADD COLUMN conversation_id UUID REFERENCES conversations(id) ON DELETE SET NULL;
+ALTER TABLE uploads ADD COLUMN conversation_id UUID REFERENCES conversations(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_files_uploader_id ON files(uploader_id);
+CREATE INDEX idx_files_conversation_id ON files(conversation_id);
+
+-- overrides of the configured default quotas, set by operators; a NULL
+-- quota means unlimited
+CREATE TABLE user_storage_quotas (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    quota_bytes BIGINT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE conversation_storage_quotas (
+    conversation_id UUID PRIMARY KEY REFERENCES conversations(id) ON DELETE CASCADE,
+    quota_bytes BIGINT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE conversation_storage_quotas;
+DROP TABLE user_storage_quotas;
+
+DROP INDEX idx_files_conversation_id;
+DROP INDEX idx_files_uploader_id;
+
+ALTER TABLE uploads DROP COLUMN conversation_id;
+ALTER TABLE files DROP COLUMN conversation_id;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- files of deleted messages get collected, so a deleted message may end up
+-- with neither content nor file
+ALTER TABLE messages DROP CONSTRAINT messages_file_id_fkey;
+ALTER TABLE messages ADD CONSTRAINT messages_file_id_fkey
+    FOREIGN KEY (file_id) REFERENCES files(id) ON DELETE SET NULL;
+
+ALTER TABLE messages DROP CONSTRAINT must_have_content;
+ALTER TABLE messages ADD CONSTRAINT must_have_content
+    CHECK (content IS NOT NULL OR file_id IS NOT NULL OR deleted_at IS NOT NULL);
+
+CREATE INDEX idx_messages_file_id ON messages(file_id);
+
+-- +goose Down
+DROP INDEX idx_messages_file_id;
+
+ALTER TABLE messages DROP CONSTRAINT must_have_content;
+ALTER TABLE messages ADD CONSTRAINT must_have_content
+    CHECK (content IS NOT NULL OR file_id IS NOT NULL);
+
+ALTER TABLE messages DROP CONSTRAINT messages_file_id_fkey;
+ALTER TABLE messages ADD CONSTRAINT messages_file_id_fkey
+    FOREIGN KEY (file_id) REFERENCES files(id);
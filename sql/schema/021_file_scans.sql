@@ -0,0 +1,19 @@
+-- +goose Up
+-- skipped: uploaded before scanning or with no scanner configured
+-- error: clamd gave no verdict, e.g. the file exceeded its stream limit
+CREATE TYPE file_scan_status AS ENUM ('pending', 'clean', 'infected', 'error', 'skipped');
+
+ALTER TABLE files ADD COLUMN scan_status file_scan_status NOT NULL DEFAULT 'skipped';
+ALTER TABLE files ADD COLUMN scan_signature TEXT; -- what clamd found
+ALTER TABLE files ADD COLUMN scanned_at TIMESTAMPTZ;
+
+CREATE INDEX idx_files_scan_pending ON files(created_at) WHERE scan_status = 'pending';
+
+-- +goose Down
+DROP INDEX idx_files_scan_pending;
+
+ALTER TABLE files DROP COLUMN scanned_at;
+ALTER TABLE files DROP COLUMN scan_signature;
+ALTER TABLE files DROP COLUMN scan_status;
+
+DROP TYPE file_scan_status;
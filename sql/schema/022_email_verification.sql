@@ -0,0 +1,9 @@
+-- +goose Up
+-- NULL until the user follows the link mailed to their current email;
+-- accounts from before verification existed count as verified
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+UPDATE users SET email_verified_at = created_at;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN email_verified_at;
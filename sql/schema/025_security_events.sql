@@ -0,0 +1,20 @@
+-- +goose Up
+-- refresh_token_reuse: a rotated refresh token was presented again, so it
+-- was likely stolen; its whole family was revoked
+CREATE TYPE security_event_type AS ENUM ('refresh_token_reuse');
+
+CREATE TABLE security_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event_type security_event_type NOT NULL,
+    refresh_token_id UUID REFERENCES refresh_tokens(id) ON DELETE SET NULL,
+    ip_address INET, -- of the request that triggered it
+    user_agent TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_security_events_user_id ON security_events(user_id, created_at);
+
+-- +goose Down
+DROP TABLE security_events;
+DROP TYPE security_event_type;
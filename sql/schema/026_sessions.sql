@@ -0,0 +1,32 @@
+-- +goose Up
+-- a session is one login on one device; rotation hands its ID on to each
+-- new refresh token, so it stays put while the tokens change
+ALTER TABLE refresh_tokens ADD COLUMN session_id UUID;
+
+-- existing chains keep one session each: every token takes the ID of the
+-- login that started its chain, found by following replaced_by_token_id
+WITH RECURSIVE chains AS (
+    SELECT r.id, r.id AS session_id
+    FROM refresh_tokens r
+    WHERE NOT EXISTS (
+        SELECT 1 FROM refresh_tokens prev WHERE prev.replaced_by_token_id = r.id
+    )
+    UNION ALL
+    SELECT r.replaced_by_token_id, chains.session_id
+    FROM chains
+    JOIN refresh_tokens r ON r.id = chains.id
+    WHERE r.replaced_by_token_id IS NOT NULL
+)
+UPDATE refresh_tokens r
+SET session_id = chains.session_id
+FROM chains
+WHERE r.id = chains.id;
+
+ALTER TABLE refresh_tokens ALTER COLUMN session_id SET NOT NULL;
+
+CREATE INDEX idx_refresh_tokens_session_id ON refresh_tokens(session_id);
+
+-- +goose Down
+DROP INDEX idx_refresh_tokens_session_id;
+
+ALTER TABLE refresh_tokens DROP COLUMN session_id;